@@ -56,6 +56,23 @@ func (mock *catalogServiceMock) DeleteCatalog(deleteCatalogOptions *catalogmanag
 	return response, args.Error(1)
 }
 
+// ListCatalogs(listCatalogsOptions *catalogmanagementv1.ListCatalogsOptions) (result *catalogmanagementv1.CatalogSearchResult, response *core.DetailedResponse, err error)
+func (mock *catalogServiceMock) ListCatalogs(listCatalogsOptions *catalogmanagementv1.ListCatalogsOptions) (*catalogmanagementv1.CatalogSearchResult, *core.DetailedResponse, error) {
+	args := mock.Called(listCatalogsOptions)
+
+	var result *catalogmanagementv1.CatalogSearchResult
+	if args.Get(0) != nil {
+		result = args.Get(0).(*catalogmanagementv1.CatalogSearchResult)
+	}
+
+	var response *core.DetailedResponse
+	if args.Get(1) != nil {
+		response = args.Get(1).(*core.DetailedResponse)
+	}
+
+	return result, response, args.Error(2)
+}
+
 // ImportOffering(importOfferingOptions *catalogmanagementv1.ImportOfferingOptions) (result *catalogmanagementv1.Offering, response *core.DetailedResponse, err error)
 func (mock *catalogServiceMock) ImportOffering(importOfferingOptions *catalogmanagementv1.ImportOfferingOptions) (*catalogmanagementv1.Offering, *core.DetailedResponse, error) {
 	args := mock.Called(importOfferingOptions)
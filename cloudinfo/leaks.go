@@ -0,0 +1,310 @@
+package cloudinfo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/globaltaggingv1"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// Kinds of resources in a ResourceSnapshot
+const (
+	SnapshotKindResourceInstance = "resource_instance"
+	SnapshotKindVpc              = "vpc"
+)
+
+// ResourceSnapshotOptions is the scope of a ResourceSnapshot. At least one resource group, service or VPC region is
+// required, unless AllResourceInstances is set.
+type ResourceSnapshotOptions struct {
+	ResourceGroupIDs      []string // list the resource instances of these resource groups
+	ResourceGroupNames    []string // list the resource instances of these resource groups, looked up by name if they exist
+	ResourceGroupPrefixes []string // list the resource instances of the resource groups whose name starts with a prefix
+	CrnServiceNames       []string // list the service instances with these CRN service names, for example `kms`
+	VpcRegions            []string // list the VPCs of these regions, no VPCs are listed if empty
+
+	// list all active resource instances of the account. This is slow in large accounts, and the tags of every new
+	// resource whose name does not contain the prefix are looked up when checking for leaks.
+	AllResourceInstances bool
+}
+
+// IsEmpty returns true if the options do not select any resources
+func (options ResourceSnapshotOptions) IsEmpty() bool {
+	return !options.AllResourceInstances && len(options.ResourceGroupIDs) == 0 && len(options.ResourceGroupNames) == 0 &&
+		len(options.ResourceGroupPrefixes) == 0 && len(options.CrnServiceNames) == 0 && len(options.VpcRegions) == 0
+}
+
+// SnapshotResource is a single resource found by TakeResourceSnapshot
+type SnapshotResource struct {
+	CRN             string
+	Name            string
+	Kind            string // SnapshotKindResourceInstance or SnapshotKindVpc
+	ResourceGroupID string
+	Region          string
+	Tags            []string // only set for leaked resources whose tags were looked up, see FindLeakedResources
+}
+
+// ResourceSnapshot is the list of resources that existed at a point in time, keyed by CRN
+type ResourceSnapshot struct {
+	Taken     time.Time
+	Resources map[string]SnapshotResource
+}
+
+// ResourceSnapshotter is implemented by services that can take resource snapshots for leak detection.
+// CloudInfoService implements it, runners type assert their CloudInfoServiceI to it.
+type ResourceSnapshotter interface {
+	TakeResourceSnapshot(options ResourceSnapshotOptions) (*ResourceSnapshot, error)
+	GetResourceTags(crn string) ([]string, error)
+}
+
+// TakeResourceSnapshot lists the resource instances and VPCs in the scope of the options
+func (infoSvc *CloudInfoService) TakeResourceSnapshot(options ResourceSnapshotOptions) (*ResourceSnapshot, error) {
+	if options.IsEmpty() {
+		return nil, fmt.Errorf("resource snapshot has no scope, set resource groups, services or VPC regions")
+	}
+	snapshot := &ResourceSnapshot{
+		Taken:     time.Now(),
+		Resources: make(map[string]SnapshotResource),
+	}
+
+	var instances []resourcecontrollerv2.ResourceInstance
+	if options.AllResourceInstances {
+		listOptions := infoSvc.resourceControllerService.NewListResourceInstancesOptions()
+		listOptions.SetType("resource_instance")
+		listOptions.SetState(resourcecontrollerv2.ListResourceInstancesOptionsStateActiveConst)
+		listOptions.SetLimit(int64(100))
+		allResources, err := listResourceInstances(infoSvc, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error listing resources: %w", err)
+		}
+		instances = append(instances, allResources...)
+	}
+	groupIDs, err := infoSvc.getSnapshotResourceGroupIDs(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, groupID := range groupIDs {
+		groupResources, err := infoSvc.ListResourcesByGroupID(groupID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing resources of resource group %s: %w", groupID, err)
+		}
+		instances = append(instances, groupResources...)
+	}
+	for _, serviceName := range options.CrnServiceNames {
+		serviceResources, err := infoSvc.ListResourcesByCrnServiceName(serviceName)
+		if err != nil {
+			return nil, fmt.Errorf("error listing resources of service %s: %w", serviceName, err)
+		}
+		instances = append(instances, serviceResources...)
+	}
+
+	for _, instance := range instances {
+		if instance.CRN == nil {
+			continue
+		}
+		// instances that were deleted may still be listed until they are reclaimed
+		if instance.State != nil && *instance.State != resourcecontrollerv2.ListResourceInstancesOptionsStateActiveConst {
+			continue
+		}
+		snapshot.Resources[*instance.CRN] = SnapshotResource{
+			CRN:             *instance.CRN,
+			Name:            core.StringNilMapper(instance.Name),
+			Kind:            SnapshotKindResourceInstance,
+			ResourceGroupID: core.StringNilMapper(instance.ResourceGroupID),
+			Region:          core.StringNilMapper(instance.RegionID),
+		}
+	}
+
+	if len(options.VpcRegions) > 0 {
+		if err := infoSvc.addVpcsToSnapshot(snapshot, options.VpcRegions); err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// getSnapshotResourceGroupIDs returns the IDs of the resource groups in the scope of the options. Resource groups
+// looked up by name that do not exist are skipped, as a test may create its resource group after the first snapshot
+// and remove it before the second.
+func (infoSvc *CloudInfoService) getSnapshotResourceGroupIDs(options ResourceSnapshotOptions) ([]string, error) {
+	groupIDs := append([]string{}, options.ResourceGroupIDs...)
+	if len(options.ResourceGroupNames) == 0 && len(options.ResourceGroupPrefixes) == 0 {
+		return groupIDs, nil
+	}
+
+	resourceGroups, _, err := infoSvc.resourceManagerService.ListResourceGroups(infoSvc.resourceManagerService.NewListResourceGroupsOptions())
+	if err != nil {
+		return nil, fmt.Errorf("error listing resource groups: %w", err)
+	}
+	if resourceGroups == nil {
+		return groupIDs, nil
+	}
+	for _, group := range resourceGroups.Resources {
+		if group.ID == nil || group.Name == nil || common.StrArrayContains(groupIDs, *group.ID) {
+			continue
+		}
+		selected := common.StrArrayContains(options.ResourceGroupNames, *group.Name)
+		for _, prefix := range options.ResourceGroupPrefixes {
+			selected = selected || (prefix != "" && strings.HasPrefix(*group.Name, prefix))
+		}
+		if selected {
+			groupIDs = append(groupIDs, *group.ID)
+		}
+	}
+	return groupIDs, nil
+}
+
+// addVpcsToSnapshot adds the VPCs of the regions to the snapshot
+func (infoSvc *CloudInfoService) addVpcsToSnapshot(snapshot *ResourceSnapshot, regions []string) error {
+	regionVpcs, err := infoSvc.ListVpcsByRegion(regions)
+	if err != nil {
+		return err
+	}
+	for _, regionVpc := range regionVpcs {
+		vpc := regionVpc.VPC
+		if vpc.CRN == nil {
+			continue
+		}
+		resource := SnapshotResource{
+			CRN:    *vpc.CRN,
+			Name:   core.StringNilMapper(vpc.Name),
+			Kind:   SnapshotKindVpc,
+			Region: regionVpc.Region,
+		}
+		if vpc.ResourceGroup != nil {
+			resource.ResourceGroupID = core.StringNilMapper(vpc.ResourceGroup.ID)
+		}
+		snapshot.Resources[*vpc.CRN] = resource
+	}
+	return nil
+}
+
+// GetResourceTags returns the user tags attached to the resource with the CRN
+func (infoSvc *CloudInfoService) GetResourceTags(crn string) ([]string, error) {
+	if infoSvc.globalTaggingService == nil {
+		return nil, fmt.Errorf("global tagging service is not initialized")
+	}
+	tagList, _, err := infoSvc.globalTaggingService.ListTags(&globaltaggingv1.ListTagsOptions{
+		AttachedTo: core.StringPtr(crn),
+		Limit:      core.Int64Ptr(1000),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags of %s: %w", crn, err)
+	}
+	var tags []string
+	for _, tag := range tagList.Items {
+		if tag.Name != nil {
+			tags = append(tags, *tag.Name)
+		}
+	}
+	return tags, nil
+}
+
+// NewSnapshotResources returns the resources of the after snapshot that are not in the before snapshot, sorted by CRN
+func NewSnapshotResources(before *ResourceSnapshot, after *ResourceSnapshot) []SnapshotResource {
+	var added []SnapshotResource
+	if after == nil {
+		return added
+	}
+	for crn, resource := range after.Resources {
+		if before != nil {
+			if _, existed := before.Resources[crn]; existed {
+				continue
+			}
+		}
+		added = append(added, resource)
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].CRN < added[j].CRN })
+	return added
+}
+
+// FindLeakedResources returns the resources that were created between the two snapshots and whose name or tags
+// contain the prefix. Tags are only looked up for new resources whose name does not contain the prefix. Errors looking
+// up tags do not stop the check, they are returned together with the leaked resources that were found.
+func FindLeakedResources(service ResourceSnapshotter, before *ResourceSnapshot, after *ResourceSnapshot, prefix string) ([]SnapshotResource, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("a prefix is required to find leaked resources")
+	}
+	lowerPrefix := strings.ToLower(prefix)
+
+	var leaked []SnapshotResource
+	var tagErrors []error
+	for _, resource := range NewSnapshotResources(before, after) {
+		if strings.Contains(strings.ToLower(resource.Name), lowerPrefix) {
+			leaked = append(leaked, resource)
+			continue
+		}
+		tags, err := service.GetResourceTags(resource.CRN)
+		if err != nil {
+			tagErrors = append(tagErrors, err)
+			continue
+		}
+		for _, tag := range tags {
+			if strings.Contains(strings.ToLower(tag), lowerPrefix) {
+				resource.Tags = tags
+				leaked = append(leaked, resource)
+				break
+			}
+		}
+	}
+	return leaked, errors.Join(tagErrors...)
+}
+
+// LeakDetector finds the resources a test left behind by taking a snapshot before the test creates any resources and
+// another one after its teardown.
+type LeakDetector struct {
+	service ResourceSnapshotter
+	options ResourceSnapshotOptions
+	prefix  string
+	before  *ResourceSnapshot
+}
+
+// NewLeakDetector returns a detector for the resources whose name or tags contain the prefix, in the scope of the options
+func NewLeakDetector(service ResourceSnapshotter, prefix string, options ResourceSnapshotOptions) *LeakDetector {
+	return &LeakDetector{
+		service: service,
+		options: options,
+		prefix:  prefix,
+	}
+}
+
+// Start takes the snapshot before the test creates any resources
+func (detector *LeakDetector) Start() error {
+	before, err := detector.service.TakeResourceSnapshot(detector.options)
+	if err != nil {
+		return fmt.Errorf("error taking resource snapshot before the test: %w", err)
+	}
+	detector.before = before
+	return nil
+}
+
+// Check takes the snapshot after the teardown and returns the leaked resources
+func (detector *LeakDetector) Check() ([]SnapshotResource, error) {
+	if detector.before == nil {
+		return nil, fmt.Errorf("leak detector was not started")
+	}
+	after, err := detector.service.TakeResourceSnapshot(detector.options)
+	if err != nil {
+		return nil, fmt.Errorf("error taking resource snapshot after the teardown: %w", err)
+	}
+	return FindLeakedResources(detector.service, detector.before, after, detector.prefix)
+}
+
+// FormatLeakedResources returns a message listing the leaked resources, one per line
+func FormatLeakedResources(leaked []SnapshotResource) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%d resource(s) were not removed by the teardown:", len(leaked)))
+	for _, resource := range leaked {
+		builder.WriteString(fmt.Sprintf("\n  %s %q region=%s resource_group=%s crn=%s", resource.Kind, resource.Name, resource.Region, resource.ResourceGroupID, resource.CRN))
+		if len(resource.Tags) > 0 {
+			builder.WriteString(fmt.Sprintf(" tags=%s", strings.Join(resource.Tags, ",")))
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,147 @@
+package cloudinfo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+	"github.com/IBM/platform-services-go-sdk/resourcemanagerv2"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockResourceInstance(crn string, name string, state string) resourcecontrollerv2.ResourceInstance {
+	return resourcecontrollerv2.ResourceInstance{
+		CRN:             core.StringPtr(crn),
+		Name:            core.StringPtr(name),
+		State:           core.StringPtr(state),
+		ResourceGroupID: core.StringPtr("group-id"),
+		RegionID:        core.StringPtr("us-south"),
+	}
+}
+
+func TestTakeResourceSnapshot(t *testing.T) {
+	rowCount := int64(3)
+	vpcService := &vpcServiceMock{
+		mockVpcs: map[string][]vpcv1.VPC{
+			"us-south": {{CRN: core.StringPtr("crn:vpc:3"), Name: core.StringPtr("test-vpc"), ResourceGroup: &vpcv1.ResourceGroupReference{ID: core.StringPtr("group-id")}}},
+		},
+	}
+	infoSvc := CloudInfoService{
+		resourceControllerService: &resourceControllerServiceMock{
+			mockResourceList: &resourcecontrollerv2.ResourceInstancesList{
+				RowsCount: &rowCount,
+				Resources: []resourcecontrollerv2.ResourceInstance{
+					mockResourceInstance("crn:kms:1", "test-kms", "active"),
+					mockResourceInstance("crn:cos:2", "test-cos", "pending_reclamation"),
+					{Name: core.StringPtr("no-crn")},
+				},
+			},
+		},
+		resourceManagerService: &resourceManagerServiceMock{
+			mockResourceGroupList: &resourcemanagerv2.ResourceGroupList{
+				Resources: []resourcemanagerv2.ResourceGroup{{ID: core.StringPtr("group-id"), Name: core.StringPtr("test-rg")}},
+			},
+		},
+		vpcService:    vpcService,
+		newVpcService: vpcService.newRegionalMock,
+	}
+
+	snapshot, err := infoSvc.TakeResourceSnapshot(ResourceSnapshotOptions{ResourceGroupNames: []string{"test-rg"}, VpcRegions: []string{"us-south"}})
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Resources, 2, "deleted instances and instances without CRN are ignored")
+	assert.Equal(t, SnapshotResource{CRN: "crn:kms:1", Name: "test-kms", Kind: SnapshotKindResourceInstance, ResourceGroupID: "group-id", Region: "us-south"}, snapshot.Resources["crn:kms:1"])
+	assert.Equal(t, SnapshotResource{CRN: "crn:vpc:3", Name: "test-vpc", Kind: SnapshotKindVpc, ResourceGroupID: "group-id", Region: "us-south"}, snapshot.Resources["crn:vpc:3"])
+	assert.Empty(t, vpcService.mockRegionUrl, "the url of the shared VPC client is not changed")
+
+	snapshot, err = infoSvc.TakeResourceSnapshot(ResourceSnapshotOptions{AllResourceInstances: true})
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Resources, 1)
+
+	snapshot, err = infoSvc.TakeResourceSnapshot(ResourceSnapshotOptions{ResourceGroupNames: []string{"unknown-rg"}})
+	require.NoError(t, err, "a resource group that does not exist has no resources")
+	assert.Empty(t, snapshot.Resources)
+
+	snapshot, err = infoSvc.TakeResourceSnapshot(ResourceSnapshotOptions{ResourceGroupPrefixes: []string{"test-"}})
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Resources, 1, "the resources of the resource groups matching the prefix are listed")
+	snapshot, err = infoSvc.TakeResourceSnapshot(ResourceSnapshotOptions{ResourceGroupPrefixes: []string{"other-"}})
+	require.NoError(t, err)
+	assert.Empty(t, snapshot.Resources)
+
+	_, err = infoSvc.TakeResourceSnapshot(ResourceSnapshotOptions{})
+	assert.Error(t, err, "the whole account is only listed with AllResourceInstances")
+}
+
+func TestFindLeakedResources(t *testing.T) {
+	infoSvc := &CloudInfoService{
+		globalTaggingService: &globalTaggingServiceMock{
+			mockTags: map[string][]string{
+				"crn:tagged": {"env:test", "owner:abc-prefix-1"},
+				"crn:other":  {"env:test"},
+			},
+		},
+	}
+
+	before := &ResourceSnapshot{Resources: map[string]SnapshotResource{
+		"crn:existing": {CRN: "crn:existing", Name: "abc-prefix-1-old"},
+	}}
+	after := &ResourceSnapshot{Resources: map[string]SnapshotResource{
+		"crn:existing": {CRN: "crn:existing", Name: "abc-prefix-1-old"},
+		"crn:named":    {CRN: "crn:named", Name: "ABC-Prefix-1-kms"},
+		"crn:tagged":   {CRN: "crn:tagged", Name: "unnamed"},
+		"crn:other":    {CRN: "crn:other", Name: "someone-else"},
+	}}
+
+	assert.Equal(t, []string{"crn:named", "crn:other", "crn:tagged"}, snapshotCRNs(NewSnapshotResources(before, after)))
+
+	leaked, err := FindLeakedResources(infoSvc, before, after, "abc-prefix-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"crn:named", "crn:tagged"}, snapshotCRNs(leaked))
+	assert.Equal(t, []string{"env:test", "owner:abc-prefix-1"}, leaked[1].Tags)
+	assert.Contains(t, FormatLeakedResources(leaked), "2 resource(s) were not removed by the teardown")
+
+	_, err = FindLeakedResources(infoSvc, before, after, "")
+	assert.Error(t, err)
+
+	infoSvc.globalTaggingService = &globalTaggingServiceMock{mockError: errors.New("tagging unavailable")}
+	leaked, err = FindLeakedResources(infoSvc, before, after, "abc-prefix-1")
+	assert.ErrorContains(t, err, "crn:other", "the tag lookup errors of every resource are returned")
+	assert.ErrorContains(t, err, "crn:tagged")
+	assert.Equal(t, []string{"crn:named"}, snapshotCRNs(leaked), "the check continues after a tag lookup error")
+}
+
+func TestLeakDetector(t *testing.T) {
+	rowCount := int64(1)
+	resourceController := &resourceControllerServiceMock{
+		mockResourceList: &resourcecontrollerv2.ResourceInstancesList{
+			RowsCount: &rowCount,
+			Resources: []resourcecontrollerv2.ResourceInstance{mockResourceInstance("crn:1", "shared", "active")},
+		},
+	}
+	infoSvc := &CloudInfoService{
+		resourceControllerService: resourceController,
+		globalTaggingService:      &globalTaggingServiceMock{},
+	}
+
+	detector := NewLeakDetector(infoSvc, "leak", ResourceSnapshotOptions{ResourceGroupIDs: []string{"group-id"}})
+	_, err := detector.Check()
+	assert.Error(t, err, "check before start must fail")
+	require.NoError(t, detector.Start())
+
+	rowCount = 2
+	resourceController.mockResourceList.Resources = append(resourceController.mockResourceList.Resources, mockResourceInstance("crn:2", "leak-cos", "active"))
+	leaked, err := detector.Check()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"crn:2"}, snapshotCRNs(leaked))
+}
+
+func snapshotCRNs(resources []SnapshotResource) []string {
+	var crns []string
+	for _, resource := range resources {
+		crns = append(crns, resource.CRN)
+	}
+	return crns
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/IBM/go-sdk-core/v5/core"
 	transitgatewayapisv1 "github.com/IBM/networking-go-sdk/transitgatewayapisv1"
 	"github.com/IBM/platform-services-go-sdk/contextbasedrestrictionsv1"
+	"github.com/IBM/platform-services-go-sdk/globaltaggingv1"
 	"github.com/IBM/platform-services-go-sdk/iamidentityv1"
 	"github.com/IBM/platform-services-go-sdk/iampolicymanagementv1"
 	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
@@ -25,6 +26,14 @@ import (
 type vpcServiceMock struct {
 	mock.Mock
 	mockRegionUrl               string
+	mockVpcs                    map[string][]vpcv1.VPC // VPCs returned by ListVpcs, by region url
+	mockInstances               []vpcv1.Instance
+	mockSubnets                 []vpcv1.Subnet
+	mockBareMetalServers        []vpcv1.BareMetalServer
+	mockPublicGateways          []vpcv1.PublicGateway
+	mockEndpointGateways        []vpcv1.EndpointGateway
+	deletedVpcs                 []string        // IDs of the VPCs deleted with DeleteVPC
+	shared                      *vpcServiceMock // the mock a regional mock was created from, see newRegionalMock
 	shouldFailGetRegion         bool
 	shouldFailSetServiceURL     bool
 	shouldFailListLoadBalancers bool
@@ -86,10 +95,23 @@ func (mock *vpcServiceMock) ListVpcs(options *vpcv1.ListVpcsOptions) (*vpcv1.VPC
 	log.Println("Count of", mock.mockRegionUrl, " = ", count)
 	vpcCol := vpcv1.VPCCollection{
 		TotalCount: &count,
+		Vpcs:       mock.mockVpcs[urlParts[0]], // VPCs by region name, without the version path of regional clients
 	}
 	return &vpcCol, nil, nil
 }
 
+func (mock *vpcServiceMock) DeleteVPC(options *vpcv1.DeleteVPCOptions) (*core.DetailedResponse, error) {
+	if options.ID != nil && *options.ID == "ERROR" {
+		return &core.DetailedResponse{StatusCode: 409}, errors.New("mock DeleteVPC error")
+	}
+	if mock.shared != nil {
+		mock.shared.deletedVpcs = append(mock.shared.deletedVpcs, *options.ID)
+	} else {
+		mock.deletedVpcs = append(mock.deletedVpcs, *options.ID)
+	}
+	return &core.DetailedResponse{StatusCode: 204}, nil
+}
+
 func (mock *vpcServiceMock) ListLoadBalancers(options *vpcv1.ListLoadBalancersOptions) (*vpcv1.LoadBalancerCollection, *core.DetailedResponse, error) {
 	if mock.shouldFailListLoadBalancers {
 		if mock.listLoadBalancersError != nil {
@@ -108,6 +130,78 @@ func (mock *vpcServiceMock) ListLoadBalancers(options *vpcv1.ListLoadBalancersOp
 	}
 	return &lbCol, nil, nil
 }
+
+// ListRegionZones returns the zones 3, 2 and 1 of the region as available, and zone 4 as impaired
+func (mock *vpcServiceMock) ListRegionZones(options *vpcv1.ListRegionZonesOptions) (*vpcv1.ZoneCollection, *core.DetailedResponse, error) {
+	var zones []vpcv1.Zone
+	for _, suffix := range []string{"3", "2", "1", "4"} {
+		name := *options.RegionName + "-" + suffix
+		status := "available"
+		if suffix == "4" {
+			status = "impaired"
+		}
+		zones = append(zones, vpcv1.Zone{Name: &name, Status: &status})
+	}
+	return &vpcv1.ZoneCollection{Zones: zones}, nil, nil
+}
+
+func (mock *vpcServiceMock) ListInstances(options *vpcv1.ListInstancesOptions) (*vpcv1.InstanceCollection, *core.DetailedResponse, error) {
+	if options.VPCID == nil {
+		return &vpcv1.InstanceCollection{Instances: mock.mockInstances}, nil, nil
+	}
+	var instances []vpcv1.Instance
+	for _, instance := range mock.mockInstances {
+		if instance.VPC != nil && core.StringNilMapper(instance.VPC.ID) == *options.VPCID {
+			instances = append(instances, instance)
+		}
+	}
+	return &vpcv1.InstanceCollection{Instances: instances}, nil, nil
+}
+
+func (mock *vpcServiceMock) ListSubnets(options *vpcv1.ListSubnetsOptions) (*vpcv1.SubnetCollection, *core.DetailedResponse, error) {
+	if options.VPCID == nil {
+		return &vpcv1.SubnetCollection{Subnets: mock.mockSubnets}, nil, nil
+	}
+	var subnets []vpcv1.Subnet
+	for _, subnet := range mock.mockSubnets {
+		if subnet.VPC != nil && core.StringNilMapper(subnet.VPC.ID) == *options.VPCID {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return &vpcv1.SubnetCollection{Subnets: subnets}, nil, nil
+}
+
+func (mock *vpcServiceMock) ListPublicGateways(options *vpcv1.ListPublicGatewaysOptions) (*vpcv1.PublicGatewayCollection, *core.DetailedResponse, error) {
+	return &vpcv1.PublicGatewayCollection{PublicGateways: mock.mockPublicGateways}, nil, nil
+}
+
+func (mock *vpcServiceMock) ListEndpointGateways(options *vpcv1.ListEndpointGatewaysOptions) (*vpcv1.EndpointGatewayCollection, *core.DetailedResponse, error) {
+	return &vpcv1.EndpointGatewayCollection{EndpointGateways: mock.mockEndpointGateways}, nil, nil
+}
+
+func (mock *vpcServiceMock) ListBareMetalServers(options *vpcv1.ListBareMetalServersOptions) (*vpcv1.BareMetalServerCollection, *core.DetailedResponse, error) {
+	return &vpcv1.BareMetalServerCollection{BareMetalServers: mock.mockBareMetalServers}, nil, nil
+}
+
+// newRegionalMock returns a new mock with the settings of the mock, used as the VPC client of a region
+func (mock *vpcServiceMock) newRegionalMock() (vpcService, error) {
+	return &vpcServiceMock{
+		shared:                      mock,
+		mockVpcs:                    mock.mockVpcs,
+		mockInstances:               mock.mockInstances,
+		mockSubnets:                 mock.mockSubnets,
+		mockBareMetalServers:        mock.mockBareMetalServers,
+		mockPublicGateways:          mock.mockPublicGateways,
+		mockEndpointGateways:        mock.mockEndpointGateways,
+		shouldFailGetRegion:         mock.shouldFailGetRegion,
+		shouldFailSetServiceURL:     mock.shouldFailSetServiceURL,
+		shouldFailListLoadBalancers: mock.shouldFailListLoadBalancers,
+		getRegionError:              mock.getRegionError,
+		setServiceURLError:          mock.setServiceURLError,
+		listLoadBalancersError:      mock.listLoadBalancersError,
+	}, nil
+}
+
 func (mock *vpcServiceMock) SetServiceURL(url string) error {
 	if mock.shouldFailSetServiceURL {
 		if mock.setServiceURLError != nil {
@@ -159,6 +253,8 @@ type resourceControllerServiceMock struct {
 	mockResourceList    *resourcecontrollerv2.ResourceInstancesList
 	mockReclamationList *resourcecontrollerv2.ReclamationsList
 	mockReclamation     *resourcecontrollerv2.Reclamation
+	deletedInstances    []string // IDs of the instances deleted with DeleteResourceInstance
+	reclaimedIDs        []string // IDs of the reclamations run with RunReclamationAction
 }
 
 func (mock *resourceControllerServiceMock) NewListResourceInstancesOptions() *resourcecontrollerv2.ListResourceInstancesOptions {
@@ -215,6 +311,7 @@ func (mock *resourceControllerServiceMock) ListReclamations(options *resourcecon
 }
 
 func (mock *resourceControllerServiceMock) RunReclamationAction(options *resourcecontrollerv2.RunReclamationActionOptions) (*resourcecontrollerv2.Reclamation, *core.DetailedResponse, error) {
+	mock.reclaimedIDs = append(mock.reclaimedIDs, *options.ID)
 	var reclamation *resourcecontrollerv2.Reclamation
 	mockID := "mock-reclamation-id"
 	mockReclamation := resourcecontrollerv2.Reclamation{ID: &mockID}
@@ -228,6 +325,18 @@ func (mock *resourceControllerServiceMock) RunReclamationAction(options *resourc
 	return reclamation, nil, nil
 }
 
+func (mock *resourceControllerServiceMock) NewDeleteResourceInstanceOptions(id string) *resourcecontrollerv2.DeleteResourceInstanceOptions {
+	return &resourcecontrollerv2.DeleteResourceInstanceOptions{ID: core.StringPtr(id)}
+}
+
+func (mock *resourceControllerServiceMock) DeleteResourceInstance(options *resourcecontrollerv2.DeleteResourceInstanceOptions) (*core.DetailedResponse, error) {
+	if *options.ID == "ERROR" {
+		return nil, errors.New("mock DeleteResourceInstance error")
+	}
+	mock.deletedInstances = append(mock.deletedInstances, *options.ID)
+	return &core.DetailedResponse{StatusCode: 204}, nil
+}
+
 // Resource Manager mock
 type resourceManagerServiceMock struct {
 	mockResourceGroupList             *resourcemanagerv2.ResourceGroupList
@@ -235,6 +344,7 @@ type resourceManagerServiceMock struct {
 	mockResCreateResourceGroup        *resourcemanagerv2.ResCreateResourceGroup
 	mockNewDeleteResourceGroupOptions *resourcemanagerv2.DeleteResourceGroupOptions
 	mockDeleteResourceGroup           *core.DetailedResponse
+	deletedResourceGroups             []string // IDs of the resource groups deleted with DeleteResourceGroup
 }
 
 func (s *resourceManagerServiceMock) NewListResourceGroupsOptions() *resourcemanagerv2.ListResourceGroupsOptions {
@@ -260,11 +370,17 @@ func (s *resourceManagerServiceMock) CreateResourceGroup(*resourcemanagerv2.Crea
 	return s.mockResCreateResourceGroup, resp, nil
 }
 
-func (s *resourceManagerServiceMock) NewDeleteResourceGroupOptions(string) *resourcemanagerv2.DeleteResourceGroupOptions {
-	return s.mockNewDeleteResourceGroupOptions
+func (s *resourceManagerServiceMock) NewDeleteResourceGroupOptions(id string) *resourcemanagerv2.DeleteResourceGroupOptions {
+	if s.mockNewDeleteResourceGroupOptions != nil {
+		return s.mockNewDeleteResourceGroupOptions
+	}
+	return &resourcemanagerv2.DeleteResourceGroupOptions{ID: core.StringPtr(id)}
 }
 
-func (s *resourceManagerServiceMock) DeleteResourceGroup(*resourcemanagerv2.DeleteResourceGroupOptions) (*core.DetailedResponse, error) {
+func (s *resourceManagerServiceMock) DeleteResourceGroup(options *resourcemanagerv2.DeleteResourceGroupOptions) (*core.DetailedResponse, error) {
+	if options != nil && options.ID != nil {
+		s.deletedResourceGroups = append(s.deletedResourceGroups, *options.ID)
+	}
 	return s.mockDeleteResourceGroup, nil
 }
 
@@ -487,6 +603,26 @@ func (mock *transitGatewayServiceMock) ListTransitGateways(*transitgatewayapisv1
 	}, nil, nil
 }
 
+// Global Tagging Service mock
+type globalTaggingServiceMock struct {
+	mock.Mock
+	mockTags  map[string][]string // tags by attached CRN
+	mockError error
+}
+
+func (mock *globalTaggingServiceMock) ListTags(options *globaltaggingv1.ListTagsOptions) (*globaltaggingv1.TagList, *core.DetailedResponse, error) {
+	if mock.mockError != nil {
+		return nil, nil, mock.mockError
+	}
+	tagList := &globaltaggingv1.TagList{Items: []globaltaggingv1.Tag{}}
+	if options.AttachedTo != nil {
+		for _, name := range mock.mockTags[*options.AttachedTo] {
+			tagList.Items = append(tagList.Items, globaltaggingv1.Tag{Name: core.StringPtr(name)})
+		}
+	}
+	return tagList, nil, nil
+}
+
 // Mock ContainerV1 Client
 type containerV1ClientMock struct {
 	mock.Mock
@@ -0,0 +1,392 @@
+package cloudinfo
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/catalogmanagementv1"
+	projects "github.com/IBM/project-go-sdk/projectv1"
+	schematics "github.com/IBM/schematics-go-sdk/schematicsv1"
+	"github.com/go-openapi/strfmt"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// Kinds of orphaned test items. The kinds are listed in the order in which they are deleted: projects may deploy
+// offerings of the catalogs, so catalogs are deleted last.
+const (
+	OrphanKindProject             = "project"
+	OrphanKindSchematicsWorkspace = "schematics_workspace"
+	OrphanKindCatalog             = "catalog"
+)
+
+// orphanKindOrder is the order of deletion of the orphan kinds
+var orphanKindOrder = []string{OrphanKindProject, OrphanKindSchematicsWorkspace, OrphanKindCatalog}
+
+const (
+	defaultOrphanTimeout      = 60 * time.Minute
+	defaultOrphanPollInterval = 30 * time.Second
+	orphanListLimit           = 100
+)
+
+// OrphanCleanupOptions selects the orphaned test projects, private catalogs and Schematics workspaces of an account.
+// An item is orphaned if it was created more than MinAge ago and its name starts with one of the NamePrefixes.
+type OrphanCleanupOptions struct {
+	NamePrefixes []string      // names of test projects, catalogs and workspaces start with one of these prefixes
+	MinAge       time.Duration // only items created more than MinAge ago are removed
+	Kinds        []string      // OrphanKind values to clean up, all kinds if empty
+
+	// DestroyWorkspaceResources runs a destroy job for each workspace before deleting it, otherwise the resources
+	// deployed by the workspace are left in the account
+	DestroyWorkspaceResources bool
+	Timeout                   time.Duration // time to wait for the undeploy of a project or destroy of a workspace, default 60 minutes
+	PollInterval              time.Duration // interval of undeploy status checks, default 30 seconds
+	DryRun                    bool          // only find the orphaned items, do not remove them
+}
+
+// OrphanedTestItem is a project, private catalog or Schematics workspace found by FindOrphanedTestItems
+type OrphanedTestItem struct {
+	Kind      string // OrphanKindProject, OrphanKindCatalog or OrphanKindSchematicsWorkspace
+	ID        string
+	Name      string
+	Location  string // Schematics location of a workspace, see GetSchematicsLocations
+	CreatedAt time.Time
+}
+
+// OrphanCleanupResult reports what CleanupOrphanedTestItems found and removed
+type OrphanCleanupResult struct {
+	Found   []OrphanedTestItem
+	Removed []OrphanedTestItem
+	Errors  []error // errors of items that could not be removed, the cleanup continues with the next item
+}
+
+// CleanupOrphanedTestItems finds the orphaned test projects, private catalogs and Schematics workspaces of the account
+// and, unless DryRun is set, removes them. See DeleteOrphanedTestItems for how each kind is removed.
+func (infoSvc *CloudInfoService) CleanupOrphanedTestItems(options OrphanCleanupOptions) (*OrphanCleanupResult, error) {
+	found, err := infoSvc.FindOrphanedTestItems(options)
+	if err != nil {
+		return nil, err
+	}
+	if options.DryRun {
+		return &OrphanCleanupResult{Found: found}, nil
+	}
+	return infoSvc.DeleteOrphanedTestItems(found, options), nil
+}
+
+// FindOrphanedTestItems lists the projects, private catalogs and Schematics workspaces of every location that match
+// the options, in the order in which they are deleted. At least one prefix is required.
+func (infoSvc *CloudInfoService) FindOrphanedTestItems(options OrphanCleanupOptions) ([]OrphanedTestItem, error) {
+	if len(options.NamePrefixes) == 0 {
+		return nil, fmt.Errorf("at least one name prefix is required to find orphaned test items")
+	}
+	for _, prefix := range options.NamePrefixes {
+		if strings.TrimSpace(prefix) == "" {
+			return nil, fmt.Errorf("empty name prefix would match every project, catalog and workspace")
+		}
+	}
+	for _, kind := range options.Kinds {
+		if !common.StrArrayContains(orphanKindOrder, kind) {
+			return nil, fmt.Errorf("unknown orphan kind %q, valid kinds are %s", kind, strings.Join(orphanKindOrder, ", "))
+		}
+	}
+
+	var found []OrphanedTestItem
+	now := time.Now()
+	add := func(kind string, id *string, name *string, location string, createdAt *strfmt.DateTime) {
+		if createdAt == nil || now.Sub(time.Time(*createdAt)) < options.MinAge || !hasOrphanPrefix(options, name) {
+			return
+		}
+		found = append(found, OrphanedTestItem{
+			Kind:      kind,
+			ID:        core.StringNilMapper(id),
+			Name:      core.StringNilMapper(name),
+			Location:  location,
+			CreatedAt: time.Time(*createdAt),
+		})
+	}
+
+	if isOrphanKindSelected(options, OrphanKindProject) {
+		projectList, err := infoSvc.listAllProjects()
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projectList {
+			var name *string
+			if project.Definition != nil {
+				name = project.Definition.Name
+			}
+			add(OrphanKindProject, project.ID, name, "", project.CreatedAt)
+		}
+	}
+
+	if isOrphanKindSelected(options, OrphanKindSchematicsWorkspace) {
+		for _, location := range GetSchematicsLocations() {
+			workspaces, err := infoSvc.listAllSchematicsWorkspaces(location)
+			if err != nil {
+				return nil, err
+			}
+			for _, workspace := range workspaces {
+				add(OrphanKindSchematicsWorkspace, workspace.ID, workspace.Name, location, workspace.CreatedAt)
+			}
+		}
+	}
+
+	if isOrphanKindSelected(options, OrphanKindCatalog) {
+		catalogs, _, err := infoSvc.catalogService.ListCatalogs(&catalogmanagementv1.ListCatalogsOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error listing catalogs: %w", err)
+		}
+		if catalogs != nil {
+			for _, catalog := range catalogs.Resources {
+				add(OrphanKindCatalog, catalog.ID, catalog.Label, "", catalog.Created)
+			}
+		}
+	}
+
+	log.Printf("Found %d orphaned test projects, catalogs and schematics workspaces", len(found))
+	return found, nil
+}
+
+// listAllProjects lists all projects of the account
+func (infoSvc *CloudInfoService) listAllProjects() ([]projects.ProjectSummary, error) {
+	var allProjects []projects.ProjectSummary
+	listOptions := &projects.ListProjectsOptions{
+		Limit: core.Int64Ptr(orphanListLimit),
+	}
+	for {
+		collection, _, err := infoSvc.projectsService.ListProjects(listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error listing projects: %w", err)
+		}
+		if collection == nil {
+			break
+		}
+		allProjects = append(allProjects, collection.Projects...)
+		if collection.Next == nil || collection.Next.Href == nil {
+			break
+		}
+		start, err := core.GetQueryParam(collection.Next.Href, "start")
+		if err != nil || start == nil {
+			break
+		}
+		listOptions.Start = start
+	}
+	return allProjects, nil
+}
+
+// listAllSchematicsWorkspaces lists all workspaces of a Schematics location
+func (infoSvc *CloudInfoService) listAllSchematicsWorkspaces(location string) ([]schematics.WorkspaceResponse, error) {
+	svc, err := infoSvc.GetSchematicsServiceByLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	var allWorkspaces []schematics.WorkspaceResponse
+	offset := int64(0)
+	for {
+		workspaceList, _, err := svc.ListWorkspaces(&schematics.ListWorkspacesOptions{
+			Offset: core.Int64Ptr(offset),
+			Limit:  core.Int64Ptr(orphanListLimit),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing schematics workspaces in location %s: %w", location, err)
+		}
+		if workspaceList == nil || len(workspaceList.Workspaces) == 0 {
+			break
+		}
+		allWorkspaces = append(allWorkspaces, workspaceList.Workspaces...)
+		if len(workspaceList.Workspaces) < orphanListLimit {
+			break
+		}
+		offset += int64(len(workspaceList.Workspaces))
+	}
+	return allWorkspaces, nil
+}
+
+// DeleteOrphanedTestItems removes the items, projects first, then workspaces and finally catalogs:
+//   - the deployed configurations of a project are undeployed before the project is deleted
+//   - a workspace is deleted, after destroying its resources if DestroyWorkspaceResources is set
+//   - a catalog is deleted
+//
+// Projects and catalogs are deleted with the project and catalog operation retry configurations.
+// Errors are collected in the result and do not stop the cleanup.
+func (infoSvc *CloudInfoService) DeleteOrphanedTestItems(items []OrphanedTestItem, options OrphanCleanupOptions) *OrphanCleanupResult {
+	result := &OrphanCleanupResult{Found: items}
+	if options.Timeout == 0 {
+		options.Timeout = defaultOrphanTimeout
+	}
+	if options.PollInterval == 0 {
+		options.PollInterval = defaultOrphanPollInterval
+	}
+
+	ordered := append([]OrphanedTestItem{}, items...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return orphanKindRank(ordered[i].Kind) < orphanKindRank(ordered[j].Kind)
+	})
+
+	for _, item := range ordered {
+		var err error
+		switch item.Kind {
+		case OrphanKindProject:
+			err = infoSvc.deleteOrphanedProject(item, options)
+		case OrphanKindSchematicsWorkspace:
+			err = infoSvc.deleteOrphanedWorkspace(item, options)
+		case OrphanKindCatalog:
+			err = infoSvc.deleteOrphanedCatalog(item)
+		default:
+			err = fmt.Errorf("unknown orphan kind %q", item.Kind)
+		}
+		if err != nil {
+			err = fmt.Errorf("error removing %s %s (%s): %w", item.Kind, item.Name, item.ID, err)
+			log.Println("ERROR:", err)
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+		log.Printf("Removed %s %s (%s)", item.Kind, item.Name, item.ID)
+		result.Removed = append(result.Removed, item)
+	}
+
+	log.Printf("Cleanup removed %d orphaned test items, %d errors", len(result.Removed), len(result.Errors))
+	return result
+}
+
+// deleteOrphanedProject undeploys the deployed configurations of the project and deletes it
+func (infoSvc *CloudInfoService) deleteOrphanedProject(item OrphanedTestItem, options OrphanCleanupOptions) error {
+	if err := infoSvc.undeployProjectConfigs(item.ID, options.Timeout, options.PollInterval); err != nil {
+		return err
+	}
+
+	config := common.ProjectOperationRetryConfig()
+	config.Logger = infoSvc.Logger
+	config.OperationName = fmt.Sprintf("DeleteProject '%s'", item.ID)
+	_, err := common.RetryWithConfig(config, func() (*projects.ProjectDeleteResponse, error) {
+		result, _, deleteErr := infoSvc.DeleteProject(item.ID)
+		return result, deleteErr
+	})
+	return err
+}
+
+// undeployProjectConfigs undeploys the deployed configurations of a project and waits until none are undeploying
+func (infoSvc *CloudInfoService) undeployProjectConfigs(projectID string, timeout time.Duration, pollInterval time.Duration) error {
+	configs, err := infoSvc.GetProjectConfigs(projectID)
+	if err != nil {
+		return fmt.Errorf("error listing configurations: %w", err)
+	}
+
+	config := common.ProjectOperationRetryConfig()
+	config.Logger = infoSvc.Logger
+	var undeploying []*ConfigDetails
+	for _, projectConfig := range configs {
+		if projectConfig.ID == nil {
+			continue
+		}
+		details := &ConfigDetails{ProjectID: projectID, ConfigID: *projectConfig.ID}
+		if _, deployed := infoSvc.IsConfigDeployed(details); !deployed {
+			continue
+		}
+		log.Printf("Undeploying configuration %s of project %s", details.ConfigID, projectID)
+		config.OperationName = fmt.Sprintf("UndeployConfig '%s'", details.ConfigID)
+		if _, err := common.RetryWithConfig(config, func() (*projects.ProjectConfigVersion, error) {
+			version, _, undeployErr := infoSvc.UndeployConfig(details)
+			return version, undeployErr
+		}); err != nil {
+			return fmt.Errorf("error undeploying configuration %s: %w", details.ConfigID, err)
+		}
+		undeploying = append(undeploying, details)
+	}
+
+	endTime := time.Now().Add(timeout)
+	for len(undeploying) > 0 {
+		var still []*ConfigDetails
+		for _, details := range undeploying {
+			if _, isUndeploying := infoSvc.IsUndeploying(details); isUndeploying {
+				still = append(still, details)
+			}
+		}
+		undeploying = still
+		if len(undeploying) == 0 {
+			break
+		}
+		if time.Now().After(endTime) {
+			return fmt.Errorf("timeout waiting for %d configuration(s) to undeploy", len(undeploying))
+		}
+		time.Sleep(pollInterval)
+	}
+	return nil
+}
+
+// deleteOrphanedWorkspace destroys the resources of the workspace if requested, then deletes the workspace
+func (infoSvc *CloudInfoService) deleteOrphanedWorkspace(item OrphanedTestItem, options OrphanCleanupOptions) error {
+	if options.DestroyWorkspaceResources {
+		destroyResult, err := infoSvc.CreateSchematicsDestroyJob(item.ID, item.Location)
+		if err != nil {
+			return fmt.Errorf("error creating destroy job: %w", err)
+		}
+		if destroyResult != nil && destroyResult.Activityid != nil {
+			status, err := infoSvc.WaitForSchematicsJobCompletion(item.ID, *destroyResult.Activityid, item.Location, int(options.Timeout.Minutes()))
+			if err != nil {
+				return fmt.Errorf("error waiting for destroy job: %w", err)
+			}
+			if status != SchematicsJobStatusCompleted {
+				return fmt.Errorf("destroy job finished with status %s", status)
+			}
+		}
+	}
+
+	_, err := infoSvc.DeleteSchematicsWorkspace(item.ID, item.Location, false)
+	return err
+}
+
+// deleteOrphanedCatalog deletes the catalog
+func (infoSvc *CloudInfoService) deleteOrphanedCatalog(item OrphanedTestItem) error {
+	config := common.CatalogOperationRetryConfig()
+	config.Logger = infoSvc.Logger
+	config.OperationName = fmt.Sprintf("DeleteCatalog '%s'", item.ID)
+	_, err := common.RetryWithConfig(config, func() (bool, error) {
+		return true, infoSvc.DeleteCatalog(item.ID)
+	})
+	return err
+}
+
+// hasOrphanPrefix returns true if the name starts with one of the prefixes of the options
+func hasOrphanPrefix(options OrphanCleanupOptions, name *string) bool {
+	if name == nil {
+		return false
+	}
+	for _, prefix := range options.NamePrefixes {
+		if strings.HasPrefix(*name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOrphanKindSelected returns true if the kind is cleaned up with the options
+func isOrphanKindSelected(options OrphanCleanupOptions, kind string) bool {
+	return len(options.Kinds) == 0 || common.StrArrayContains(options.Kinds, kind)
+}
+
+// orphanKindRank returns the position of the kind in the order of deletion
+func orphanKindRank(kind string) int {
+	for i, orderedKind := range orphanKindOrder {
+		if kind == orderedKind {
+			return i
+		}
+	}
+	return len(orphanKindOrder)
+}
+
+// PrintOrphanedTestItems prints the orphaned items to stdout
+func PrintOrphanedTestItems(items []OrphanedTestItem) {
+	fmt.Printf("Orphaned test projects, catalogs and schematics workspaces: %d\n", len(items))
+	for _, item := range items {
+		location := item.Location
+		if location == "" {
+			location = "N/A"
+		}
+		fmt.Printf("Kind: %s\nName: %s\nID: %s\nLocation: %s\nCreatedAt: %s\n--------------------\n",
+			item.Kind, item.Name, item.ID, location, item.CreatedAt.Format(time.RFC3339))
+	}
+}
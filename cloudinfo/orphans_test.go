@@ -0,0 +1,142 @@
+package cloudinfo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/catalogmanagementv1"
+	projects "github.com/IBM/project-go-sdk/projectv1"
+	schematics "github.com/IBM/schematics-go-sdk/schematicsv1"
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func orphanTestTime(age time.Duration) *strfmt.DateTime {
+	createdAt := strfmt.DateTime(time.Now().Add(-age))
+	return &createdAt
+}
+
+func newOrphanTestService() (*CloudInfoService, *ProjectsServiceMock, *catalogServiceMock, map[string]*mockSchematicsService, *mockIamAuthenticator) {
+	projectsService := new(ProjectsServiceMock)
+	projectsService.On("ListProjects", mock.Anything).Return(&projects.ProjectCollection{
+		Projects: []projects.ProjectSummary{
+			{ID: core.StringPtr("project-1"), CreatedAt: orphanTestTime(48 * time.Hour), Definition: &projects.ProjectDefinitionSummary{Name: core.StringPtr("ci-test-project")}},
+			{ID: core.StringPtr("project-2"), CreatedAt: orphanTestTime(time.Hour), Definition: &projects.ProjectDefinitionSummary{Name: core.StringPtr("ci-test-recent")}},
+			{ID: core.StringPtr("project-3"), CreatedAt: orphanTestTime(48 * time.Hour), Definition: &projects.ProjectDefinitionSummary{Name: core.StringPtr("prod-project")}},
+		},
+	}, &core.DetailedResponse{StatusCode: 200}, nil)
+
+	catalogService := new(catalogServiceMock)
+	catalogService.On("ListCatalogs", mock.Anything).Return(&catalogmanagementv1.CatalogSearchResult{
+		Resources: []catalogmanagementv1.Catalog{
+			{ID: core.StringPtr("catalog-1"), Label: core.StringPtr("ci-test-catalog"), Created: orphanTestTime(48 * time.Hour)},
+			{ID: core.StringPtr("catalog-2"), Label: core.StringPtr("shared-catalog"), Created: orphanTestTime(48 * time.Hour)},
+		},
+	}, &core.DetailedResponse{StatusCode: 200}, nil)
+
+	usService := new(mockSchematicsService)
+	usService.On("ListWorkspaces", mock.Anything).Return(&schematics.WorkspaceResponseList{
+		Workspaces: []schematics.WorkspaceResponse{
+			{ID: core.StringPtr("us-south.workspace.ci-test-1"), Name: core.StringPtr("ci-test-workspace"), CreatedAt: orphanTestTime(48 * time.Hour)},
+			{ID: core.StringPtr("us-south.workspace.other"), Name: core.StringPtr("other-workspace"), CreatedAt: orphanTestTime(48 * time.Hour)},
+		},
+	}, &core.DetailedResponse{StatusCode: 200}, nil)
+	euService := new(mockSchematicsService)
+	euService.On("ListWorkspaces", mock.Anything).Return(&schematics.WorkspaceResponseList{
+		Workspaces: []schematics.WorkspaceResponse{
+			{ID: core.StringPtr("eu-de.workspace.ci-test-2"), Name: core.StringPtr("ci-test-eu-workspace"), CreatedAt: orphanTestTime(48 * time.Hour)},
+		},
+	}, &core.DetailedResponse{StatusCode: 200}, nil)
+
+	mockAuth := new(mockIamAuthenticator)
+	infoSvc := &CloudInfoService{
+		projectsService: projectsService,
+		catalogService:  catalogService,
+		schematicsServices: map[string]schematicsService{
+			"us": usService,
+			"eu": euService,
+		},
+		authenticator: mockAuth,
+	}
+	return infoSvc, projectsService, catalogService, map[string]*mockSchematicsService{"us": usService, "eu": euService}, mockAuth
+}
+
+func orphanIDs(items []OrphanedTestItem) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestFindOrphanedTestItems(t *testing.T) {
+	infoSvc, _, _, _, _ := newOrphanTestService()
+
+	t.Run("RequiresPrefix", func(t *testing.T) {
+		_, err := infoSvc.FindOrphanedTestItems(OrphanCleanupOptions{})
+		assert.Error(t, err)
+		_, err = infoSvc.FindOrphanedTestItems(OrphanCleanupOptions{NamePrefixes: []string{" "}})
+		assert.Error(t, err)
+	})
+
+	t.Run("UnknownKind", func(t *testing.T) {
+		_, err := infoSvc.FindOrphanedTestItems(OrphanCleanupOptions{NamePrefixes: []string{"ci-test"}, Kinds: []string{"vpc"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("PrefixAndAge", func(t *testing.T) {
+		found, err := infoSvc.FindOrphanedTestItems(OrphanCleanupOptions{NamePrefixes: []string{"ci-test"}, MinAge: 24 * time.Hour})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"project-1", "us-south.workspace.ci-test-1", "eu-de.workspace.ci-test-2", "catalog-1"}, orphanIDs(found))
+		assert.Equal(t, "us", found[1].Location)
+		assert.Equal(t, "eu", found[2].Location)
+	})
+
+	t.Run("Kinds", func(t *testing.T) {
+		found, err := infoSvc.FindOrphanedTestItems(OrphanCleanupOptions{NamePrefixes: []string{"ci-test"}, Kinds: []string{OrphanKindCatalog}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"catalog-1"}, orphanIDs(found))
+	})
+}
+
+func TestCleanupOrphanedTestItems(t *testing.T) {
+	options := OrphanCleanupOptions{NamePrefixes: []string{"ci-test"}, MinAge: 24 * time.Hour}
+
+	t.Run("DryRun", func(t *testing.T) {
+		infoSvc, projectsService, catalogService, schematicsServices, _ := newOrphanTestService()
+		dryRunOptions := options
+		dryRunOptions.DryRun = true
+		result, err := infoSvc.CleanupOrphanedTestItems(dryRunOptions)
+		require.NoError(t, err)
+		assert.Len(t, result.Found, 4)
+		assert.Empty(t, result.Removed)
+		projectsService.AssertNotCalled(t, "DeleteProject", mock.Anything)
+		catalogService.AssertNotCalled(t, "DeleteCatalog", mock.Anything)
+		schematicsServices["us"].AssertNotCalled(t, "DeleteWorkspace", mock.Anything)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		infoSvc, projectsService, catalogService, schematicsServices, mockAuth := newOrphanTestService()
+		// the configurations of the project cannot be listed, so the project is not deleted
+		projectsService.On("NewConfigsPager", mock.Anything).Return((*projects.ConfigsPager)(nil), errors.New("forbidden"))
+		catalogService.On("DeleteCatalog", mock.Anything).Return(&core.DetailedResponse{StatusCode: 200}, nil)
+		mockAuth.On("RequestToken").Return(&core.IamTokenServerResponse{RefreshToken: "test-refresh-token"}, nil)
+		for _, svc := range schematicsServices {
+			svc.On("DeleteWorkspace", mock.Anything).Return(core.StringPtr("deleted"), &core.DetailedResponse{StatusCode: 200}, nil)
+		}
+
+		result, err := infoSvc.CleanupOrphanedTestItems(options)
+		require.NoError(t, err)
+		require.Len(t, result.Errors, 1)
+		assert.ErrorContains(t, result.Errors[0], "project-1")
+		assert.Equal(t, []string{"us-south.workspace.ci-test-1", "eu-de.workspace.ci-test-2", "catalog-1"}, orphanIDs(result.Removed))
+		projectsService.AssertNotCalled(t, "DeleteProject", mock.Anything)
+		catalogService.AssertNumberOfCalls(t, "DeleteCatalog", 1)
+		schematicsServices["us"].AssertNumberOfCalls(t, "DeleteWorkspace", 1)
+		schematicsServices["eu"].AssertNumberOfCalls(t, "DeleteWorkspace", 1)
+	})
+}
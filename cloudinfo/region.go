@@ -8,6 +8,7 @@ import (
 	"os"
 	"sort"
 
+	"github.com/IBM/go-sdk-core/v5/core"
 	transitgatewayapisv1 "github.com/IBM/networking-go-sdk/transitgatewayapisv1"
 	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
 	"github.com/IBM/vpc-go-sdk/vpcv1"
@@ -50,6 +51,45 @@ func (infoSvc *CloudInfoService) GetAvailableVpcRegions() ([]vpcv1.Region, error
 	return availRegions, nil
 }
 
+// RegionVpc is a VPC and the name of the region it is located in
+type RegionVpc struct {
+	Region string
+	VPC    vpcv1.VPC
+}
+
+// ListVpcsByRegion is a method for receiver CloudInfoService that will list all VPCs of the account in each of the
+// given regions, using the VPC client of each region.
+// Returns the VPCs with the name of their region, and error.
+func (infoSvc *CloudInfoService) ListVpcsByRegion(regions []string) ([]RegionVpc, error) {
+	var regionVpcs []RegionVpc
+	for _, regionName := range regions {
+		regionVpcService, err := infoSvc.getRegionVpcService(regionName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting VPC client for region %s: %w", regionName, err)
+		}
+
+		listOptions := &vpcv1.ListVpcsOptions{Limit: core.Int64Ptr(100)}
+		for page := 0; page < 100; page++ {
+			vpcCol, _, err := regionVpcService.ListVpcs(listOptions)
+			if err != nil {
+				return nil, fmt.Errorf("error listing VPCs in region %s: %w", regionName, err)
+			}
+			for _, vpc := range vpcCol.Vpcs {
+				regionVpcs = append(regionVpcs, RegionVpc{Region: regionName, VPC: vpc})
+			}
+			if vpcCol.Next == nil || vpcCol.Next.Href == nil {
+				break
+			}
+			start, startErr := core.GetQueryParam(vpcCol.Next.Href, "start")
+			if startErr != nil || start == nil {
+				return nil, fmt.Errorf("error in fetching start value from next href: %w", startErr)
+			}
+			listOptions.Start = start
+		}
+	}
+	return regionVpcs, nil
+}
+
 // GetLeastVpcTestRegion is a method for receiver CloudInfoService that will determine a region available
 // to the caller account that currently contains the least amount of deployed VPCs, using default options.
 // Returns a string representing an IBM Cloud region name, and error.
@@ -77,16 +117,22 @@ func (infoSvc *CloudInfoService) GetLeastVpcTestRegionWithoutActivityTracker() (
 // The determination can be influenced by specifying CloudInfoService.regionsData and supplying appropriate options.
 // If no CloudInfoService.regionsData exists, it will simply loop through all available regions for the caller account
 // and choose a region with lowest VPC count.
+// Regions are queried concurrently, each with its own VPC client, see CloudInfoServiceOptions.RegionScanConcurrency.
 // Returns a string representing an IBM Cloud region name, and error.
 func (infoSvc *CloudInfoService) GetLeastVpcTestRegionO(options GetTestRegionOptions) (string, error) {
 
 	var bestregion RegionData
 
-	regions, err := infoSvc.GetTestRegionsByPriority()
+	regions, err := infoSvc.GetTestRegionsByPriority(RegionServiceVpc)
 	if err != nil {
 		return "", err
 	}
 
+	// skip regions selected by other test processes, see CloudInfoServiceOptions.RegionLeases
+	unlockSelection := infoSvc.lockRegionSelection()
+	defer unlockSelection()
+	regions = infoSvc.filterLeasedRegions(regions)
+
 	// if we need to filter out regions by activity tracker existence, prepare a list of those regions
 	// NOTE: we only want to do this once at beginning and then use results below
 	var atInstanceList []resourcecontrollerv2.ResourceInstance
@@ -99,6 +145,7 @@ func (infoSvc *CloudInfoService) GetLeastVpcTestRegionO(options GetTestRegionOpt
 		}
 	}
 
+	var candidates []RegionData
 	for _, region := range regions {
 		// if option is set, ignore region if there is existing activity tracker
 		if options.ExcludeActivityTrackerRegions {
@@ -107,19 +154,24 @@ func (infoSvc *CloudInfoService) GetLeastVpcTestRegionO(options GetTestRegionOpt
 				continue // ignore and move to next region
 			}
 		}
+		candidates = append(candidates, region)
+	}
 
-		setErr := infoSvc.vpcService.SetServiceURL(region.Endpoint)
-		if setErr != nil {
-			log.Println("Failed to set a service url in vpc service")
-			return "", err
-		}
-
-		vpcCol, detailedResponse, err := infoSvc.vpcService.ListVpcs(&vpcv1.ListVpcsOptions{})
+	// count the VPCs of the regions concurrently, each region with its own VPC client
+	counts := infoSvc.scanRegionCounts(candidates, func(region RegionData, regionVpcService vpcService) (int, error) {
+		vpcCol, detailedResponse, err := regionVpcService.ListVpcs(&vpcv1.ListVpcsOptions{})
 		if err != nil {
 			log.Println("Failed LIST VPCs for region", region.Name, ":", err, "Full Response:", detailedResponse)
-			return "", err
+			return 0, err
+		}
+		return int(*vpcCol.TotalCount), nil
+	})
+
+	for i, region := range candidates {
+		if counts[i].err != nil {
+			return "", counts[i].err
 		}
-		region.ResourceCount = int(*vpcCol.TotalCount)
+		region.ResourceCount = counts[i].count
 
 		// region list is sorted by priority, so if vpc count is zero then short circuit and return, it is the best region
 		if region.ResourceCount == 0 {
@@ -133,17 +185,13 @@ func (infoSvc *CloudInfoService) GetLeastVpcTestRegionO(options GetTestRegionOpt
 		}
 	}
 
-	// after this is done need to set serviceURL back to default
-	defer func() {
-		err = infoSvc.vpcService.SetServiceURL(vpcv1.DefaultServiceURL)
-	}()
-
 	// if return val is still empty, then there were no regions available, send error
 	if len(bestregion.Name) == 0 {
 		return "", errors.New("ERROR: No region could be determined")
 	}
 
 	log.Printf("Selected region %s with %d VPCs", bestregion.Name, bestregion.ResourceCount)
+	infoSvc.leaseRegion(bestregion.Name)
 	return bestregion.Name, nil
 }
 
@@ -164,16 +212,22 @@ func (infoSvc *CloudInfoService) GetLeastSdnlbTestRegion(defaultRegion string) (
 // The determination can be influenced by specifying CloudInfoService.regionsData and supplying appropriate options.
 // If no CloudInfoService.regionsData exists, it will simply loop through all available regions for the caller account
 // and choose a region with lowest SDN load balancer count.
+// Regions are queried concurrently, each with its own VPC client, see CloudInfoServiceOptions.RegionScanConcurrency.
 // If no region can be determined, returns the provided defaultRegion.
 // Returns a string representing an IBM Cloud region name, and error.
 func (infoSvc *CloudInfoService) GetLeastSdnlbTestRegionO(defaultRegion string, options GetTestRegionOptions) (string, error) {
 	var bestregion RegionData
 
-	regions, err := infoSvc.GetTestRegionsByPriority()
+	regions, err := infoSvc.GetTestRegionsByPriority(RegionServiceVpc)
 	if err != nil {
 		return "", err
 	}
 
+	// skip regions selected by other test processes, see CloudInfoServiceOptions.RegionLeases
+	unlockSelection := infoSvc.lockRegionSelection()
+	defer unlockSelection()
+	regions = infoSvc.filterLeasedRegions(regions)
+
 	// if we need to filter out regions by activity tracker existence, prepare a list of those regions
 	// NOTE: we only want to do this once at beginning and then use results below
 	var atInstanceList []resourcecontrollerv2.ResourceInstance
@@ -186,6 +240,7 @@ func (infoSvc *CloudInfoService) GetLeastSdnlbTestRegionO(defaultRegion string,
 		}
 	}
 
+	var candidates []RegionData
 	for _, region := range regions {
 		// if option is set, ignore region if there is existing activity tracker
 		if options.ExcludeActivityTrackerRegions {
@@ -193,18 +248,24 @@ func (infoSvc *CloudInfoService) GetLeastSdnlbTestRegionO(defaultRegion string,
 				continue // ignore and move to next region
 			}
 		}
+		candidates = append(candidates, region)
+	}
 
-		setErr := infoSvc.vpcService.SetServiceURL(region.Endpoint)
-		if setErr != nil {
-			return "", setErr
-		}
-
-		lbCol, detailedResponse, err := infoSvc.vpcService.ListLoadBalancers(&vpcv1.ListLoadBalancersOptions{})
+	// count the load balancers of the regions concurrently, each region with its own VPC client
+	counts := infoSvc.scanRegionCounts(candidates, func(region RegionData, regionVpcService vpcService) (int, error) {
+		lbCol, detailedResponse, err := regionVpcService.ListLoadBalancers(&vpcv1.ListLoadBalancersOptions{})
 		if err != nil {
 			log.Println("Failed LIST Load Balancers for region", region.Name, ":", err, "Full Response:", detailedResponse)
-			return "", err
+			return 0, err
+		}
+		return int(*lbCol.TotalCount), nil
+	})
+
+	for i, region := range candidates {
+		if counts[i].err != nil {
+			return "", counts[i].err
 		}
-		region.ResourceCount = int(*lbCol.TotalCount)
+		region.ResourceCount = counts[i].count
 
 		// region list is sorted by priority, so if load balancer count is zero then short circuit and return, it is the best region
 		if region.ResourceCount == 0 {
@@ -217,18 +278,12 @@ func (infoSvc *CloudInfoService) GetLeastSdnlbTestRegionO(defaultRegion string,
 		}
 	}
 
-	// after this is done need to set serviceURL back to default
-	defer func() {
-		if resetErr := infoSvc.vpcService.SetServiceURL(vpcv1.DefaultServiceURL); resetErr != nil {
-			log.Println("Warning: failed to reset service URL to default:", resetErr)
-		}
-	}()
-
 	// if return val is still empty, then there were no regions available, return default region
 	if len(bestregion.Name) == 0 {
 		return defaultRegion, nil
 	}
 
+	infoSvc.leaseRegion(bestregion.Name)
 	return bestregion.Name, nil
 }
 
@@ -277,7 +332,7 @@ func (infoSvc *CloudInfoService) GetRegionWithoutService(serviceName string, sup
 	}
 
 	// No supportedRegions: use priority-ordered regions and return the first match.
-	regions, err := infoSvc.GetTestRegionsByPriority()
+	regions, err := infoSvc.GetTestRegionsByPriority(serviceName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get test regions: %w", err)
 	}
@@ -313,7 +368,7 @@ func (infoSvc *CloudInfoService) GetRegionWithLeastResources(serviceName string)
 	}
 
 	// Get priority-ordered available regions
-	regions, err := infoSvc.GetTestRegionsByPriority()
+	regions, err := infoSvc.GetTestRegionsByPriority(serviceName)
 	if err != nil {
 		return "", fmt.Errorf("failed to get test regions: %w", err)
 	}
@@ -356,6 +411,40 @@ func (infoSvc *CloudInfoService) GetRegionWithoutWatsonXGovernance(supportedRegi
 
 // GetRegionWithLeastTransitGateways returns the region with the minimum number of transit gateways.
 func (infoSvc *CloudInfoService) GetRegionWithLeastTransitGateways() (string, error) {
+	regionCounts, err := infoSvc.countTransitGatewaysByRegion()
+	if err != nil {
+		return "", err
+	}
+
+	// Get priority-ordered available regions
+	regions, err := infoSvc.GetTestRegionsByPriority(RegionServiceTransitGateway)
+	if err != nil {
+		return "", fmt.Errorf("failed to get test regions: %w", err)
+	}
+
+	// Find region with lowest count
+	var bestRegion string
+	minCount := math.MaxInt
+
+	for _, region := range regions {
+		count := regionCounts[region.Name]
+
+		if count < minCount {
+			minCount = count
+			bestRegion = region.Name
+		}
+	}
+
+	if bestRegion == "" {
+		return "", fmt.Errorf("no suitable region found for transit gateways")
+	}
+
+	log.Printf("Selected region %s with %d transit gateways", bestRegion, minCount)
+	return bestRegion, nil
+}
+
+// countTransitGatewaysByRegion returns the number of transit gateways of the account in each location (region)
+func (infoSvc *CloudInfoService) countTransitGatewaysByRegion() (map[string]int, error) {
 	// Get all transit gateways using Transit Gateway SDK with pagination support
 	maxPages := 100
 	countPages := 0
@@ -368,7 +457,7 @@ func (infoSvc *CloudInfoService) GetRegionWithLeastTransitGateways() (string, er
 	for moreData {
 		result, _, err := infoSvc.transitGatewayService.ListTransitGateways(listOptions)
 		if err != nil {
-			return "", fmt.Errorf("failed to list transit gateways: %w", err)
+			return nil, fmt.Errorf("failed to list transit gateways: %w", err)
 		}
 		countPages++
 
@@ -380,7 +469,7 @@ func (infoSvc *CloudInfoService) GetRegionWithLeastTransitGateways() (string, er
 			// Get the start token for the next page
 			nextStart, err := result.GetNextStart()
 			if err != nil {
-				return "", fmt.Errorf("error getting next page start token: %w", err)
+				return nil, fmt.Errorf("error getting next page start token: %w", err)
 			}
 			if nextStart != nil {
 				listOptions.SetStart(*nextStart)
@@ -402,31 +491,7 @@ func (infoSvc *CloudInfoService) GetRegionWithLeastTransitGateways() (string, er
 		}
 	}
 
-	// Get priority-ordered available regions
-	regions, err := infoSvc.GetTestRegionsByPriority()
-	if err != nil {
-		return "", fmt.Errorf("failed to get test regions: %w", err)
-	}
-
-	// Find region with lowest count
-	var bestRegion string
-	minCount := math.MaxInt
-
-	for _, region := range regions {
-		count := regionCounts[region.Name]
-
-		if count < minCount {
-			minCount = count
-			bestRegion = region.Name
-		}
-	}
-
-	if bestRegion == "" {
-		return "", fmt.Errorf("no suitable region found for transit gateways")
-	}
-
-	log.Printf("Selected region %s with %d transit gateways", bestRegion, minCount)
-	return bestRegion, nil
+	return regionCounts, nil
 }
 
 // regionHasActivityTracker is a helper function to determine if a given region is represented in an array
@@ -450,9 +515,11 @@ func regionHasActivityTracker(region string, activityTrackerList []resourcecontr
 
 // GetTestRegionsByPriority is a method for receiver CloudInfoService that will use the service regionsData
 // to determine a priority order and region eligibility for test resources to be deployed.
+// Regions in a blackout window, regions that do not allow one of the services (by CRN service name), and regions whose
+// resource counts are above their maximum are not eligible, see RegionData.
 // The returned array will then be used by various methods to determine best region to use for different test scenarios.
 // Returns an array of RegionData struct, and error.
-func (infoSvc *CloudInfoService) GetTestRegionsByPriority() ([]RegionData, error) {
+func (infoSvc *CloudInfoService) GetTestRegionsByPriority(services ...string) ([]RegionData, error) {
 
 	var regions []RegionData
 
@@ -484,6 +551,11 @@ func (infoSvc *CloudInfoService) GetTestRegionsByPriority() ([]RegionData, error
 		}
 	}
 
+	regions, err := infoSvc.filterRegionsForTest(regions, services)
+	if err != nil {
+		return nil, err
+	}
+
 	// sort by priority ascending
 	sort.Sort(SortedRegionsDataByPriority(regions))
 
@@ -492,6 +564,8 @@ func (infoSvc *CloudInfoService) GetTestRegionsByPriority() ([]RegionData, error
 
 // LoadRegionPrefsFromFile is a method for receiver CloudInfoService that will populate the CloudInfoService.regionsData
 // by reading a file in the YAML format.
+// The file is either a list of regions, or a map with the list of regions in `regions` and the configuration of
+// NewRegionScorer in `scoring`.
 // Returns error.
 func (infoSvc *CloudInfoService) LoadRegionPrefsFromFile(filePath string) error {
 	data, readErr := os.ReadFile(filePath)
@@ -500,15 +574,16 @@ func (infoSvc *CloudInfoService) LoadRegionPrefsFromFile(filePath string) error
 		return readErr
 	}
 
-	var regionsData []RegionData
+	var prefs regionPrefsFile
 
-	err := yaml.Unmarshal(data, &regionsData)
+	err := prefs.unmarshal(data)
 	if err != nil {
 		log.Println("ERROR unmarshalling", filePath, ":", err)
 		return err
 	}
 
-	infoSvc.regionsData = regionsData
+	infoSvc.regionsData = prefs.Regions
+	infoSvc.regionScoring = prefs.Scoring
 
 	return nil
 }
@@ -556,10 +631,18 @@ func (infoSvc *CloudInfoService) GetLeastPowerConnectionZone() (string, error) {
 		return "", errors.New("no available zones were supplied for power systems")
 	}
 
-	regions := infoSvc.regionsData
+	regions, err := infoSvc.filterRegionsForTest(infoSvc.regionsData, []string{RegionServicePowerVS})
+	if err != nil {
+		return "", err
+	}
 	// sort by priority ascending
 	sort.Sort(SortedRegionsDataByPriority(regions))
 
+	// skip zones selected by other test processes, see CloudInfoServiceOptions.RegionLeases
+	unlockSelection := infoSvc.lockRegionSelection()
+	defer unlockSelection()
+	regions = infoSvc.filterLeasedRegions(regions)
+
 	// load existing powercloud connections and their datacenter for the account
 	connections, connErr := infoSvc.ListPowerConnectionsForAccount()
 	if connErr != nil {
@@ -592,6 +675,7 @@ func (infoSvc *CloudInfoService) GetLeastPowerConnectionZone() (string, error) {
 	}
 
 	log.Printf("Selected Power zone %s with %d connections", bestregion.Name, bestregion.ResourceCount)
+	infoSvc.leaseRegion(bestregion.Name)
 	return bestregion.Name, nil
 }
 
@@ -0,0 +1,110 @@
+package cloudinfo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+const (
+	// regionSelectionLeaseName is the lease held while a region is selected, so that two processes do not select the
+	// same region before either has leased it
+	regionSelectionLeaseName    = "region-selection"
+	regionSelectionLockTimeout  = 5 * time.Minute
+	regionSelectionLockInterval = time.Second
+	// defaultRegionLeaseMaxHolders is the number of tests that can lease a region at the same time, see
+	// CloudInfoServiceOptions.RegionLeaseMaxHolders
+	defaultRegionLeaseMaxHolders = 1
+)
+
+// regionLeaseName returns the name of the lease of a region or zone
+func regionLeaseName(region string) string {
+	return "region-" + region
+}
+
+// lockRegionSelection waits until no other process is selecting a region and returns the function that ends the
+// selection. If the lock can not be acquired the region is selected without it.
+func (infoSvc *CloudInfoService) lockRegionSelection() func() {
+	if infoSvc.regionLeases == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), regionSelectionLockTimeout)
+	defer cancel()
+	lease, err := infoSvc.regionLeases.Acquire(ctx, regionSelectionLeaseName, 1, regionSelectionLockInterval)
+	if err != nil {
+		log.Printf("WARNING: selecting region without coordinating with other processes: %s", err)
+		return func() {}
+	}
+	return func() {
+		if releaseErr := lease.Release(); releaseErr != nil {
+			log.Printf("WARNING: %s", releaseErr)
+		}
+	}
+}
+
+// getRegionLeaseMaxHolders returns the number of tests that can lease a region at the same time
+func (infoSvc *CloudInfoService) getRegionLeaseMaxHolders() int {
+	if infoSvc.regionLeaseMaxHolders > 0 {
+		return infoSvc.regionLeaseMaxHolders
+	}
+	return defaultRegionLeaseMaxHolders
+}
+
+// filterLeasedRegions returns the regions that are not fully leased by other tests. If every region is fully leased,
+// all regions are returned so that a region can still be selected.
+func (infoSvc *CloudInfoService) filterLeasedRegions(regions []RegionData) []RegionData {
+	if infoSvc.regionLeases == nil {
+		return regions
+	}
+
+	maxHolders := infoSvc.getRegionLeaseMaxHolders()
+	var available []RegionData
+	for _, region := range regions {
+		held, err := infoSvc.regionLeases.Held(regionLeaseName(region.Name))
+		if err != nil {
+			log.Printf("WARNING: error reading lease of region %s, ignoring it: %s", region.Name, err)
+		} else if held >= maxHolders {
+			log.Println("Region", region.Name, "skipped, it is leased by", held, "other tests")
+			continue
+		}
+		available = append(available, region)
+	}
+	if len(available) == 0 {
+		log.Println("WARNING: all regions are leased by other tests, selecting from all regions")
+		return regions
+	}
+	return available
+}
+
+// leaseRegion leases the selected region until it is released with ReleaseRegionLease, or until the lease TTL has
+// passed. No lease is returned if region leases are not enabled, or if all slots of the region are held, which only
+// happens once every region is fully leased.
+func (infoSvc *CloudInfoService) leaseRegion(region string) *common.Lease {
+	if infoSvc.regionLeases == nil || region == "" {
+		return nil
+	}
+	lease, err := infoSvc.regionLeases.TryAcquire(regionLeaseName(region), infoSvc.getRegionLeaseMaxHolders())
+	if err != nil {
+		log.Printf("WARNING: could not lease region %s, it is shared with other tests: %s", region, err)
+		return nil
+	}
+	return lease
+}
+
+// ReleaseRegionLease releases a lease of the region held by this process, so that other tests can select the region
+// again. Test runners call it during teardown for the region selected for the test.
+func (infoSvc *CloudInfoService) ReleaseRegionLease(region string) error {
+	return ReleaseRegionLease(infoSvc.regionLeases, region)
+}
+
+// ReleaseRegionLease releases a lease of the region held by this process in the lease directory of leases, for regions
+// selected by a CloudInfoService that is no longer available. Nothing is released if leases is nil.
+func ReleaseRegionLease(leases *common.LeaseManager, region string) error {
+	if leases == nil || region == "" {
+		return nil
+	}
+	return leases.ReleaseOwned(regionLeaseName(region))
+}
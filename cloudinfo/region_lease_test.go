@@ -0,0 +1,76 @@
+package cloudinfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+func TestRegionLeases(t *testing.T) {
+	dir := t.TempDir()
+	other := common.NewLeaseManager(dir, time.Hour)
+	other.Owner = "other-process"
+	infoSvc := &CloudInfoService{regionLeases: common.NewLeaseManager(dir, time.Hour)}
+	regions := []RegionData{{Name: "us-south"}, {Name: "eu-de"}}
+
+	t.Run("NotLeased", func(t *testing.T) {
+		assert.Equal(t, regions, infoSvc.filterLeasedRegions(regions))
+	})
+
+	t.Run("LeasedByOtherProcess", func(t *testing.T) {
+		lease, err := other.TryAcquire(regionLeaseName("us-south"), defaultRegionLeaseMaxHolders)
+		require.NoError(t, err)
+		defer lease.Release()
+
+		assert.Equal(t, []RegionData{{Name: "eu-de"}}, infoSvc.filterLeasedRegions(regions))
+	})
+
+	t.Run("LeasedUntilReleased", func(t *testing.T) {
+		lease := infoSvc.leaseRegion("eu-de")
+		require.NotNil(t, lease)
+		assert.Equal(t, []RegionData{{Name: "us-south"}}, infoSvc.filterLeasedRegions(regions), "another test of this process does not select the region")
+
+		held, err := other.HeldByOthers(regionLeaseName("eu-de"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, held)
+
+		require.NoError(t, infoSvc.ReleaseRegionLease("eu-de"))
+		assert.Equal(t, regions, infoSvc.filterLeasedRegions(regions))
+	})
+
+	t.Run("MaxHolders", func(t *testing.T) {
+		shared := &CloudInfoService{regionLeases: infoSvc.regionLeases, regionLeaseMaxHolders: 2}
+		first := shared.leaseRegion("us-south")
+		require.NotNil(t, first)
+		assert.Equal(t, regions, shared.filterLeasedRegions(regions), "region can be leased by a second test")
+		second := shared.leaseRegion("us-south")
+		require.NotNil(t, second)
+		assert.Equal(t, []RegionData{{Name: "eu-de"}}, shared.filterLeasedRegions(regions))
+		assert.Nil(t, shared.leaseRegion("us-south"), "all slots of the region are held")
+
+		require.NoError(t, first.Release())
+		require.NoError(t, ReleaseRegionLease(common.NewLeaseManager(dir, time.Hour), "us-south"), "released by another manager of this process")
+		assert.Equal(t, regions, shared.filterLeasedRegions(regions))
+	})
+
+	t.Run("AllLeased", func(t *testing.T) {
+		for _, region := range regions {
+			lease, err := other.TryAcquire(regionLeaseName(region.Name), defaultRegionLeaseMaxHolders)
+			require.NoError(t, err)
+			defer lease.Release()
+		}
+		assert.Equal(t, regions, infoSvc.filterLeasedRegions(regions))
+		assert.Nil(t, infoSvc.leaseRegion("us-south"))
+	})
+
+	t.Run("NotCoordinated", func(t *testing.T) {
+		unlock := (&CloudInfoService{}).lockRegionSelection()
+		unlock()
+		assert.Equal(t, regions, (&CloudInfoService{}).filterLeasedRegions(regions))
+		assert.Nil(t, (&CloudInfoService{}).leaseRegion("us-south"))
+		assert.NoError(t, (&CloudInfoService{}).ReleaseRegionLease("us-south"))
+	})
+}
@@ -0,0 +1,227 @@
+package cloudinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+	"gopkg.in/yaml.v3"
+)
+
+// CRN service names of the services whose region selection honors RegionData.AllowedServices and RegionData.DeniedServices
+const (
+	RegionServiceVpc            = "is"
+	RegionServicePowerVS        = "power-iaas"
+	RegionServiceTransitGateway = "transit"
+)
+
+// regionPrefsFile is the region prefs file in the map format, see LoadRegionPrefsFromFile
+type regionPrefsFile struct {
+	Regions []RegionData         `yaml:"regions"`
+	Scoring *RegionScoringConfig `yaml:"scoring"`
+}
+
+// unmarshal reads the region prefs file in either the list or the map format. Unknown fields are logged as a warning
+// and ignored, so that prefs files with fields of other tools or versions can still be read.
+func (prefs *regionPrefsFile) unmarshal(data []byte) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	isMap := len(document.Content) > 0 && document.Content[0].Kind == yaml.MappingNode
+
+	if strictErr := prefs.decode(data, isMap, true); strictErr != nil {
+		// the strict error is only about unknown fields if the file can be decoded without the check
+		*prefs = regionPrefsFile{}
+		if err := prefs.decode(data, isMap, false); err != nil {
+			return err
+		}
+		log.Printf("WARNING: ignoring unknown fields of region prefs: %s", strictErr)
+	}
+	return prefs.validate()
+}
+
+// decode decodes the region prefs file in the map format, or the list format if isMap is false
+func (prefs *regionPrefsFile) decode(data []byte, isMap bool, knownFields bool) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(knownFields)
+	var err error
+	if isMap {
+		err = decoder.Decode(prefs)
+	} else {
+		err = decoder.Decode(&prefs.Regions)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// validate checks the values of the region prefs file, returning all errors found
+func (prefs *regionPrefsFile) validate() error {
+	var errs []error
+	names := make(map[string]bool)
+	for index, region := range prefs.Regions {
+		prefix := fmt.Sprintf("regions[%d]", index)
+		if region.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", prefix))
+		} else {
+			prefix = fmt.Sprintf("%s (%s)", prefix, region.Name)
+			if names[region.Name] {
+				errs = append(errs, fmt.Errorf("%s: region is listed more than once", prefix))
+			}
+			names[region.Name] = true
+		}
+
+		for _, service := range region.AllowedServices {
+			if common.StrArrayContains(region.DeniedServices, service) {
+				errs = append(errs, fmt.Errorf("%s: service %s is both allowed and denied", prefix, service))
+			}
+		}
+		for counter, limit := range region.MaxResources {
+			if !isRegionCounterName(counter) {
+				errs = append(errs, fmt.Errorf("%s: maxResources: unknown region counter %q", prefix, counter))
+			}
+			if limit < 0 {
+				errs = append(errs, fmt.Errorf("%s: maxResources: %s is negative", prefix, counter))
+			}
+		}
+		for blackoutIndex, blackout := range region.Blackouts {
+			if blackout.Start.IsZero() || blackout.End.IsZero() {
+				errs = append(errs, fmt.Errorf("%s: blackouts[%d]: start and end are required", prefix, blackoutIndex))
+			} else if !blackout.End.After(blackout.Start) {
+				errs = append(errs, fmt.Errorf("%s: blackouts[%d]: end %s is not after start %s", prefix, blackoutIndex,
+					blackout.End.Format(time.RFC3339), blackout.Start.Format(time.RFC3339)))
+			}
+		}
+	}
+
+	if prefs.Scoring != nil {
+		for index, criterion := range prefs.Scoring.Criteria {
+			prefix := fmt.Sprintf("scoring.criteria[%d]", index)
+			if !isRegionCounterName(criterion.Counter) {
+				errs = append(errs, fmt.Errorf("%s: unknown region counter %q", prefix, criterion.Counter))
+			}
+			if criterion.Weight != nil && *criterion.Weight < 0 {
+				errs = append(errs, fmt.Errorf("%s: weight is negative", prefix))
+			}
+			if criterion.Max != nil && *criterion.Max < 0 {
+				errs = append(errs, fmt.Errorf("%s: max is negative", prefix))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid region prefs: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// isRegionCounterName reports if name is the name of a built-in region counter, see GetRegionCounter
+func isRegionCounterName(name string) bool {
+	switch name {
+	case RegionCounterVpc, RegionCounterLoadBalancer, RegionCounterTransitGateway:
+		return true
+	}
+	return strings.HasPrefix(name, RegionCounterServicePrefix) && len(name) > len(RegionCounterServicePrefix)
+}
+
+// filterRegionsForTest removes the regions that are in a blackout window, that do not allow one of the services, or
+// whose resource counts are above their maximum. The resources of up to RegionScanConcurrency regions are counted at
+// the same time. The excluded regions are logged.
+func (infoSvc *CloudInfoService) filterRegionsForTest(regions []RegionData, services []string) ([]RegionData, error) {
+	now := time.Now()
+	var available []RegionData
+	for _, region := range regions {
+		if reason := regionUnavailableReason(region, services, now); reason != "" {
+			log.Printf("Region %s is not used for test: %s", region.Name, reason)
+			continue
+		}
+		available = append(available, region)
+	}
+
+	// counters are shared by the regions, so that counters listing the resources of the account list them once
+	counters := make(map[string]RegionCounter)
+	for _, region := range available {
+		for name := range region.MaxResources {
+			if _, found := counters[name]; found {
+				continue
+			}
+			counter, err := infoSvc.GetRegionCounter(name)
+			if err != nil {
+				return nil, err
+			}
+			counters[name] = counter
+		}
+	}
+
+	reasons := make([]string, len(available))
+	errs := make([]error, len(available))
+	infoSvc.scanRegions(available, func(index int, region RegionData) {
+		reasons[index], errs[index] = regionFullReason(region, counters)
+	})
+
+	var filtered []RegionData
+	for index, region := range available {
+		if errs[index] != nil {
+			return nil, errs[index]
+		}
+		if reasons[index] != "" {
+			log.Printf("Region %s is not used for test: %s", region.Name, reasons[index])
+			continue
+		}
+		filtered = append(filtered, region)
+	}
+	return filtered, nil
+}
+
+// regionUnavailableReason returns why the region is not used for test at the time for the services, empty if it is used
+func regionUnavailableReason(region RegionData, services []string, now time.Time) string {
+	for _, blackout := range region.Blackouts {
+		if !now.Before(blackout.Start) && now.Before(blackout.End) {
+			reason := fmt.Sprintf("blackout until %s", blackout.End.Format(time.RFC3339))
+			if blackout.Reason != "" {
+				reason += " (" + blackout.Reason + ")"
+			}
+			return reason
+		}
+	}
+	for _, service := range services {
+		if common.StrArrayContains(region.DeniedServices, service) {
+			return fmt.Sprintf("service %s is denied", service)
+		}
+		if len(region.AllowedServices) > 0 && !common.StrArrayContains(region.AllowedServices, service) {
+			return fmt.Sprintf("service %s is not allowed", service)
+		}
+	}
+	return ""
+}
+
+// regionFullReason counts the resources of the region that have a maximum, returning which count is above its maximum,
+// empty if the region is not full. Counters of VPC resources are skipped for regions without a VPC endpoint, for
+// example PowerVS zones.
+func regionFullReason(region RegionData, counters map[string]RegionCounter) (string, error) {
+	for name, limit := range region.MaxResources {
+		if region.Endpoint == "" && isVpcRegionCounterName(name) {
+			continue
+		}
+		count, err := counters[name].CountRegion(region)
+		if err != nil {
+			return "", fmt.Errorf("error counting %s of region %s: %w", name, region.Name, err)
+		}
+		if count > limit {
+			return fmt.Sprintf("%s count %d is above the maximum of %d", name, count, limit), nil
+		}
+	}
+	return "", nil
+}
+
+// isVpcRegionCounterName reports if name is the name of a built-in counter of the resources of a VPC region endpoint
+func isVpcRegionCounterName(name string) bool {
+	return name == RegionCounterVpc || name == RegionCounterLoadBalancer
+}
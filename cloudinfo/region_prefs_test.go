@@ -0,0 +1,99 @@
+package cloudinfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadExtendedRegionPrefs(t *testing.T) {
+	t.Run("ExtendedFields", func(t *testing.T) {
+		infoSvc := CloudInfoService{}
+		require.NoError(t, infoSvc.LoadRegionPrefsFromFile("testdata/region-extended-prefs.yaml"))
+		require.Len(t, infoSvc.regionsData, 3)
+		assert.Equal(t, []string{RegionServicePowerVS}, infoSvc.regionsData[0].DeniedServices)
+		assert.Equal(t, map[string]int{RegionCounterVpc: 0}, infoSvc.regionsData[1].MaxResources)
+		assert.Equal(t, []string{RegionServiceVpc}, infoSvc.regionsData[2].AllowedServices)
+		require.Len(t, infoSvc.regionsData[2].Blackouts, 1)
+		assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), infoSvc.regionsData[2].Blackouts[0].End.UTC())
+	})
+
+	t.Run("InvalidValues", func(t *testing.T) {
+		infoSvc := CloudInfoService{}
+		err := infoSvc.LoadRegionPrefsFromFile("testdata/region-invalid-prefs.yaml")
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "regions[0] (reg-1-1): service is is both allowed and denied")
+		assert.ErrorContains(t, err, `regions[0] (reg-1-1): maxResources: unknown region counter "unknown"`)
+		assert.ErrorContains(t, err, "regions[0] (reg-1-1): maxResources: vpc is negative")
+		assert.ErrorContains(t, err, "regions[0] (reg-1-1): blackouts[0]: end 2020-01-01T00:00:00Z is not after start 2020-01-02T00:00:00Z")
+		assert.ErrorContains(t, err, "regions[1] (reg-1-1): region is listed more than once")
+		assert.Empty(t, infoSvc.regionsData, "invalid prefs are not loaded")
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		infoSvc := CloudInfoService{}
+		require.NoError(t, infoSvc.LoadRegionPrefsFromFile("testdata/region-unknown-field-prefs.yaml"), "unknown fields are ignored with a warning")
+		require.Len(t, infoSvc.regionsData, 1)
+		assert.Equal(t, "reg-1-1", infoSvc.regionsData[0].Name)
+		assert.True(t, infoSvc.regionsData[0].UseForTest)
+	})
+}
+
+func TestRegionPrefsFiltering(t *testing.T) {
+	vpcService := &vpcServiceMock{}
+	now := time.Now()
+	infoSvc := CloudInfoService{
+		vpcService:    vpcService,
+		newVpcService: vpcService.newRegionalMock,
+		regionsData: []RegionData{
+			{Name: "reg-1-4", UseForTest: true, TestPriority: 1, DeniedServices: []string{RegionServiceVpc}},
+			{Name: "reg-2-1", UseForTest: true, TestPriority: 2, MaxResources: map[string]int{RegionCounterVpc: 0}},
+			{Name: "reg-3-2", UseForTest: true, TestPriority: 3, MaxResources: map[string]int{RegionCounterVpc: 2}},
+			{Name: "reg-4-0", UseForTest: true, TestPriority: 4, Blackouts: []RegionBlackout{{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}},
+			{Name: "reg-5-3", UseForTest: true, TestPriority: 5, AllowedServices: []string{RegionServicePowerVS}},
+		},
+	}
+
+	regionNames := func(regions []RegionData) []string {
+		var names []string
+		for _, region := range regions {
+			names = append(names, region.Name)
+		}
+		return names
+	}
+
+	t.Run("NoService", func(t *testing.T) {
+		regions, err := infoSvc.GetTestRegionsByPriority()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reg-1-4", "reg-3-2", "reg-5-3"}, regionNames(regions))
+	})
+
+	t.Run("VpcService", func(t *testing.T) {
+		regions, err := infoSvc.GetTestRegionsByPriority(RegionServiceVpc)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reg-3-2"}, regionNames(regions))
+
+		region, err := infoSvc.GetLeastVpcTestRegion()
+		require.NoError(t, err)
+		assert.Equal(t, "reg-3-2", region)
+	})
+
+	t.Run("PowerVSService", func(t *testing.T) {
+		regions, err := infoSvc.filterRegionsForTest(infoSvc.regionsData[3:], []string{RegionServicePowerVS})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reg-5-3"}, regionNames(regions))
+
+		regions, err = infoSvc.filterRegionsForTest(infoSvc.regionsData[:1], []string{RegionServicePowerVS})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reg-1-4"}, regionNames(regions), "only the VPC service is denied")
+	})
+
+	t.Run("PowerVSZoneWithoutVpcEndpoint", func(t *testing.T) {
+		// zones of the prefs file have no VPC endpoint, their VPC counters are not counted
+		regions, err := infoSvc.filterRegionsForTest(infoSvc.regionsData[1:2], []string{RegionServicePowerVS})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reg-2-1"}, regionNames(regions))
+	})
+}
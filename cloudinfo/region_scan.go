@@ -0,0 +1,114 @@
+package cloudinfo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+)
+
+// defaultRegionScanConcurrency is the number of regions queried at the same time when selecting a region
+const defaultRegionScanConcurrency = 8
+
+// regionCount is the resource count of a region, or the error of counting it
+type regionCount struct {
+	count int
+	err   error
+}
+
+// getRegionalVpcService returns the VPC client for a region endpoint, creating it on first use. Each region has its
+// own client so that regions can be queried concurrently without changing the url of the shared VPC client.
+func (infoSvc *CloudInfoService) getRegionalVpcService(endpoint string) (vpcService, error) {
+	infoSvc.regionalVpcLock.Lock()
+	defer infoSvc.regionalVpcLock.Unlock()
+
+	if service, found := infoSvc.regionalVpcServices[endpoint]; found {
+		return service, nil
+	}
+
+	service, err := infoSvc.newRegionalVpcService()
+	if err != nil {
+		return nil, err
+	}
+	if err := service.SetServiceURL(endpoint); err != nil {
+		return nil, fmt.Errorf("error setting VPC service url %s: %w", endpoint, err)
+	}
+	if infoSvc.regionalVpcServices == nil {
+		infoSvc.regionalVpcServices = make(map[string]vpcService)
+	}
+	infoSvc.regionalVpcServices[endpoint] = service
+	return service, nil
+}
+
+// newRegionalVpcService creates a copy of the shared VPC client, with the same authenticator, headers, HTTP client,
+// retries and API version, whose service url can be set to a regional endpoint
+func (infoSvc *CloudInfoService) newRegionalVpcService() (vpcService, error) {
+	if infoSvc.newVpcService != nil {
+		return infoSvc.newVpcService()
+	}
+	sharedService, ok := infoSvc.vpcService.(*vpcv1.VpcV1)
+	if !ok || sharedService.Service == nil || sharedService.Service.Options == nil {
+		return nil, errors.New("VPC clients for regions can not be created")
+	}
+	return sharedService.Clone(), nil
+}
+
+// scanRegionCounts counts the resources of each region with count, querying up to RegionScanConcurrency regions at the
+// same time. The counts are returned in the order of the regions. Regions after the first region with a count of zero
+// are not counted, as that region is selected regardless of their counts.
+func (infoSvc *CloudInfoService) scanRegionCounts(regions []RegionData, count func(region RegionData, regionVpcService vpcService) (int, error)) []regionCount {
+	counts := make([]regionCount, len(regions))
+
+	// index of the first region with a zero count found so far
+	var firstZero atomic.Int64
+	firstZero.Store(int64(len(regions)))
+
+	infoSvc.scanRegions(regions, func(index int, region RegionData) {
+		if int64(index) > firstZero.Load() {
+			return
+		}
+		regionVpcService, err := infoSvc.getRegionalVpcService(region.Endpoint)
+		if err == nil {
+			counts[index].count, err = count(region, regionVpcService)
+		}
+		counts[index].err = err
+		if err == nil && counts[index].count == 0 {
+			for {
+				current := firstZero.Load()
+				if int64(index) >= current || firstZero.CompareAndSwap(current, int64(index)) {
+					break
+				}
+			}
+		}
+	})
+
+	return counts
+}
+
+// scanRegions calls scan with each region and its index, scanning up to RegionScanConcurrency regions at the same
+// time, and returns once every region is scanned
+func (infoSvc *CloudInfoService) scanRegions(regions []RegionData, scan func(index int, region RegionData)) {
+	concurrency := infoSvc.regionScanConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultRegionScanConcurrency
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency && worker < len(regions); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				scan(index, regions[index])
+			}
+		}()
+	}
+	for index := range regions {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,100 @@
+package cloudinfo
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanRegionCounts(t *testing.T) {
+	sharedVpcService := &vpcServiceMock{mockRegionUrl: "default"}
+	infoSvc := &CloudInfoService{
+		vpcService:            sharedVpcService,
+		newVpcService:         sharedVpcService.newRegionalMock,
+		regionScanConcurrency: 2,
+	}
+
+	var lock sync.Mutex
+	var counted []string
+	countByURL := func(region RegionData, regionVpcService vpcService) (int, error) {
+		lock.Lock()
+		counted = append(counted, region.Name)
+		lock.Unlock()
+		regionMock := regionVpcService.(*vpcServiceMock)
+		switch regionMock.mockRegionUrl {
+		case "reg-zero":
+			return 0, nil
+		case "reg-error":
+			return 0, errors.New("mock count error")
+		default:
+			return len(regionMock.mockRegionUrl), nil
+		}
+	}
+
+	t.Run("CountsInRegionOrder", func(t *testing.T) {
+		regions := []RegionData{{Name: "a", Endpoint: "reg-a"}, {Name: "bb", Endpoint: "reg-bb"}, {Name: "ccc", Endpoint: "reg-ccc"}}
+		counts := infoSvc.scanRegionCounts(regions, countByURL)
+		require.Len(t, counts, 3)
+		assert.Equal(t, []regionCount{{count: 5}, {count: 6}, {count: 7}}, counts)
+		assert.Equal(t, "default", sharedVpcService.mockRegionUrl, "shared VPC client must not be changed")
+	})
+
+	t.Run("ZeroCountShortCircuit", func(t *testing.T) {
+		infoSvc.regionScanConcurrency = 1
+		counted = nil
+		regions := []RegionData{{Name: "a", Endpoint: "reg-a"}, {Name: "zero", Endpoint: "reg-zero"}, {Name: "ccc", Endpoint: "reg-ccc"}}
+		counts := infoSvc.scanRegionCounts(regions, countByURL)
+		assert.Equal(t, 0, counts[1].count)
+		assert.NoError(t, counts[1].err)
+		assert.Equal(t, []string{"a", "zero"}, counted, "regions after the first zero count are not queried")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		regions := []RegionData{{Name: "error", Endpoint: "reg-error"}, {Name: "a", Endpoint: "reg-a"}}
+		counts := infoSvc.scanRegionCounts(regions, countByURL)
+		assert.EqualError(t, counts[0].err, "mock count error")
+		assert.NoError(t, counts[1].err)
+	})
+
+	t.Run("RegionalClientsAreReused", func(t *testing.T) {
+		first, err := infoSvc.getRegionalVpcService("reg-a")
+		require.NoError(t, err)
+		second, err := infoSvc.getRegionalVpcService("reg-a")
+		require.NoError(t, err)
+		other, err := infoSvc.getRegionalVpcService("reg-bb")
+		require.NoError(t, err)
+		assert.Same(t, first, second)
+		assert.NotSame(t, first, other)
+	})
+
+	t.Run("NoClientFactory", func(t *testing.T) {
+		_, err := (&CloudInfoService{vpcService: sharedVpcService}).getRegionalVpcService("reg-a")
+		assert.Error(t, err)
+	})
+
+	t.Run("RegionalClientKeepsConfig", func(t *testing.T) {
+		shared, err := vpcv1.NewVpcV1(&vpcv1.VpcV1Options{
+			Authenticator: &core.NoAuthAuthenticator{},
+			URL:           "https://shared.example.com/v1",
+		})
+		require.NoError(t, err)
+		shared.SetDefaultHeaders(http.Header{"X-Test": []string{"value"}})
+		shared.EnableRetries(3, 0)
+
+		regional, err := (&CloudInfoService{vpcService: shared}).getRegionalVpcService("https://regional.example.com/v1")
+		require.NoError(t, err)
+		regionalService, ok := regional.(*vpcv1.VpcV1)
+		require.True(t, ok)
+		assert.Equal(t, "https://regional.example.com/v1", regionalService.GetServiceURL())
+		assert.Equal(t, "value", regionalService.Service.DefaultHeaders.Get("X-Test"))
+		assert.Equal(t, shared.Service.Options.Authenticator, regionalService.Service.Options.Authenticator)
+		assert.Equal(t, *shared.Version, *regionalService.Version)
+		assert.Equal(t, "https://shared.example.com/v1", shared.GetServiceURL(), "shared client url is not changed")
+	})
+}
@@ -0,0 +1,328 @@
+package cloudinfo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// Names of the built-in region counters, used in the `scoring` section of the region prefs file
+const (
+	RegionCounterVpc            = "vpc"
+	RegionCounterLoadBalancer   = "load_balancer"
+	RegionCounterTransitGateway = "transit_gateway"
+	// RegionCounterServicePrefix is followed by a CRN service name, for example `service:logdnaat`, to count the
+	// instances of the service in each region
+	RegionCounterServicePrefix = "service:"
+)
+
+// RegionCounter counts the resources of a region that a RegionCriterion scores
+type RegionCounter interface {
+	CountRegion(region RegionData) (int, error)
+}
+
+// RegionCounterFunc is a RegionCounter implemented by a function
+type RegionCounterFunc func(region RegionData) (int, error)
+
+func (counter RegionCounterFunc) CountRegion(region RegionData) (int, error) {
+	return counter(region)
+}
+
+// RegionCriterion is one criterion of a RegionScorer. The score of a region for the criterion is its count multiplied
+// by the weight, a criterion with a zero Weight is counted but not scored, for a Max only. If Max is set, a region with
+// a count above Max is excluded, regardless of its score.
+type RegionCriterion struct {
+	Name    string
+	Counter RegionCounter
+	Weight  float64
+	Max     *int
+}
+
+// RegionScoringConfig is the `scoring` section of the region prefs file, see LoadRegionPrefsFromFile
+type RegionScoringConfig struct {
+	Criteria []RegionCriterionConfig `yaml:"criteria"`
+	// SupportedRegions excludes all other regions, for example the regions that offer a service. Empty for no restriction.
+	SupportedRegions []string `yaml:"supportedRegions"`
+}
+
+// RegionCriterionConfig is a criterion of the region prefs file, Counter is the name of a built-in counter. The
+// weight is 1 if it is not set.
+type RegionCriterionConfig struct {
+	Counter string   `yaml:"counter"`
+	Weight  *float64 `yaml:"weight"`
+	Max     *int     `yaml:"max"`
+}
+
+// RegionScorer ranks the test regions by the weighted sum of the counts of its criteria, lowest first. Create it with
+// CloudInfoService.NewRegionScorer.
+type RegionScorer struct {
+	Criteria []RegionCriterion
+	// SupportedRegions excludes all other regions. Empty for no restriction.
+	SupportedRegions []string
+	// Services are the CRN service names of the test, regions whose preferences do not allow one of them are not
+	// ranked, see RegionData.AllowedServices. Default: RegionServiceVpc
+	Services []string
+	infoSvc  *CloudInfoService
+}
+
+// RegionScore is the score of a region with the count and score of each criterion
+type RegionScore struct {
+	Region   string
+	Priority int
+	Score    float64
+	Counts   map[string]int
+	Scores   map[string]float64
+	Excluded string // reason the region is excluded by a hard constraint, empty if the region can be selected
+}
+
+// errRegionScorerNotCreated is returned by a RegionScorer that was not created with CloudInfoService.NewRegionScorer
+var errRegionScorerNotCreated = errors.New("region scorer has no CloudInfoService, create it with CloudInfoService.NewRegionScorer")
+
+// NewRegionScorer returns a scorer with the criteria. If no criteria are given, the criteria and supported regions of the
+// `scoring` section of the loaded region prefs file are used.
+func (infoSvc *CloudInfoService) NewRegionScorer(criteria ...RegionCriterion) (*RegionScorer, error) {
+	scorer := &RegionScorer{Criteria: criteria, Services: []string{RegionServiceVpc}, infoSvc: infoSvc}
+	if len(criteria) > 0 {
+		return scorer, nil
+	}
+	if infoSvc.regionScoring == nil || len(infoSvc.regionScoring.Criteria) == 0 {
+		return nil, errors.New("no region scoring criteria were given or loaded from the region prefs file")
+	}
+
+	for _, config := range infoSvc.regionScoring.Criteria {
+		counter, err := infoSvc.GetRegionCounter(config.Counter)
+		if err != nil {
+			return nil, err
+		}
+		weight := 1.0
+		if config.Weight != nil {
+			weight = *config.Weight
+		}
+		scorer.Criteria = append(scorer.Criteria, RegionCriterion{
+			Name:    config.Counter,
+			Counter: counter,
+			Weight:  weight,
+			Max:     config.Max,
+		})
+	}
+	scorer.SupportedRegions = infoSvc.regionScoring.SupportedRegions
+	return scorer, nil
+}
+
+// GetRegionCounter returns the built-in counter with the name, see RegionCounterVpc and the other counter names
+func (infoSvc *CloudInfoService) GetRegionCounter(name string) (RegionCounter, error) {
+	switch {
+	case name == RegionCounterVpc:
+		return infoSvc.VpcRegionCounter(), nil
+	case name == RegionCounterLoadBalancer:
+		return infoSvc.LoadBalancerRegionCounter(), nil
+	case name == RegionCounterTransitGateway:
+		return infoSvc.TransitGatewayRegionCounter(), nil
+	case strings.HasPrefix(name, RegionCounterServicePrefix) && len(name) > len(RegionCounterServicePrefix):
+		return infoSvc.ServiceInstanceRegionCounter(strings.TrimPrefix(name, RegionCounterServicePrefix)), nil
+	default:
+		return nil, fmt.Errorf("unknown region counter %q", name)
+	}
+}
+
+// VpcRegionCounter returns a counter of the VPCs of a region
+func (infoSvc *CloudInfoService) VpcRegionCounter() RegionCounter {
+	return RegionCounterFunc(func(region RegionData) (int, error) {
+		regionVpcService, err := infoSvc.getRegionalVpcService(region.Endpoint)
+		if err != nil {
+			return 0, err
+		}
+		vpcCol, _, err := regionVpcService.ListVpcs(&vpcv1.ListVpcsOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("error listing VPCs of region %s: %w", region.Name, err)
+		}
+		return int(*vpcCol.TotalCount), nil
+	})
+}
+
+// LoadBalancerRegionCounter returns a counter of the load balancers of a region
+func (infoSvc *CloudInfoService) LoadBalancerRegionCounter() RegionCounter {
+	return RegionCounterFunc(func(region RegionData) (int, error) {
+		regionVpcService, err := infoSvc.getRegionalVpcService(region.Endpoint)
+		if err != nil {
+			return 0, err
+		}
+		lbCol, _, err := regionVpcService.ListLoadBalancers(&vpcv1.ListLoadBalancersOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("error listing load balancers of region %s: %w", region.Name, err)
+		}
+		return int(*lbCol.TotalCount), nil
+	})
+}
+
+// TransitGatewayRegionCounter returns a counter of the transit gateways of a region. The transit gateways of the
+// account are listed once, on the first count.
+func (infoSvc *CloudInfoService) TransitGatewayRegionCounter() RegionCounter {
+	var once sync.Once
+	var counts map[string]int
+	var listErr error
+	return RegionCounterFunc(func(region RegionData) (int, error) {
+		once.Do(func() {
+			counts, listErr = infoSvc.countTransitGatewaysByRegion()
+		})
+		return counts[region.Name], listErr
+	})
+}
+
+// ServiceInstanceRegionCounter returns a counter of the instances of a service, by CRN service name, in a region.
+// The instances of the account are listed once, on the first count.
+func (infoSvc *CloudInfoService) ServiceInstanceRegionCounter(crnServiceName string) RegionCounter {
+	var once sync.Once
+	counts := make(map[string]int)
+	var listErr error
+	return RegionCounterFunc(func(region RegionData) (int, error) {
+		once.Do(func() {
+			instances, err := infoSvc.ListResourcesByCrnServiceName(crnServiceName)
+			if err != nil {
+				listErr = fmt.Errorf("error listing %s instances: %w", crnServiceName, err)
+				return
+			}
+			for _, instance := range instances {
+				if instance.RegionID != nil {
+					counts[*instance.RegionID]++
+				}
+			}
+		})
+		return counts[region.Name], listErr
+	})
+}
+
+// Rank returns the scores of the test regions, see GetTestRegionsByPriority. Regions that can be selected come first,
+// ordered by score and then priority, followed by the excluded regions.
+func (scorer *RegionScorer) Rank() ([]RegionScore, error) {
+	if scorer.infoSvc == nil {
+		return nil, errRegionScorerNotCreated
+	}
+	regions, err := scorer.infoSvc.GetTestRegionsByPriority(scorer.Services...)
+	if err != nil {
+		return nil, err
+	}
+	return scorer.rank(regions)
+}
+
+// Best returns the region with the lowest score, excluding regions leased by other test processes, and logs the scores
+// of all regions.
+func (scorer *RegionScorer) Best() (string, error) {
+	if scorer.infoSvc == nil {
+		return "", errRegionScorerNotCreated
+	}
+	regions, err := scorer.infoSvc.GetTestRegionsByPriority(scorer.Services...)
+	if err != nil {
+		return "", err
+	}
+
+	// skip regions selected by other test processes, see CloudInfoServiceOptions.RegionLeases
+	unlockSelection := scorer.infoSvc.lockRegionSelection()
+	defer unlockSelection()
+	regions = scorer.infoSvc.filterLeasedRegions(regions)
+
+	scores, err := scorer.rank(regions)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Region scores:\n%s", FormatRegionScores(scores))
+	if len(scores) == 0 || scores[0].Excluded != "" {
+		return "", errors.New("no region satisfies the region scoring constraints")
+	}
+
+	log.Printf("Selected region %s with score %g", scores[0].Region, scores[0].Score)
+	scorer.infoSvc.leaseRegion(scores[0].Region)
+	return scores[0].Region, nil
+}
+
+// rank scores the regions, the criteria of each region are counted concurrently
+func (scorer *RegionScorer) rank(regions []RegionData) ([]RegionScore, error) {
+	scores := make([]RegionScore, len(regions))
+	errs := make([]error, len(regions))
+
+	concurrency := scorer.infoSvc.regionScanConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultRegionScanConcurrency
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for index, region := range regions {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			scores[index], errs[index] = scorer.scoreRegion(region)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// the regions are sorted by priority, a stable sort keeps that order for equal scores
+	sort.SliceStable(scores, func(i, j int) bool {
+		if (scores[i].Excluded == "") != (scores[j].Excluded == "") {
+			return scores[i].Excluded == ""
+		}
+		return scores[i].Score < scores[j].Score
+	})
+	return scores, nil
+}
+
+// scoreRegion counts the criteria of a region and checks its hard constraints
+func (scorer *RegionScorer) scoreRegion(region RegionData) (RegionScore, error) {
+	score := RegionScore{
+		Region:   region.Name,
+		Priority: region.TestPriority,
+		Counts:   make(map[string]int, len(scorer.Criteria)),
+		Scores:   make(map[string]float64, len(scorer.Criteria)),
+	}
+	if len(scorer.SupportedRegions) > 0 && !common.StrArrayContains(scorer.SupportedRegions, region.Name) {
+		score.Excluded = "not a supported region"
+		return score, nil
+	}
+
+	for _, criterion := range scorer.Criteria {
+		count, err := criterion.Counter.CountRegion(region)
+		if err != nil {
+			return score, fmt.Errorf("error counting %s of region %s: %w", criterion.Name, region.Name, err)
+		}
+		score.Counts[criterion.Name] = count
+		score.Scores[criterion.Name] = criterion.Weight * float64(count)
+		score.Score += criterion.Weight * float64(count)
+		if criterion.Max != nil && count > *criterion.Max && score.Excluded == "" {
+			score.Excluded = fmt.Sprintf("%s count %d is above the maximum of %d", criterion.Name, count, *criterion.Max)
+		}
+	}
+	return score, nil
+}
+
+// FormatRegionScores returns one line per region with the count and score of each criterion, for logging
+func FormatRegionScores(scores []RegionScore) string {
+	lines := make([]string, 0, len(scores))
+	for _, score := range scores {
+		names := make([]string, 0, len(score.Counts))
+		for name := range score.Counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		line := fmt.Sprintf("%s (priority %d): score %g", score.Region, score.Priority, score.Score)
+		for _, name := range names {
+			line += fmt.Sprintf(", %s %d (%g)", name, score.Counts[name], score.Scores[name])
+		}
+		if score.Excluded != "" {
+			line += fmt.Sprintf(" - excluded: %s", score.Excluded)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,182 @@
+package cloudinfo
+
+import (
+	"errors"
+	"testing"
+
+	transitgatewayapisv1 "github.com/IBM/networking-go-sdk/transitgatewayapisv1"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionScorer(t *testing.T) {
+	vpcService := &vpcServiceMock{}
+	tgLocation := "reg-2-1"
+	infoSvc := &CloudInfoService{
+		vpcService:    vpcService,
+		newVpcService: vpcService.newRegionalMock,
+		transitGatewayService: &transitGatewayServiceMock{
+			mockTransitGateways: &transitgatewayapisv1.TransitGatewayCollection{
+				TransitGateways: []transitgatewayapisv1.TransitGateway{{Location: &tgLocation}, {Location: &tgLocation}},
+			},
+		},
+	}
+	regions := []RegionData{
+		{Name: "reg-1-4", Endpoint: "reg-1-4", UseForTest: true, TestPriority: 1},
+		{Name: "reg-2-1", Endpoint: "reg-2-1", UseForTest: true, TestPriority: 2},
+		{Name: "reg-3-2", Endpoint: "reg-3-2", UseForTest: true, TestPriority: 3},
+	}
+	infoSvc.regionsData = regions
+
+	t.Run("WeightedRank", func(t *testing.T) {
+		scorer, err := infoSvc.NewRegionScorer(
+			RegionCriterion{Name: "vpc", Counter: infoSvc.VpcRegionCounter(), Weight: 2},
+			RegionCriterion{Name: "transit_gateway", Counter: infoSvc.TransitGatewayRegionCounter(), Weight: 5},
+		)
+		require.NoError(t, err)
+		scores, err := scorer.Rank()
+		require.NoError(t, err)
+		require.Len(t, scores, 3)
+		assert.Equal(t, "reg-3-2", scores[0].Region)
+		assert.Equal(t, 4.0, scores[0].Score)
+		assert.Equal(t, "reg-1-4", scores[1].Region)
+		assert.Equal(t, "reg-2-1", scores[2].Region)
+		assert.Equal(t, map[string]int{"vpc": 1, "transit_gateway": 2}, scores[2].Counts)
+		assert.Equal(t, map[string]float64{"vpc": 2, "transit_gateway": 10}, scores[2].Scores)
+	})
+
+	t.Run("PriorityBreaksTies", func(t *testing.T) {
+		scorer, err := infoSvc.NewRegionScorer(RegionCriterion{Name: "none", Counter: RegionCounterFunc(func(RegionData) (int, error) { return 0, nil })})
+		require.NoError(t, err)
+		best, err := scorer.Best()
+		require.NoError(t, err)
+		assert.Equal(t, "reg-1-4", best)
+	})
+
+	t.Run("HardConstraints", func(t *testing.T) {
+		maxVpcs := 2
+		scorer, err := infoSvc.NewRegionScorer(RegionCriterion{Name: "vpc", Counter: infoSvc.VpcRegionCounter(), Max: &maxVpcs})
+		require.NoError(t, err)
+		scorer.SupportedRegions = []string{"reg-1-4", "reg-2-1"}
+		scores, err := scorer.Rank()
+		require.NoError(t, err)
+		assert.Equal(t, "reg-2-1", scores[0].Region)
+		assert.Empty(t, scores[0].Excluded)
+		assert.NotEmpty(t, scores[1].Excluded)
+		assert.NotEmpty(t, scores[2].Excluded)
+
+		best, err := scorer.Best()
+		require.NoError(t, err)
+		assert.Equal(t, "reg-2-1", best)
+
+		scorer.SupportedRegions = []string{"reg-1-4"}
+		_, err = scorer.Best()
+		assert.Error(t, err)
+	})
+
+	t.Run("ZeroWeight", func(t *testing.T) {
+		maxVpcs := 3
+		scorer, err := infoSvc.NewRegionScorer(
+			RegionCriterion{Name: "vpc", Counter: infoSvc.VpcRegionCounter(), Max: &maxVpcs},
+			RegionCriterion{Name: "transit_gateway", Counter: infoSvc.TransitGatewayRegionCounter(), Weight: 1},
+		)
+		require.NoError(t, err)
+		scores, err := scorer.Rank()
+		require.NoError(t, err)
+		assert.Equal(t, "reg-3-2", scores[0].Region, "a criterion with a zero weight is not scored")
+		assert.Equal(t, 0.0, scores[0].Score)
+		assert.Equal(t, 2, scores[0].Counts["vpc"])
+		assert.Equal(t, "reg-2-1", scores[1].Region)
+		assert.NotEmpty(t, scores[2].Excluded, "the maximum of a criterion with a zero weight is checked")
+	})
+
+	t.Run("Services", func(t *testing.T) {
+		serviceSvc := &CloudInfoService{
+			vpcService:    vpcService,
+			newVpcService: vpcService.newRegionalMock,
+			regionsData: []RegionData{
+				{Name: "reg-1-4", Endpoint: "reg-1-4", UseForTest: true, TestPriority: 1, DeniedServices: []string{RegionServiceVpc}},
+				{Name: "reg-2-1", Endpoint: "reg-2-1", UseForTest: true, TestPriority: 2},
+			},
+		}
+		scorer, err := serviceSvc.NewRegionScorer(RegionCriterion{Name: "none", Counter: RegionCounterFunc(func(RegionData) (int, error) { return 0, nil })})
+		require.NoError(t, err)
+		scores, err := scorer.Rank()
+		require.NoError(t, err)
+		require.Len(t, scores, 1, "regions that deny the VPC service are not ranked by default")
+		assert.Equal(t, "reg-2-1", scores[0].Region)
+
+		scorer.Services = []string{RegionServicePowerVS}
+		scores, err = scorer.Rank()
+		require.NoError(t, err)
+		assert.Len(t, scores, 2)
+	})
+
+	t.Run("NotCreatedWithService", func(t *testing.T) {
+		scorer := &RegionScorer{}
+		_, err := scorer.Rank()
+		assert.Error(t, err)
+		_, err = scorer.Best()
+		assert.Error(t, err)
+	})
+
+	t.Run("ServiceInstanceCounter", func(t *testing.T) {
+		count := int64(2)
+		crn := "crn:v1:bluemix:public:kms:reg-1-4:a/account:::"
+		infoSvc.resourceControllerService = &resourceControllerServiceMock{
+			mockResourceList: &resourcecontrollerv2.ResourceInstancesList{
+				RowsCount: &count,
+				Resources: []resourcecontrollerv2.ResourceInstance{
+					{CRN: &crn, RegionID: &regions[0].Name},
+					{CRN: &crn, RegionID: &regions[0].Name},
+				},
+			},
+		}
+		counter, err := infoSvc.GetRegionCounter("service:kms")
+		require.NoError(t, err)
+		instances, err := counter.CountRegion(regions[0])
+		require.NoError(t, err)
+		assert.Equal(t, 2, instances)
+		instances, err = counter.CountRegion(regions[1])
+		require.NoError(t, err)
+		assert.Equal(t, 0, instances)
+	})
+
+	t.Run("CounterError", func(t *testing.T) {
+		scorer, err := infoSvc.NewRegionScorer(RegionCriterion{Name: "broken", Counter: RegionCounterFunc(func(RegionData) (int, error) { return 0, errors.New("mock count error") })})
+		require.NoError(t, err)
+		_, err = scorer.Rank()
+		assert.ErrorContains(t, err, "mock count error")
+	})
+
+	t.Run("UnknownCounter", func(t *testing.T) {
+		_, err := infoSvc.GetRegionCounter("unknown")
+		assert.Error(t, err)
+	})
+
+	t.Run("FromPrefsFile", func(t *testing.T) {
+		prefsSvc := &CloudInfoService{
+			vpcService:            vpcService,
+			newVpcService:         vpcService.newRegionalMock,
+			transitGatewayService: infoSvc.transitGatewayService,
+		}
+		_, err := prefsSvc.NewRegionScorer()
+		assert.Error(t, err, "no criteria without a scoring section")
+
+		require.NoError(t, prefsSvc.LoadRegionPrefsFromFile("testdata/region-scoring-prefs.yaml"))
+		assert.Len(t, prefsSvc.regionsData, 3)
+		scorer, err := prefsSvc.NewRegionScorer()
+		require.NoError(t, err)
+		require.Len(t, scorer.Criteria, 2)
+		assert.Equal(t, 2.0, scorer.Criteria[0].Weight)
+		assert.Equal(t, 1, *scorer.Criteria[1].Max)
+
+		scores, err := scorer.Rank()
+		require.NoError(t, err)
+		assert.Equal(t, "reg-3-2", scores[0].Region)
+		assert.Equal(t, "reg-1-4", scores[1].Region)
+		assert.Contains(t, scores[2].Excluded, "transit_gateway")
+		assert.Contains(t, FormatRegionScores(scores), "reg-2-1 (priority 2): score 4, transit_gateway 2 (2), vpc 1 (2) - excluded")
+	})
+}
@@ -18,6 +18,7 @@ func TestLeastVpcAllAvailRegions(t *testing.T) {
 	// first test, low priority wins
 	infoSvc := CloudInfoService{
 		vpcService:                vpcService,
+		newVpcService:             vpcService.newRegionalMock,
 		resourceControllerService: resourceControllerService,
 		regionsData: []RegionData{
 			{Name: "reg-1-10", UseForTest: true, TestPriority: 1},
@@ -115,6 +116,7 @@ func TestLeastSdnlbAllAvailRegions(t *testing.T) {
 	//create main cloud service objects with mock service and region data
 	infoSvc := CloudInfoService{
 		vpcService:                vpcService,
+		newVpcService:             vpcService.newRegionalMock,
 		resourceControllerService: resourceControllerService,
 		regionsData: []RegionData{
 			{Name: "reg-1-10", UseForTest: true, TestPriority: 1},
@@ -191,6 +193,7 @@ func TestLeastSdnlbAllAvailRegions(t *testing.T) {
 
 		infoSvcErr := CloudInfoService{
 			vpcService:                vpcServiceErr,
+			newVpcService:             vpcServiceErr.newRegionalMock,
 			resourceControllerService: resourceControllerService,
 			regionsData: []RegionData{
 				{Name: "reg-1-10", UseForTest: true, TestPriority: 1},
@@ -211,6 +214,7 @@ func TestLeastSdnlbAllAvailRegions(t *testing.T) {
 
 		infoSvcErr := CloudInfoService{
 			vpcService:                vpcServiceErr,
+			newVpcService:             vpcServiceErr.newRegionalMock,
 			resourceControllerService: resourceControllerService,
 			regionsData: []RegionData{
 				{Name: "reg-1-10", UseForTest: true, TestPriority: 1},
@@ -232,6 +236,7 @@ func TestLeastSdnlbAllAvailRegions(t *testing.T) {
 
 		infoSvcErr := CloudInfoService{
 			vpcService:                vpcServiceErr,
+			newVpcService:             vpcServiceErr.newRegionalMock,
 			resourceControllerService: resourceControllerService,
 			regionsData: []RegionData{
 				{Name: "reg-1-10", UseForTest: true, TestPriority: 1},
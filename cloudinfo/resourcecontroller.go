@@ -212,6 +212,10 @@ func PrintResources(resources []resourcecontrollerv2.ResourceInstance) {
 // resources is a list of resources to print
 func PrintResourceKey(keyList []string, resources []resourcecontrollerv2.ResourceInstance) {
 	for _, resource := range resources {
+		lastOperation := resource.LastOperation
+		if lastOperation == nil {
+			lastOperation = &resourcecontrollerv2.ResourceInstanceLastOperation{}
+		}
 		resourceMap := map[string]interface{}{
 			"Name":               core.StringNilMapper(resource.Name),
 			"Location":           core.StringNilMapper(resource.RegionID),
@@ -227,8 +231,8 @@ func PrintResourceKey(keyList []string, resources []resourcecontrollerv2.Resourc
 			"CreatedBy":          core.StringNilMapper(resource.CreatedBy),
 			"DashboardURL":       core.StringNilMapper(resource.DashboardURL),
 			"GUID":               core.StringNilMapper(resource.GUID),
-			"LastOperationState": core.StringNilMapper(resource.LastOperation.State),
-			"LastOperationType":  core.StringNilMapper(resource.LastOperation.Type),
+			"LastOperationState": core.StringNilMapper(lastOperation.State),
+			"LastOperationType":  core.StringNilMapper(lastOperation.Type),
 			"ScheduledReclaimBy": core.StringNilMapper(resource.ScheduledReclaimBy),
 		}
 
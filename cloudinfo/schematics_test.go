@@ -72,6 +72,14 @@ func (m *mockSchematicsService) DeleteWorkspace(options *schematics.DeleteWorksp
 	return args.Get(0).(*string), args.Get(1).(*core.DetailedResponse), args.Error(2)
 }
 
+func (m *mockSchematicsService) ListWorkspaces(options *schematics.ListWorkspacesOptions) (*schematics.WorkspaceResponseList, *core.DetailedResponse, error) {
+	args := m.Called(options)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*core.DetailedResponse), args.Error(2)
+	}
+	return args.Get(0).(*schematics.WorkspaceResponseList), args.Get(1).(*core.DetailedResponse), args.Error(2)
+}
+
 func (m *mockSchematicsService) TemplateRepoUpload(options *schematics.TemplateRepoUploadOptions) (*schematics.TemplateRepoTarUploadResponse, *core.DetailedResponse, error) {
 	args := m.Called(options)
 	if args.Get(0) == nil {
@@ -26,6 +26,7 @@ import (
 	"github.com/IBM/cloud-databases-go-sdk/clouddatabasesv5"
 	"github.com/IBM/go-sdk-core/v5/core"
 	"github.com/IBM/platform-services-go-sdk/contextbasedrestrictionsv1"
+	"github.com/IBM/platform-services-go-sdk/globaltaggingv1"
 	"github.com/IBM/platform-services-go-sdk/iamidentityv1"
 	"github.com/IBM/platform-services-go-sdk/iampolicymanagementv1"
 	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
@@ -157,7 +158,9 @@ type CloudInfoService struct {
 	containerV1Client         containerV1Client
 	catalogService            catalogService
 	globalCatalogBaseURL      string
+	kmsBaseURL                string // Key Protect endpoint, only set to support testing, the endpoint of the key region is used if empty
 	transitGatewayService     transitGatewayService
+	globalTaggingService      globalTaggingService
 	// stackDefinitionCreator is used to create stack definitions and only added to support testing/mocking
 	stackDefinitionCreator StackDefinitionCreator
 	regionsData            []RegionData
@@ -175,6 +178,19 @@ type CloudInfoService struct {
 	apiCache *APICache
 	// offeringSingleflight prevents duplicate concurrent GetOffering requests
 	offeringSingleflight singleflight.Group
+	// regionLeases coordinates region selection with other test processes, nil if not coordinated
+	regionLeases *common.LeaseManager
+	// regionLeaseMaxHolders is the number of tests that can lease a region at the same time
+	regionLeaseMaxHolders int
+	// regionalVpcServices holds a VPC client for each region endpoint, used to query regions concurrently
+	regionalVpcServices map[string]vpcService
+	regionalVpcLock     sync.Mutex
+	// newVpcService creates the VPC clients of regionalVpcServices, only set to support testing/mocking
+	newVpcService func() (vpcService, error)
+	// regionScanConcurrency is the number of regions queried at the same time when selecting a region
+	regionScanConcurrency int
+	// regionScoring is the `scoring` section of the region prefs file, used by NewRegionScorer
+	regionScoring *RegionScoringConfig
 }
 
 // interface for the cloudinfo service (can be mocked in tests)
@@ -303,6 +319,7 @@ type CloudInfoServiceOptions struct {
 	GlobalCatalogBaseURL      string
 	SchematicsServices        map[string]schematicsService
 	TransitGatewayService     transitGatewayService
+	GlobalTaggingService      globalTaggingService
 	// StackDefinitionCreator is used to create stack definitions and only added to support testing/mocking
 	StackDefinitionCreator StackDefinitionCreator
 	Logger                 common.Logger // Logger option for CloudInfoService
@@ -312,6 +329,16 @@ type CloudInfoServiceOptions struct {
 	CacheTTL time.Duration
 	// BypassCacheForValidation forces cache bypass for critical validation operations
 	BypassCacheForValidation bool
+	// RegionLeases coordinates region selection with other test processes: regions selected by another test are not
+	// selected until its teardown releases the lease, see ReleaseRegionLease. Default is the lease manager of
+	// common.GetDefaultLeaseManager if the TEST_LEASE_DIR environment variable is set, otherwise regions are only
+	// coordinated within the process.
+	RegionLeases *common.LeaseManager
+	// RegionLeaseMaxHolders is the number of tests of all processes that can use a region at the same time when region
+	// leases are enabled, regions are only shared by more tests once every region is fully leased (default: 1)
+	RegionLeaseMaxHolders int
+	// RegionScanConcurrency is the number of regions queried at the same time when selecting a region (default: 8)
+	RegionScanConcurrency int
 }
 
 // RegionData is a data structure used for holding configurable information about a region.
@@ -323,6 +350,23 @@ type RegionData struct {
 	Endpoint      string
 	Status        string
 	ResourceCount int
+	// AllowedServices, if not empty, are the only services tested in the region, by CRN service name (for example `is`
+	// for VPC or `power-iaas` for PowerVS)
+	AllowedServices []string `yaml:"allowedServices"`
+	// DeniedServices are the services not tested in the region, by CRN service name
+	DeniedServices []string `yaml:"deniedServices"`
+	// MaxResources are the resource counts, by region counter name (see GetRegionCounter), above which the region is full.
+	// The VPC and load balancer counts are not checked when selecting PowerVS zones.
+	MaxResources map[string]int `yaml:"maxResources"`
+	// Blackouts are the time windows in which the region is not used for test, for example known maintenance
+	Blackouts []RegionBlackout `yaml:"blackouts"`
+}
+
+// RegionBlackout is a time window in which a region is not used for test
+type RegionBlackout struct {
+	Start  time.Time `yaml:"start"`
+	End    time.Time `yaml:"end"`
+	Reason string    `yaml:"reason"`
 }
 
 // vpcService interface for an external VPC Service API. Used for mocking external service in tests.
@@ -332,6 +376,13 @@ type vpcService interface {
 	ListLoadBalancers(*vpcv1.ListLoadBalancersOptions) (*vpcv1.LoadBalancerCollection, *core.DetailedResponse, error)
 	NewGetRegionOptions(string) *vpcv1.GetRegionOptions
 	ListVpcs(*vpcv1.ListVpcsOptions) (*vpcv1.VPCCollection, *core.DetailedResponse, error)
+	DeleteVPC(*vpcv1.DeleteVPCOptions) (*core.DetailedResponse, error)
+	ListRegionZones(*vpcv1.ListRegionZonesOptions) (*vpcv1.ZoneCollection, *core.DetailedResponse, error)
+	ListInstances(*vpcv1.ListInstancesOptions) (*vpcv1.InstanceCollection, *core.DetailedResponse, error)
+	ListSubnets(*vpcv1.ListSubnetsOptions) (*vpcv1.SubnetCollection, *core.DetailedResponse, error)
+	ListBareMetalServers(*vpcv1.ListBareMetalServersOptions) (*vpcv1.BareMetalServerCollection, *core.DetailedResponse, error)
+	ListPublicGateways(*vpcv1.ListPublicGatewaysOptions) (*vpcv1.PublicGatewayCollection, *core.DetailedResponse, error)
+	ListEndpointGateways(*vpcv1.ListEndpointGatewaysOptions) (*vpcv1.EndpointGatewayCollection, *core.DetailedResponse, error)
 	SetServiceURL(string) error
 }
 
@@ -352,6 +403,8 @@ type resourceControllerService interface {
 	ListReclamations(*resourcecontrollerv2.ListReclamationsOptions) (*resourcecontrollerv2.ReclamationsList, *core.DetailedResponse, error)
 	ListResourceInstances(*resourcecontrollerv2.ListResourceInstancesOptions) (*resourcecontrollerv2.ResourceInstancesList, *core.DetailedResponse, error)
 	RunReclamationAction(*resourcecontrollerv2.RunReclamationActionOptions) (*resourcecontrollerv2.Reclamation, *core.DetailedResponse, error)
+	NewDeleteResourceInstanceOptions(string) *resourcecontrollerv2.DeleteResourceInstanceOptions
+	DeleteResourceInstance(*resourcecontrollerv2.DeleteResourceInstanceOptions) (*core.DetailedResponse, error)
 }
 
 // resourceManagerService for external Resource Manager V2 Service API. Used for mocking.
@@ -399,6 +452,7 @@ type projectsService interface {
 	GetProject(getProjectOptions *projects.GetProjectOptions) (result *projects.Project, response *core.DetailedResponse, err error)
 	UpdateProject(updateProjectOptions *projects.UpdateProjectOptions) (result *projects.Project, response *core.DetailedResponse, err error)
 	DeleteProject(deleteProjectOptions *projects.DeleteProjectOptions) (result *projects.ProjectDeleteResponse, response *core.DetailedResponse, err error)
+	ListProjects(listProjectsOptions *projects.ListProjectsOptions) (result *projects.ProjectCollection, response *core.DetailedResponse, err error)
 
 	NewCreateConfigOptions(projectID string, definition projects.ProjectConfigDefinitionPrototypeIntf) *projects.CreateConfigOptions
 	NewConfigsPager(listConfigsOptions *projects.ListConfigsOptions) (*projects.ConfigsPager, error)
@@ -427,6 +481,7 @@ type catalogService interface {
 	GetVersion(getVersionOptions *catalogmanagementv1.GetVersionOptions) (result *catalogmanagementv1.Offering, response *core.DetailedResponse, err error)
 	CreateCatalog(createCatalogOptions *catalogmanagementv1.CreateCatalogOptions) (result *catalogmanagementv1.Catalog, response *core.DetailedResponse, err error)
 	DeleteCatalog(deleteCatalogOptions *catalogmanagementv1.DeleteCatalogOptions) (response *core.DetailedResponse, err error)
+	ListCatalogs(listCatalogsOptions *catalogmanagementv1.ListCatalogsOptions) (result *catalogmanagementv1.CatalogSearchResult, response *core.DetailedResponse, err error)
 	ImportOffering(importOfferingOptions *catalogmanagementv1.ImportOfferingOptions) (result *catalogmanagementv1.Offering, response *core.DetailedResponse, err error)
 	GetOffering(getOfferingOptions *catalogmanagementv1.GetOfferingOptions) (result *catalogmanagementv1.Offering, response *core.DetailedResponse, err error)
 }
@@ -443,6 +498,7 @@ type schematicsService interface {
 	CreateWorkspace(*schematics.CreateWorkspaceOptions) (*schematics.WorkspaceResponse, *core.DetailedResponse, error)
 	UpdateWorkspace(*schematics.UpdateWorkspaceOptions) (*schematics.WorkspaceResponse, *core.DetailedResponse, error)
 	DeleteWorkspace(*schematics.DeleteWorkspaceOptions) (*string, *core.DetailedResponse, error)
+	ListWorkspaces(*schematics.ListWorkspacesOptions) (*schematics.WorkspaceResponseList, *core.DetailedResponse, error)
 	TemplateRepoUpload(*schematics.TemplateRepoUploadOptions) (*schematics.TemplateRepoTarUploadResponse, *core.DetailedResponse, error)
 	ReplaceWorkspaceInputs(*schematics.ReplaceWorkspaceInputsOptions) (*schematics.UserValues, *core.DetailedResponse, error)
 	GetWorkspaceOutputs(*schematics.GetWorkspaceOutputsOptions) ([]schematics.OutputValuesInner, *core.DetailedResponse, error)
@@ -460,6 +516,11 @@ type transitGatewayService interface {
 	ListTransitGateways(*transitgatewayapisv1.ListTransitGatewaysOptions) (*transitgatewayapisv1.TransitGatewayCollection, *core.DetailedResponse, error)
 }
 
+// globalTaggingService for external Global Tagging V1 Service API. Used for mocking.
+type globalTaggingService interface {
+	ListTags(*globaltaggingv1.ListTagsOptions) (*globaltaggingv1.TagList, *core.DetailedResponse, error)
+}
+
 // ReplaceCBRRule replaces a CBR rule using the provided options.
 // updatedExistingRule is the rule to be replaced with the changes already made.
 // eTag is the eTag of the existing rule that is being replaced.
@@ -754,6 +815,20 @@ func NewCloudInfoServiceWithKey(options CloudInfoServiceOptions) (*CloudInfoServ
 		infoSvc.transitGatewayService = tgwClient
 	}
 
+	if options.GlobalTaggingService != nil {
+		infoSvc.globalTaggingService = options.GlobalTaggingService
+	} else {
+		taggingClient, taggingErr := globaltaggingv1.NewGlobalTaggingV1(&globaltaggingv1.GlobalTaggingV1Options{
+			Authenticator: infoSvc.authenticator,
+		})
+		if taggingErr != nil {
+			log.Println("Error creating global tagging client:", taggingErr)
+			return nil, taggingErr
+		}
+
+		infoSvc.globalTaggingService = taggingClient
+	}
+
 	// Schematics is a regional endpoint service, and cross-location API calls do not work.
 	// Here we will set up multiple services for the known geographic locations (US and EU)
 	if options.SchematicsServices != nil {
@@ -778,6 +853,15 @@ func NewCloudInfoServiceWithKey(options CloudInfoServiceOptions) (*CloudInfoServ
 		}
 	}
 
+	infoSvc.regionScanConcurrency = options.RegionScanConcurrency
+
+	infoSvc.regionLeaseMaxHolders = options.RegionLeaseMaxHolders
+	if options.RegionLeases != nil {
+		infoSvc.regionLeases = options.RegionLeases
+	} else if os.Getenv(common.LeaseDirEnvVar) != "" {
+		infoSvc.regionLeases = common.GetDefaultLeaseManager()
+	}
+
 	if options.StackDefinitionCreator != nil {
 		infoSvc.stackDefinitionCreator = options.StackDefinitionCreator
 	} else {
@@ -0,0 +1,413 @@
+package cloudinfo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+	"github.com/IBM/platform-services-go-sdk/resourcemanagerv2"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/go-openapi/strfmt"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// sweepDeleteLastServices are the CRN service names of instances that other instances may depend on, for example for
+// encryption keys. They are deleted after all other instances.
+var sweepDeleteLastServices = []string{"kms", "hs-crypto", "secrets-manager"}
+
+// sweepReclamationRetryConfig returns the retry configuration of reclamation purges, replaced in unit tests
+var sweepReclamationRetryConfig = common.ReclamationPurgeRetryConfig
+
+// SweepOptions selects the stale test resources of an account. A resource is stale if it was created more than
+// MinAge ago and its name starts with one of the NamePrefixes, or it has one of the Tags.
+type SweepOptions struct {
+	NamePrefixes []string      // names of test resources start with one of these prefixes
+	Tags         []string      // user tags of test resources, looked up for every resource that does not match a prefix
+	MinAge       time.Duration // only resources created more than MinAge ago are swept
+	VpcRegions   []string      // regions in which VPCs are swept, no VPCs are swept if empty
+	DryRun       bool          // only find the stale resources, do not delete them
+}
+
+// StaleTestResources are the resources found by FindStaleTestResources
+type StaleTestResources struct {
+	ResourceInstances  []resourcecontrollerv2.ResourceInstance // active instances
+	ReclaimedInstances []resourcecontrollerv2.ResourceInstance // deleted instances waiting for reclamation
+	Vpcs               []RegionVpc
+	ResourceGroups     []resourcemanagerv2.ResourceGroup
+}
+
+// Count returns the number of stale resources
+func (resources *StaleTestResources) Count() int {
+	return len(resources.ResourceInstances) + len(resources.ReclaimedInstances) + len(resources.Vpcs) + len(resources.ResourceGroups)
+}
+
+// SweepResult is the outcome of deleting stale test resources
+type SweepResult struct {
+	Found   *StaleTestResources
+	Deleted []string // CRNs or IDs of the deleted resources and purged reclamations
+	Skipped []string // CRNs of the VPCs that were not deleted because they still contain resources
+	Errors  []error  // errors of resources that could not be deleted, the sweep continues with the next resource
+}
+
+// SweepTestResources finds the stale test resources of the account and, unless DryRun is set, deletes them.
+// See DeleteStaleTestResources for the order of deletion.
+func (infoSvc *CloudInfoService) SweepTestResources(options SweepOptions) (*SweepResult, error) {
+	found, err := infoSvc.FindStaleTestResources(options)
+	if err != nil {
+		return nil, err
+	}
+	if options.DryRun {
+		return &SweepResult{Found: found}, nil
+	}
+	return infoSvc.DeleteStaleTestResources(found), nil
+}
+
+// FindStaleTestResources lists the resource instances, reclaimed instances, resource groups and VPCs of the account
+// that match the options. At least one prefix or tag is required.
+func (infoSvc *CloudInfoService) FindStaleTestResources(options SweepOptions) (*StaleTestResources, error) {
+	if len(options.NamePrefixes) == 0 && len(options.Tags) == 0 {
+		return nil, fmt.Errorf("at least one name prefix or tag is required to find stale test resources")
+	}
+	for _, prefix := range options.NamePrefixes {
+		if strings.TrimSpace(prefix) == "" {
+			return nil, fmt.Errorf("empty name prefix would match every resource")
+		}
+	}
+
+	found := &StaleTestResources{}
+	now := time.Now()
+
+	for _, state := range []string{
+		resourcecontrollerv2.ListResourceInstancesOptionsStateActiveConst,
+		resourcecontrollerv2.ListResourceInstancesOptionsStatePendingReclamationConst,
+	} {
+		listOptions := infoSvc.resourceControllerService.NewListResourceInstancesOptions()
+		listOptions.SetType("resource_instance")
+		listOptions.SetState(state)
+		listOptions.SetLimit(int64(100))
+		instances, err := listResourceInstances(infoSvc, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s resources: %w", state, err)
+		}
+		for _, instance := range instances {
+			if instance.State == nil || *instance.State != state {
+				continue
+			}
+			if !infoSvc.isStaleTestResource(options, instance.Name, instance.CRN, instance.CreatedAt, now) {
+				continue
+			}
+			if state == resourcecontrollerv2.ListResourceInstancesOptionsStateActiveConst {
+				found.ResourceInstances = append(found.ResourceInstances, instance)
+			} else {
+				found.ReclaimedInstances = append(found.ReclaimedInstances, instance)
+			}
+		}
+	}
+
+	if len(options.VpcRegions) > 0 {
+		regionVpcs, err := infoSvc.ListVpcsByRegion(options.VpcRegions)
+		if err != nil {
+			return nil, err
+		}
+		for _, regionVpc := range regionVpcs {
+			if infoSvc.isStaleTestResource(options, regionVpc.VPC.Name, regionVpc.VPC.CRN, regionVpc.VPC.CreatedAt, now) {
+				found.Vpcs = append(found.Vpcs, regionVpc)
+			}
+		}
+	}
+
+	resourceGroups, _, err := infoSvc.resourceManagerService.ListResourceGroups(infoSvc.resourceManagerService.NewListResourceGroupsOptions())
+	if err != nil {
+		return nil, fmt.Errorf("error listing resource groups: %w", err)
+	}
+	if resourceGroups != nil {
+		for _, group := range resourceGroups.Resources {
+			if group.Default != nil && *group.Default {
+				continue
+			}
+			if infoSvc.isStaleTestResource(options, group.Name, group.CRN, group.CreatedAt, now) {
+				found.ResourceGroups = append(found.ResourceGroups, group)
+			}
+		}
+	}
+
+	log.Printf("Found %d stale resource instances, %d reclaimed instances, %d VPCs and %d resource groups",
+		len(found.ResourceInstances), len(found.ReclaimedInstances), len(found.Vpcs), len(found.ResourceGroups))
+	return found, nil
+}
+
+// DeleteStaleTestResources deletes the resources in an order that respects their dependencies: resource instances
+// first, with key management instances last as others may use their keys, then VPCs, then the reclamations of the
+// deleted and already reclaimed instances are purged, and finally the resource groups, which must be empty.
+// Errors are collected in the result and do not stop the sweep.
+func (infoSvc *CloudInfoService) DeleteStaleTestResources(resources *StaleTestResources) *SweepResult {
+	result := &SweepResult{Found: resources}
+	fail := func(err error) {
+		log.Println("ERROR:", err)
+		result.Errors = append(result.Errors, err)
+	}
+
+	// 1. resource instances, including their keys and aliases
+	instances := append([]resourcecontrollerv2.ResourceInstance{}, resources.ResourceInstances...)
+	sort.SliceStable(instances, func(i, j int) bool {
+		return !isSweepDeleteLastInstance(instances[i]) && isSweepDeleteLastInstance(instances[j])
+	})
+	var reclaimCRNs []string
+	for _, instance := range instances {
+		id := core.StringNilMapper(instance.GUID)
+		if id == "" {
+			id = core.StringNilMapper(instance.CRN)
+		}
+		deleteOptions := infoSvc.resourceControllerService.NewDeleteResourceInstanceOptions(id)
+		deleteOptions.SetRecursive(true)
+		if _, err := infoSvc.resourceControllerService.DeleteResourceInstance(deleteOptions); err != nil {
+			fail(fmt.Errorf("error deleting resource instance %s (%s): %w", core.StringNilMapper(instance.Name), id, err))
+			continue
+		}
+		log.Printf("Deleted resource instance %s (%s)", core.StringNilMapper(instance.Name), id)
+		result.Deleted = append(result.Deleted, core.StringNilMapper(instance.CRN))
+		reclaimCRNs = append(reclaimCRNs, core.StringNilMapper(instance.CRN))
+	}
+
+	// 2. VPCs, which are skipped while they still contain subnets or other resources
+	if len(resources.Vpcs) > 0 {
+		infoSvc.deleteVpcs(resources.Vpcs, result, fail)
+	}
+
+	// 3. reclamations, so that names and quotas are released right away
+	for _, instance := range resources.ReclaimedInstances {
+		reclaimCRNs = append(reclaimCRNs, core.StringNilMapper(instance.CRN))
+	}
+	// the reclamation of an instance only appears some time after its delete, the purge is retried until it exists
+	summary := common.PurgeReclamations(infoSvc, reclaimCRNs, sweepReclamationRetryConfig())
+	for _, crn := range summary.Purged {
+		result.Deleted = append(result.Deleted, "reclamation:"+crn)
+	}
+	for _, crn := range summary.NotFound {
+		fail(fmt.Errorf("error purging reclamation of %s: %w", crn, common.ErrReclamationNotFound))
+	}
+	for _, crn := range reclaimCRNs {
+		if err, failed := summary.Failed[crn]; failed {
+			fail(fmt.Errorf("error purging reclamation of %s: %w", crn, err))
+		}
+	}
+
+	// 4. resource groups
+	for _, group := range resources.ResourceGroups {
+		groupID := core.StringNilMapper(group.ID)
+		response, err := infoSvc.DeleteResourceGroup(groupID)
+		if err == nil && response != nil && (response.StatusCode < 200 || response.StatusCode >= 300) {
+			err = fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		}
+		if err != nil {
+			fail(fmt.Errorf("error deleting resource group %s (%s): %w", core.StringNilMapper(group.Name), groupID, err))
+			continue
+		}
+		log.Printf("Deleted resource group %s (%s)", core.StringNilMapper(group.Name), groupID)
+		result.Deleted = append(result.Deleted, groupID)
+	}
+
+	log.Printf("Sweep deleted %d resources, skipped %d VPCs, %d errors", len(result.Deleted), len(result.Skipped), len(result.Errors))
+	return result
+}
+
+// deleteVpcs deletes the VPCs, using the VPC client of the region of each VPC. VPCs that still contain subnets,
+// instances or gateways are skipped, as their delete would fail, and reported in the result.
+func (infoSvc *CloudInfoService) deleteVpcs(vpcs []RegionVpc, result *SweepResult, fail func(error)) {
+	for _, regionVpc := range vpcs {
+		regionVpcService, err := infoSvc.getRegionVpcService(regionVpc.Region)
+		if err != nil {
+			fail(fmt.Errorf("error getting VPC client for region %s: %w", regionVpc.Region, err))
+			continue
+		}
+		vpcID := core.StringNilMapper(regionVpc.VPC.ID)
+		contents, err := listVpcContents(regionVpcService, vpcID)
+		if err != nil {
+			fail(fmt.Errorf("error listing the resources of VPC %s (%s) in region %s: %w", core.StringNilMapper(regionVpc.VPC.Name), vpcID, regionVpc.Region, err))
+			continue
+		}
+		if len(contents) > 0 {
+			log.Printf("Skipping VPC %s (%s) in region %s, it still contains %s", core.StringNilMapper(regionVpc.VPC.Name), vpcID, regionVpc.Region, strings.Join(contents, ", "))
+			result.Skipped = append(result.Skipped, core.StringNilMapper(regionVpc.VPC.CRN))
+			continue
+		}
+		if _, err := regionVpcService.DeleteVPC(&vpcv1.DeleteVPCOptions{ID: core.StringPtr(vpcID)}); err != nil {
+			fail(fmt.Errorf("error deleting VPC %s (%s) in region %s: %w", core.StringNilMapper(regionVpc.VPC.Name), vpcID, regionVpc.Region, err))
+			continue
+		}
+		log.Printf("Deleted VPC %s (%s) in region %s", core.StringNilMapper(regionVpc.VPC.Name), vpcID, regionVpc.Region)
+		result.Deleted = append(result.Deleted, core.StringNilMapper(regionVpc.VPC.CRN))
+	}
+}
+
+// listVpcContents returns the kinds of resources the VPC still contains, empty if the VPC can be deleted
+func listVpcContents(regionVpcService vpcService, vpcID string) ([]string, error) {
+	var contents []string
+
+	subnetOptions := &vpcv1.ListSubnetsOptions{}
+	subnetOptions.SetVPCID(vpcID)
+	subnetOptions.SetLimit(1)
+	subnetCol, _, err := regionVpcService.ListSubnets(subnetOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(subnetCol.Subnets) > 0 {
+		contents = append(contents, "subnets")
+	}
+
+	instanceOptions := &vpcv1.ListInstancesOptions{}
+	instanceOptions.SetVPCID(vpcID)
+	instanceOptions.SetLimit(1)
+	instanceCol, _, err := regionVpcService.ListInstances(instanceOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(instanceCol.Instances) > 0 {
+		contents = append(contents, "instances")
+	}
+
+	// gateways can not be listed by VPC, they are matched by the ID of their VPC
+	hasPublicGateways, err := hasVpcPublicGateways(regionVpcService, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	if hasPublicGateways {
+		contents = append(contents, "public gateways")
+	}
+	hasEndpointGateways, err := hasVpcEndpointGateways(regionVpcService, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	if hasEndpointGateways {
+		contents = append(contents, "endpoint gateways")
+	}
+	return contents, nil
+}
+
+func hasVpcPublicGateways(regionVpcService vpcService, vpcID string) (bool, error) {
+	listOptions := &vpcv1.ListPublicGatewaysOptions{}
+	listOptions.SetLimit(100)
+	for page := 0; page < maxZoneResourcePages; page++ {
+		gatewayCol, _, err := regionVpcService.ListPublicGateways(listOptions)
+		if err != nil {
+			return false, err
+		}
+		for _, gateway := range gatewayCol.PublicGateways {
+			if gateway.VPC != nil && core.StringNilMapper(gateway.VPC.ID) == vpcID {
+				return true, nil
+			}
+		}
+		nextStart, err := gatewayCol.GetNextStart()
+		if err != nil {
+			return false, err
+		}
+		if nextStart == nil {
+			return false, nil
+		}
+		listOptions.SetStart(*nextStart)
+	}
+	return false, errors.New("too many pages of public gateways")
+}
+
+func hasVpcEndpointGateways(regionVpcService vpcService, vpcID string) (bool, error) {
+	listOptions := &vpcv1.ListEndpointGatewaysOptions{}
+	listOptions.SetLimit(100)
+	for page := 0; page < maxZoneResourcePages; page++ {
+		gatewayCol, _, err := regionVpcService.ListEndpointGateways(listOptions)
+		if err != nil {
+			return false, err
+		}
+		for _, gateway := range gatewayCol.EndpointGateways {
+			if gateway.VPC != nil && core.StringNilMapper(gateway.VPC.ID) == vpcID {
+				return true, nil
+			}
+		}
+		nextStart, err := gatewayCol.GetNextStart()
+		if err != nil {
+			return false, err
+		}
+		if nextStart == nil {
+			return false, nil
+		}
+		listOptions.SetStart(*nextStart)
+	}
+	return false, errors.New("too many pages of endpoint gateways")
+}
+
+// isStaleTestResource returns true if the resource is older than MinAge and matches a prefix or tag of the options.
+// Resources without a creation time are never stale, and resources whose tags can not be looked up are skipped.
+func (infoSvc *CloudInfoService) isStaleTestResource(options SweepOptions, name *string, crn *string, createdAt *strfmt.DateTime, now time.Time) bool {
+	if createdAt == nil || now.Sub(time.Time(*createdAt)) < options.MinAge {
+		return false
+	}
+	for _, prefix := range options.NamePrefixes {
+		if name != nil && strings.HasPrefix(*name, prefix) {
+			return true
+		}
+	}
+	if len(options.Tags) == 0 || crn == nil {
+		return false
+	}
+	tags, err := infoSvc.GetResourceTags(*crn)
+	if err != nil {
+		log.Printf("WARNING: skipping %s, its tags could not be looked up: %s", core.StringNilMapper(name), err)
+		return false
+	}
+	for _, tag := range tags {
+		for _, wanted := range options.Tags {
+			if strings.EqualFold(tag, wanted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSweepDeleteLastInstance returns true for instances of services that other instances may depend on
+func isSweepDeleteLastInstance(instance resourcecontrollerv2.ResourceInstance) bool {
+	// crn:v1:bluemix:public:<service-name>:<location>:...
+	crnParts := strings.Split(core.StringNilMapper(instance.CRN), ":")
+	if len(crnParts) < 5 {
+		return false
+	}
+	for _, serviceName := range sweepDeleteLastServices {
+		if crnParts[4] == serviceName {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintStaleTestResources prints the stale resources to stdout, using PrintResources for resource instances
+func PrintStaleTestResources(resources *StaleTestResources) {
+	fmt.Printf("Stale resource instances: %d\n", len(resources.ResourceInstances))
+	PrintResources(resources.ResourceInstances)
+	fmt.Printf("Reclaimed resource instances: %d\n", len(resources.ReclaimedInstances))
+	PrintResources(resources.ReclaimedInstances)
+
+	fmt.Printf("Stale VPCs: %d\n", len(resources.Vpcs))
+	for _, regionVpc := range resources.Vpcs {
+		fmt.Printf("Name: %s\nRegion: %s\nID: %s\nCRN: %s\nCreatedAt: %s\n--------------------\n",
+			core.StringNilMapper(regionVpc.VPC.Name), regionVpc.Region, core.StringNilMapper(regionVpc.VPC.ID),
+			core.StringNilMapper(regionVpc.VPC.CRN), formatSweepTime(regionVpc.VPC.CreatedAt))
+	}
+
+	fmt.Printf("Stale resource groups: %d\n", len(resources.ResourceGroups))
+	for _, group := range resources.ResourceGroups {
+		fmt.Printf("Name: %s\nID: %s\nCreatedAt: %s\n--------------------\n",
+			core.StringNilMapper(group.Name), core.StringNilMapper(group.ID), formatSweepTime(group.CreatedAt))
+	}
+}
+
+// formatSweepTime formats an optional creation time
+func formatSweepTime(createdAt *strfmt.DateTime) string {
+	if createdAt == nil {
+		return "N/A"
+	}
+	return time.Time(*createdAt).Format(time.RFC3339)
+}
@@ -0,0 +1,208 @@
+package cloudinfo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/platform-services-go-sdk/resourcecontrollerv2"
+	"github.com/IBM/platform-services-go-sdk/resourcemanagerv2"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+func sweepTestInstance(service string, guid string, name string, state string, age time.Duration) resourcecontrollerv2.ResourceInstance {
+	createdAt := strfmt.DateTime(time.Now().Add(-age))
+	return resourcecontrollerv2.ResourceInstance{
+		GUID:      core.StringPtr(guid),
+		CRN:       core.StringPtr("crn:v1:bluemix:public:" + service + ":us-south:a/account:" + guid + "::"),
+		Name:      core.StringPtr(name),
+		State:     core.StringPtr(state),
+		CreatedAt: &createdAt,
+	}
+}
+
+// setSweepTestRetryConfig purges reclamations without delays and with a single retry
+func setSweepTestRetryConfig(t *testing.T) {
+	t.Setenv("SKIP_RETRY_DELAYS", "true")
+	original := sweepReclamationRetryConfig
+	sweepReclamationRetryConfig = func() common.RetryConfig {
+		config := common.ReclamationPurgeRetryConfig()
+		config.MaxRetries = 1
+		return config
+	}
+	t.Cleanup(func() { sweepReclamationRetryConfig = original })
+}
+
+func newSweepTestService() (*CloudInfoService, *resourceControllerServiceMock, *vpcServiceMock, *resourceManagerServiceMock) {
+	old := strfmt.DateTime(time.Now().Add(-48 * time.Hour))
+	recent := strfmt.DateTime(time.Now().Add(-time.Hour))
+	rowCount := int64(5)
+
+	resourceController := &resourceControllerServiceMock{
+		mockResourceList: &resourcecontrollerv2.ResourceInstancesList{
+			RowsCount: &rowCount,
+			Resources: []resourcecontrollerv2.ResourceInstance{
+				sweepTestInstance("kms", "kms-1", "ci-test-kms", "active", 48*time.Hour),
+				sweepTestInstance("cloud-object-storage", "cos-1", "ci-test-cos", "active", 48*time.Hour),
+				sweepTestInstance("cloud-object-storage", "cos-2", "ci-test-recent", "active", time.Hour),
+				sweepTestInstance("databases-for-postgresql", "pg-1", "ci-test-pg", "pending_reclamation", 48*time.Hour),
+				sweepTestInstance("cloud-object-storage", "cos-3", "prod-cos", "active", 48*time.Hour),
+			},
+		},
+	}
+	vpcService := &vpcServiceMock{
+		mockVpcs: map[string][]vpcv1.VPC{
+			"us-south": {
+				{ID: core.StringPtr("vpc-1"), CRN: core.StringPtr("crn:vpc-1"), Name: core.StringPtr("ci-test-vpc"), CreatedAt: &old},
+				{ID: core.StringPtr("vpc-2"), CRN: core.StringPtr("crn:vpc-2"), Name: core.StringPtr("ci-test-new-vpc"), CreatedAt: &recent},
+				{ID: core.StringPtr("vpc-3"), CRN: core.StringPtr("crn:vpc-3"), Name: core.StringPtr("tagged-vpc"), CreatedAt: &old},
+			},
+		},
+	}
+	resourceManager := &resourceManagerServiceMock{
+		mockResourceGroupList: &resourcemanagerv2.ResourceGroupList{
+			Resources: []resourcemanagerv2.ResourceGroup{
+				{ID: core.StringPtr("rg-1"), Name: core.StringPtr("ci-test-rg"), CreatedAt: &old},
+				{ID: core.StringPtr("rg-default"), Name: core.StringPtr("ci-test-default"), CreatedAt: &old, Default: core.BoolPtr(true)},
+			},
+		},
+	}
+	infoSvc := &CloudInfoService{
+		resourceControllerService: resourceController,
+		vpcService:                vpcService,
+		newVpcService:             vpcService.newRegionalMock,
+		resourceManagerService:    resourceManager,
+		globalTaggingService: &globalTaggingServiceMock{
+			mockTags: map[string][]string{"crn:vpc-3": {"owner:ci"}},
+		},
+	}
+	return infoSvc, resourceController, vpcService, resourceManager
+}
+
+func TestFindStaleTestResources(t *testing.T) {
+	infoSvc, _, _, _ := newSweepTestService()
+
+	t.Run("RequiresPrefixOrTag", func(t *testing.T) {
+		_, err := infoSvc.FindStaleTestResources(SweepOptions{})
+		assert.Error(t, err)
+		_, err = infoSvc.FindStaleTestResources(SweepOptions{NamePrefixes: []string{" "}})
+		assert.Error(t, err)
+	})
+
+	t.Run("PrefixTagAndAge", func(t *testing.T) {
+		found, err := infoSvc.FindStaleTestResources(SweepOptions{
+			NamePrefixes: []string{"ci-test"},
+			Tags:         []string{"OWNER:CI"},
+			MinAge:       24 * time.Hour,
+			VpcRegions:   []string{"us-south"},
+		})
+		require.NoError(t, err)
+		require.Len(t, found.ResourceInstances, 2)
+		assert.Equal(t, "kms-1", *found.ResourceInstances[0].GUID)
+		assert.Equal(t, "cos-1", *found.ResourceInstances[1].GUID)
+		require.Len(t, found.ReclaimedInstances, 1)
+		assert.Equal(t, "pg-1", *found.ReclaimedInstances[0].GUID)
+		require.Len(t, found.Vpcs, 2)
+		assert.Equal(t, "vpc-1", *found.Vpcs[0].VPC.ID)
+		assert.Equal(t, "vpc-3", *found.Vpcs[1].VPC.ID, "VPC matched by tag")
+		require.Len(t, found.ResourceGroups, 1, "default resource group is never swept")
+		assert.Equal(t, 6, found.Count())
+	})
+
+	t.Run("TagLookupErrorSkipsResource", func(t *testing.T) {
+		infoSvc, _, _, _ := newSweepTestService()
+		infoSvc.globalTaggingService = &globalTaggingServiceMock{mockError: errors.New("mock ListTags error")}
+		found, err := infoSvc.FindStaleTestResources(SweepOptions{
+			NamePrefixes: []string{"ci-test"},
+			Tags:         []string{"owner:ci"},
+			MinAge:       24 * time.Hour,
+			VpcRegions:   []string{"us-south"},
+		})
+		require.NoError(t, err)
+		require.Len(t, found.Vpcs, 1, "VPC whose tags could not be looked up is skipped")
+		assert.Equal(t, "vpc-1", *found.Vpcs[0].VPC.ID)
+		assert.Equal(t, 5, found.Count())
+	})
+}
+
+func TestSweepTestResources(t *testing.T) {
+	setSweepTestRetryConfig(t)
+	options := SweepOptions{NamePrefixes: []string{"ci-test"}, MinAge: 24 * time.Hour, VpcRegions: []string{"us-south"}}
+
+	t.Run("DryRun", func(t *testing.T) {
+		infoSvc, resourceController, vpcService, resourceManager := newSweepTestService()
+		dryRunOptions := options
+		dryRunOptions.DryRun = true
+		result, err := infoSvc.SweepTestResources(dryRunOptions)
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.Found.Count())
+		assert.Empty(t, result.Deleted)
+		assert.Empty(t, resourceController.deletedInstances)
+		assert.Empty(t, resourceController.reclaimedIDs)
+		assert.Empty(t, vpcService.deletedVpcs)
+		assert.Empty(t, resourceManager.deletedResourceGroups)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		infoSvc, resourceController, vpcService, resourceManager := newSweepTestService()
+		result, err := infoSvc.SweepTestResources(options)
+		require.NoError(t, err)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, []string{"cos-1", "kms-1"}, resourceController.deletedInstances, "key management instances are deleted last")
+		assert.Equal(t, []string{"vpc-1"}, vpcService.deletedVpcs)
+		assert.Len(t, resourceController.reclaimedIDs, 3, "reclamations of deleted and reclaimed instances are purged")
+		assert.Equal(t, []string{"rg-1"}, resourceManager.deletedResourceGroups)
+		assert.Len(t, result.Deleted, 7)
+		assert.Empty(t, vpcService.mockRegionUrl, "the url of the shared VPC client is not changed")
+	})
+
+	t.Run("ContinuesOnError", func(t *testing.T) {
+		infoSvc, resourceController, _, resourceManager := newSweepTestService()
+		found := &StaleTestResources{
+			ResourceInstances: []resourcecontrollerv2.ResourceInstance{
+				sweepTestInstance("cloud-object-storage", "ERROR", "ci-test-broken", "active", 48*time.Hour),
+			},
+			ResourceGroups: []resourcemanagerv2.ResourceGroup{{ID: core.StringPtr("rg-1"), Name: core.StringPtr("ci-test-rg")}},
+		}
+		result := infoSvc.DeleteStaleTestResources(found)
+		assert.Len(t, result.Errors, 1)
+		assert.Empty(t, resourceController.deletedInstances)
+		assert.Equal(t, []string{"rg-1"}, resourceManager.deletedResourceGroups)
+	})
+
+	t.Run("SkipsNonEmptyVpcs", func(t *testing.T) {
+		infoSvc, _, vpcService, _ := newSweepTestService()
+		vpcService.mockSubnets = []vpcv1.Subnet{{ID: core.StringPtr("subnet-1"), VPC: &vpcv1.VPCReference{ID: core.StringPtr("vpc-1")}}}
+		vpcService.mockEndpointGateways = []vpcv1.EndpointGateway{{ID: core.StringPtr("vpe-1"), VPC: &vpcv1.VPCReference{ID: core.StringPtr("vpc-2")}}}
+		found := &StaleTestResources{
+			Vpcs: []RegionVpc{
+				{Region: "us-south", VPC: vpcService.mockVpcs["us-south"][0]},
+				{Region: "us-south", VPC: vpcService.mockVpcs["us-south"][1]},
+				{Region: "us-south", VPC: vpcService.mockVpcs["us-south"][2]},
+			},
+		}
+		result := infoSvc.DeleteStaleTestResources(found)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, []string{"vpc-3"}, vpcService.deletedVpcs)
+		assert.Equal(t, []string{"crn:vpc-1", "crn:vpc-2"}, result.Skipped)
+	})
+
+	t.Run("ReclamationNotFound", func(t *testing.T) {
+		infoSvc, resourceController, _, _ := newSweepTestService()
+		resourceController.mockReclamationList = &resourcecontrollerv2.ReclamationsList{}
+		found := &StaleTestResources{
+			ReclaimedInstances: []resourcecontrollerv2.ResourceInstance{
+				sweepTestInstance("databases-for-postgresql", "pg-1", "ci-test-pg", "pending_reclamation", 48*time.Hour),
+			},
+		}
+		result := infoSvc.DeleteStaleTestResources(found)
+		require.Len(t, result.Errors, 1)
+		assert.ErrorIs(t, result.Errors[0], common.ErrReclamationNotFound)
+		assert.Empty(t, resourceController.reclaimedIDs)
+	})
+}
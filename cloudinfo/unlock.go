@@ -0,0 +1,144 @@
+package cloudinfo
+
+import (
+	"bytes"
+	"crypto/md5" // #nosec G501 -- Content-MD5 header required by the COS API, not used for security
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kmsDualAuthDeletePolicy is the body of the Key Protect policy update that turns off dual authorization for the
+// deletion of a key
+const kmsDualAuthDeletePolicy = `{"metadata":{"collectionType":"application/vnd.ibm.kms.policy+json","collectionTotal":1},` +
+	`"resources":[{"type":"application/vnd.ibm.kms.policy+json","dualAuthDelete":{"enabled":false}}]}`
+
+// cosObjectLockWithoutRetention is the Object Lock configuration of a bucket without a default retention
+const cosObjectLockWithoutRetention = `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`
+
+// DisableKmsKeyDualAuthDelete turns off the dual authorization policy of a Key Protect key, so that the key can be
+// deleted by a single user. keyCRN is the CRN of the key, for example the `crn` attribute of an `ibm_kms_key`.
+func (infoSvc *CloudInfoService) DisableKmsKeyDualAuthDelete(keyCRN string) error {
+	crnParts := strings.Split(keyCRN, ":")
+	if len(crnParts) < 10 || crnParts[8] != "key" || crnParts[9] == "" {
+		return fmt.Errorf("invalid key crn %s, the key id is not present", keyCRN)
+	}
+	if crnParts[4] != "kms" {
+		return fmt.Errorf("key %s is not a Key Protect key, service %s is not supported", keyCRN, crnParts[4])
+	}
+	region, instanceID, keyID := crnParts[5], crnParts[7], crnParts[9]
+
+	baseURL := infoSvc.kmsBaseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s.kms.cloud.ibm.com", url.PathEscape(region))
+	}
+	reqURL := fmt.Sprintf("%s/api/v2/keys/%s/policies?policy=dualAuthDelete", baseURL, url.PathEscape(keyID))
+	headers := map[string]string{
+		"Bluemix-Instance": instanceID,
+		"Content-Type":     "application/vnd.ibm.kms.policy+json",
+	}
+	responseBody, err := infoSvc.sendUnlockRequest(http.MethodPut, reqURL, headers, []byte(kmsDualAuthDeletePolicy))
+	var requestErr *unlockRequestError
+	if errors.As(err, &requestErr) && (requestErr.StatusCode == http.StatusBadRequest || requestErr.StatusCode == http.StatusForbidden ||
+		requestErr.StatusCode == http.StatusConflict) {
+		return fmt.Errorf("disabling dual authorization delete of key %s was refused by Key Protect, the deletion must be authorized by a second user: %w", keyID, err)
+	}
+	if err != nil {
+		return fmt.Errorf("error disabling dual authorization delete of key %s: %w", keyID, err)
+	}
+
+	// the response lists the policies of the key after the update
+	var policies struct {
+		Resources []struct {
+			DualAuthDelete *struct {
+				Enabled *bool `json:"enabled"`
+			} `json:"dualAuthDelete"`
+		} `json:"resources"`
+	}
+	if len(responseBody) == 0 || json.Unmarshal(responseBody, &policies) != nil {
+		return nil
+	}
+	for _, policy := range policies.Resources {
+		if policy.DualAuthDelete != nil && policy.DualAuthDelete.Enabled != nil && *policy.DualAuthDelete.Enabled {
+			return fmt.Errorf("dual authorization delete of key %s is still enabled after the policy update", keyID)
+		}
+	}
+	return nil
+}
+
+// RemoveCosBucketDefaultRetention removes the default retention of the Object Lock configuration of an Object Storage
+// bucket, so that objects written to the bucket are no longer locked. Objects that are already locked keep their
+// retention. bucketURL is the S3 endpoint of the bucket followed by its name, for example
+// `https://s3.us-south.cloud-object-storage.appdomain.cloud/my-bucket`.
+func (infoSvc *CloudInfoService) RemoveCosBucketDefaultRetention(bucketURL string) error {
+	if !strings.Contains(bucketURL, "://") {
+		bucketURL = "https://" + bucketURL
+	}
+	parsedURL, err := url.Parse(bucketURL)
+	if err != nil || parsedURL.Host == "" || strings.Trim(parsedURL.Path, "/") == "" {
+		return fmt.Errorf("invalid bucket url %s, expected the S3 endpoint followed by the bucket name", bucketURL)
+	}
+	parsedURL.RawQuery = "object-lock"
+
+	body := []byte(cosObjectLockWithoutRetention)
+	checksum := md5.Sum(body) // #nosec G401 -- Content-MD5 header required by the COS API
+	headers := map[string]string{
+		"Content-Type": "application/xml",
+		"Content-MD5":  base64.StdEncoding.EncodeToString(checksum[:]),
+	}
+	if _, err := infoSvc.sendUnlockRequest(http.MethodPut, parsedURL.String(), headers, body); err != nil {
+		return fmt.Errorf("error removing default retention of bucket %s: %w", strings.Trim(parsedURL.Path, "/"), err)
+	}
+	return nil
+}
+
+// unlockRequestError is returned by sendUnlockRequest when the response status is not successful
+type unlockRequestError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err *unlockRequestError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", err.StatusCode, err.Body)
+}
+
+// sendUnlockRequest sends an authenticated request to a service without an SDK and returns the body of the response,
+// or an unlockRequestError if the response status is not successful
+func (infoSvc *CloudInfoService) sendUnlockRequest(method string, reqURL string, headers map[string]string, body []byte) ([]byte, error) {
+	token, err := infoSvc.authenticator.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("error getting auth token: %w", err)
+	}
+
+	req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	for name, value := range headers {
+		req.Header.Add(name, value)
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	resp, err := client.Do(req) // #nosec G704 -- URL is built from the CRN or output of a test resource
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	responseBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &unlockRequestError{StatusCode: resp.StatusCode, Body: string(responseBody)}
+	}
+	return responseBody, nil
+}
@@ -0,0 +1,80 @@
+package cloudinfo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisableKmsKeyDualAuthDelete(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		switch r.URL.Path {
+		case "/api/v2/keys/missing/policies":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/v2/keys/refused/policies":
+			w.WriteHeader(http.StatusConflict)
+		case "/api/v2/keys/unchanged/policies":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"resources":[{"type":"application/vnd.ibm.kms.policy+json","dualAuthDelete":{"enabled":true}}]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"resources":[{"type":"application/vnd.ibm.kms.policy+json","dualAuthDelete":{"enabled":false}}]}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	infoSvc := CloudInfoService{
+		authenticator: &MockAuthenticator{Token: "mock-token"},
+		kmsBaseURL:    mockServer.URL,
+	}
+
+	require.NoError(t, infoSvc.DisableKmsKeyDualAuthDelete("crn:v1:bluemix:public:kms:us-south:a/account:instance-1:key:key-1"))
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, "/api/v2/keys/key-1/policies", requests[0].URL.Path)
+	assert.Equal(t, "dualAuthDelete", requests[0].URL.Query().Get("policy"))
+	assert.Equal(t, "instance-1", requests[0].Header.Get("Bluemix-Instance"))
+	assert.Equal(t, "Bearer mock-token", requests[0].Header.Get("Authorization"))
+	assert.Contains(t, bodies[0], `"dualAuthDelete":{"enabled":false}`)
+
+	assert.Error(t, infoSvc.DisableKmsKeyDualAuthDelete("crn:v1:bluemix:public:kms:us-south:a/account:instance-1:key:missing"))
+	assert.ErrorContains(t, infoSvc.DisableKmsKeyDualAuthDelete("crn:v1:bluemix:public:kms:us-south:a/account:instance-1:key:refused"), "refused by Key Protect")
+	assert.ErrorContains(t, infoSvc.DisableKmsKeyDualAuthDelete("crn:v1:bluemix:public:kms:us-south:a/account:instance-1:key:unchanged"), "still enabled")
+	assert.Error(t, infoSvc.DisableKmsKeyDualAuthDelete("crn:v1:bluemix:public:kms:us-south:a/account:instance-1::"), "crn without key id")
+	assert.Error(t, infoSvc.DisableKmsKeyDualAuthDelete("crn:v1:bluemix:public:hs-crypto:us-south:a/account:instance-1:key:key-1"), "only Key Protect is supported")
+	assert.Len(t, requests, 4)
+}
+
+func TestRemoveCosBucketDefaultRetention(t *testing.T) {
+	var request *http.Request
+	var body string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		request = r
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	infoSvc := CloudInfoService{authenticator: &MockAuthenticator{Token: "mock-token"}}
+
+	require.NoError(t, infoSvc.RemoveCosBucketDefaultRetention(mockServer.URL+"/bucket-1"))
+	require.NotNil(t, request)
+	assert.Equal(t, http.MethodPut, request.Method)
+	assert.Equal(t, "/bucket-1", request.URL.Path)
+	assert.Equal(t, "object-lock", request.URL.RawQuery)
+	assert.NotEmpty(t, request.Header.Get("Content-MD5"))
+	assert.Equal(t, cosObjectLockWithoutRetention, body)
+
+	assert.Error(t, infoSvc.RemoveCosBucketDefaultRetention("bucket-1"), "url without endpoint")
+	assert.Error(t, infoSvc.RemoveCosBucketDefaultRetention(mockServer.URL), "url without bucket")
+}
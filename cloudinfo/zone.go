@@ -0,0 +1,242 @@
+package cloudinfo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// Names of the zone resources counted by GetLeastVpcTestZoneO
+const (
+	ZoneResourceInstances        = "instances"
+	ZoneResourceSubnets          = "subnets"
+	ZoneResourceBareMetalServers = "bare_metal_servers"
+)
+
+// maximum number of pages listed for each zone resource
+const maxZoneResourcePages = 100
+
+type GetTestZoneOptions struct {
+	// Resources are the zone resources counted, see ZoneResourceInstances and the other names.
+	// Default is all zone resources.
+	Resources []string
+	// Zones restricts the selection to these zones of the region, for example zones that offer a machine profile.
+	// Default is all available zones of the region.
+	Zones []string
+}
+
+// ZoneCount is the number of each zone resource in a zone
+type ZoneCount struct {
+	Zone   string
+	Counts map[string]int
+	Total  int
+}
+
+// GetLeastVpcTestZone is a method for receiver CloudInfoService that will determine the zone of a VPC region
+// that currently contains the least amount of instances, subnets and bare metal servers.
+// Returns a string representing an IBM Cloud zone name, and error.
+func (infoSvc *CloudInfoService) GetLeastVpcTestZone(region string) (string, error) {
+	return infoSvc.GetLeastVpcTestZoneO(region, GetTestZoneOptions{})
+}
+
+// GetLeastVpcTestZoneO is a method for receiver CloudInfoService that will determine the zone of a VPC region
+// that currently contains the least amount of the zone resources of the options.
+// Zones with the same count are chosen in name order, so that `<region>-1` is used if the zones are empty.
+// Returns a string representing an IBM Cloud zone name, and error.
+func (infoSvc *CloudInfoService) GetLeastVpcTestZoneO(region string, options GetTestZoneOptions) (string, error) {
+	zoneCounts, err := infoSvc.GetVpcZoneCountsO(region, options)
+	if err != nil {
+		return "", err
+	}
+	if len(zoneCounts) == 0 {
+		return "", fmt.Errorf("no zone could be determined for region %s", region)
+	}
+
+	bestZone := zoneCounts[0]
+	for _, zoneCount := range zoneCounts[1:] {
+		if zoneCount.Total < bestZone.Total {
+			bestZone = zoneCount
+		}
+	}
+
+	log.Printf("Selected VPC zone %s with %d resources %v", bestZone.Zone, bestZone.Total, bestZone.Counts)
+	return bestZone.Zone, nil
+}
+
+// GetVpcZoneCounts is a method for receiver CloudInfoService that will count the instances, subnets and bare metal
+// servers in each available zone of a VPC region.
+// Returns an array of ZoneCount ordered by zone name, and error.
+func (infoSvc *CloudInfoService) GetVpcZoneCounts(region string) ([]ZoneCount, error) {
+	return infoSvc.GetVpcZoneCountsO(region, GetTestZoneOptions{})
+}
+
+// GetVpcZoneCountsO is a method for receiver CloudInfoService that will count the zone resources of the options
+// in each available zone of a VPC region.
+// Returns an array of ZoneCount ordered by zone name, and error.
+func (infoSvc *CloudInfoService) GetVpcZoneCountsO(region string, options GetTestZoneOptions) ([]ZoneCount, error) {
+	resources := options.Resources
+	if len(resources) == 0 {
+		resources = []string{ZoneResourceInstances, ZoneResourceSubnets, ZoneResourceBareMetalServers}
+	}
+
+	regionVpcService, err := infoSvc.getRegionVpcService(region)
+	if err != nil {
+		return nil, err
+	}
+
+	zones, err := listAvailableZones(regionVpcService, region)
+	if err != nil {
+		return nil, err
+	}
+	zoneCounts := make([]ZoneCount, 0, len(zones))
+	zoneIndexes := make(map[string]int)
+	for _, zone := range zones {
+		if len(options.Zones) > 0 && !common.StrArrayContains(options.Zones, zone) {
+			continue
+		}
+		zoneIndexes[zone] = len(zoneCounts)
+		zoneCounts = append(zoneCounts, ZoneCount{Zone: zone, Counts: make(map[string]int)})
+	}
+
+	for _, resource := range resources {
+		resourceZones, err := listZoneResources(regionVpcService, resource)
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s of region %s: %w", resource, region, err)
+		}
+		for _, zone := range resourceZones {
+			if index, found := zoneIndexes[zone]; found {
+				zoneCounts[index].Counts[resource]++
+				zoneCounts[index].Total++
+			}
+		}
+	}
+
+	return zoneCounts, nil
+}
+
+// getRegionVpcService returns the VPC client for the endpoint of a region
+func (infoSvc *CloudInfoService) getRegionVpcService(region string) (vpcService, error) {
+	regionDetail, detailedResponse, err := infoSvc.vpcService.GetRegion(infoSvc.vpcService.NewGetRegionOptions(region))
+	if err != nil {
+		log.Println("Failed GET DETAILS for region", region, ":", err, "Full Response:", detailedResponse)
+		return nil, err
+	}
+	if regionDetail.Endpoint == nil {
+		return nil, fmt.Errorf("region %s has no endpoint", region)
+	}
+	return infoSvc.getRegionalVpcService(*regionDetail.Endpoint + "/v1")
+}
+
+// listAvailableZones returns the names of the available zones of a region, ordered by name
+func listAvailableZones(regionVpcService vpcService, region string) ([]string, error) {
+	zoneCol, detailedResponse, err := regionVpcService.ListRegionZones(&vpcv1.ListRegionZonesOptions{RegionName: &region})
+	if err != nil {
+		log.Println("Failed LIST ZONES for region", region, ":", err, "Full Response:", detailedResponse)
+		return nil, err
+	}
+
+	var zones []string
+	for _, zone := range zoneCol.Zones {
+		if zone.Name != nil && zone.Status != nil && *zone.Status == vpcv1.ZoneStatusAvailableConst {
+			zones = append(zones, *zone.Name)
+		}
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// listZoneResources returns the zone name of each resource of the type in the region of the VPC client
+func listZoneResources(regionVpcService vpcService, resource string) ([]string, error) {
+	switch resource {
+	case ZoneResourceInstances:
+		return listInstanceZones(regionVpcService)
+	case ZoneResourceSubnets:
+		return listSubnetZones(regionVpcService)
+	case ZoneResourceBareMetalServers:
+		return listBareMetalServerZones(regionVpcService)
+	default:
+		return nil, fmt.Errorf("unknown zone resource %q", resource)
+	}
+}
+
+func listInstanceZones(regionVpcService vpcService) ([]string, error) {
+	var zones []string
+	listOptions := &vpcv1.ListInstancesOptions{}
+	listOptions.SetLimit(100)
+	for page := 0; page < maxZoneResourcePages; page++ {
+		instanceCol, _, err := regionVpcService.ListInstances(listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instanceCol.Instances {
+			if instance.Zone != nil && instance.Zone.Name != nil {
+				zones = append(zones, *instance.Zone.Name)
+			}
+		}
+		nextStart, err := instanceCol.GetNextStart()
+		if err != nil {
+			return nil, err
+		}
+		if nextStart == nil {
+			return zones, nil
+		}
+		listOptions.SetStart(*nextStart)
+	}
+	return nil, errors.New("too many pages of instances")
+}
+
+func listSubnetZones(regionVpcService vpcService) ([]string, error) {
+	var zones []string
+	listOptions := &vpcv1.ListSubnetsOptions{}
+	listOptions.SetLimit(100)
+	for page := 0; page < maxZoneResourcePages; page++ {
+		subnetCol, _, err := regionVpcService.ListSubnets(listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range subnetCol.Subnets {
+			if subnet.Zone != nil && subnet.Zone.Name != nil {
+				zones = append(zones, *subnet.Zone.Name)
+			}
+		}
+		nextStart, err := subnetCol.GetNextStart()
+		if err != nil {
+			return nil, err
+		}
+		if nextStart == nil {
+			return zones, nil
+		}
+		listOptions.SetStart(*nextStart)
+	}
+	return nil, errors.New("too many pages of subnets")
+}
+
+func listBareMetalServerZones(regionVpcService vpcService) ([]string, error) {
+	var zones []string
+	listOptions := &vpcv1.ListBareMetalServersOptions{}
+	listOptions.SetLimit(100)
+	for page := 0; page < maxZoneResourcePages; page++ {
+		serverCol, _, err := regionVpcService.ListBareMetalServers(listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range serverCol.BareMetalServers {
+			if server.Zone != nil && server.Zone.Name != nil {
+				zones = append(zones, *server.Zone.Name)
+			}
+		}
+		nextStart, err := serverCol.GetNextStart()
+		if err != nil {
+			return nil, err
+		}
+		if nextStart == nil {
+			return zones, nil
+		}
+		listOptions.SetStart(*nextStart)
+	}
+	return nil, errors.New("too many pages of bare metal servers")
+}
@@ -0,0 +1,78 @@
+package cloudinfo
+
+import (
+	"testing"
+
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeastVpcTestZone(t *testing.T) {
+	zoneRef := func(name string) *vpcv1.ZoneReference {
+		return &vpcv1.ZoneReference{Name: &name}
+	}
+	vpcService := &vpcServiceMock{
+		mockInstances: []vpcv1.Instance{
+			{Zone: zoneRef("us-south-1")},
+			{Zone: zoneRef("us-south-1")},
+			{Zone: zoneRef("us-south-3")},
+		},
+		mockSubnets: []vpcv1.Subnet{
+			{Zone: zoneRef("us-south-1")},
+			{Zone: zoneRef("us-south-2")},
+			{Zone: zoneRef("us-south-2")},
+			{Zone: zoneRef("us-south-4")},
+		},
+		mockBareMetalServers: []vpcv1.BareMetalServer{
+			{Zone: zoneRef("us-south-3")},
+		},
+	}
+	infoSvc := CloudInfoService{vpcService: vpcService, newVpcService: vpcService.newRegionalMock}
+
+	t.Run("ZoneCounts", func(t *testing.T) {
+		counts, err := infoSvc.GetVpcZoneCounts("us-south")
+		require.NoError(t, err)
+		assert.Equal(t, []ZoneCount{
+			{Zone: "us-south-1", Counts: map[string]int{ZoneResourceInstances: 2, ZoneResourceSubnets: 1}, Total: 3},
+			{Zone: "us-south-2", Counts: map[string]int{ZoneResourceSubnets: 2}, Total: 2},
+			{Zone: "us-south-3", Counts: map[string]int{ZoneResourceInstances: 1, ZoneResourceBareMetalServers: 1}, Total: 2},
+		}, counts, "impaired zones are not counted")
+	})
+
+	t.Run("LeastZone", func(t *testing.T) {
+		zone, err := infoSvc.GetLeastVpcTestZone("us-south")
+		require.NoError(t, err)
+		assert.Equal(t, "us-south-2", zone, "first zone in name order with the least resources")
+	})
+
+	t.Run("SelectedResources", func(t *testing.T) {
+		zone, err := infoSvc.GetLeastVpcTestZoneO("us-south", GetTestZoneOptions{Resources: []string{ZoneResourceInstances}})
+		require.NoError(t, err)
+		assert.Equal(t, "us-south-2", zone)
+
+		zone, err = infoSvc.GetLeastVpcTestZoneO("us-south", GetTestZoneOptions{Resources: []string{ZoneResourceSubnets}})
+		require.NoError(t, err)
+		assert.Equal(t, "us-south-3", zone)
+	})
+
+	t.Run("SelectedZones", func(t *testing.T) {
+		zone, err := infoSvc.GetLeastVpcTestZoneO("us-south", GetTestZoneOptions{Zones: []string{"us-south-1"}})
+		require.NoError(t, err)
+		assert.Equal(t, "us-south-1", zone)
+
+		_, err = infoSvc.GetLeastVpcTestZoneO("us-south", GetTestZoneOptions{Zones: []string{"us-south-4"}})
+		assert.Error(t, err, "zone that is not available")
+	})
+
+	t.Run("UnknownResource", func(t *testing.T) {
+		_, err := infoSvc.GetLeastVpcTestZoneO("us-south", GetTestZoneOptions{Resources: []string{"unknown"}})
+		assert.ErrorContains(t, err, "unknown zone resource")
+	})
+
+	t.Run("RegionError", func(t *testing.T) {
+		failingService := &vpcServiceMock{shouldFailGetRegion: true}
+		_, err := (&CloudInfoService{vpcService: failingService, newVpcService: failingService.newRegionalMock}).GetLeastVpcTestZone("us-south")
+		assert.Error(t, err)
+	})
+}
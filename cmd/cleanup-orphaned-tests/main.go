@@ -0,0 +1,71 @@
+// Command cleanup-orphaned-tests removes the IBM Cloud Projects, private catalogs and Schematics workspaces that tests
+// left behind in a shared test account, for example when a test crashed before its teardown or a workspace was kept
+// because DeleteWorkspaceOnFail was false. It is meant to run on a schedule in CI, so it does not ask for
+// confirmation: use -dry-run to only list what would be removed.
+//
+// Usage:
+//
+//	TF_VAR_ibmcloud_api_key=<api key> go run github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cmd/cleanup-orphaned-tests -prefix <prefix>[,<prefix>] [-min-age-hours 24] [-kinds project,schematics_workspace,catalog] [-destroy-workspace-resources=false] [-timeout-minutes 60] [-dry-run]
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+)
+
+const ibmcloudApiKeyVar = "TF_VAR_ibmcloud_api_key"
+
+func main() {
+	prefixes := flag.String("prefix", "", "comma separated name prefixes of test projects, catalogs and workspaces")
+	minAgeHours := flag.Int("min-age-hours", 24, "only items created more than this many hours ago are removed")
+	kinds := flag.String("kinds", "", "comma separated kinds to clean up: project, schematics_workspace, catalog (default all)")
+	destroyWorkspaceResources := flag.Bool("destroy-workspace-resources", true, "destroy the resources of a workspace before deleting it")
+	timeoutMinutes := flag.Int("timeout-minutes", 60, "time to wait for the undeploy of a project or the destroy of a workspace")
+	dryRun := flag.Bool("dry-run", false, "only list the orphaned items")
+	flag.Parse()
+
+	options := cloudinfo.OrphanCleanupOptions{
+		NamePrefixes:              splitList(*prefixes),
+		MinAge:                    time.Duration(*minAgeHours) * time.Hour,
+		Kinds:                     splitList(*kinds),
+		DestroyWorkspaceResources: *destroyWorkspaceResources,
+		Timeout:                   time.Duration(*timeoutMinutes) * time.Minute,
+		DryRun:                    *dryRun,
+	}
+
+	cloudInfoService, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{})
+	if err != nil {
+		log.Fatalf("Error creating cloud info service: %s", err)
+	}
+
+	result, err := cloudInfoService.CleanupOrphanedTestItems(options)
+	if err != nil {
+		log.Fatalf("Error finding orphaned test items: %s", err)
+	}
+	cloudinfo.PrintOrphanedTestItems(result.Found)
+	if *dryRun {
+		return
+	}
+
+	log.Printf("Removed %d of %d orphaned test items", len(result.Removed), len(result.Found))
+	if len(result.Errors) > 0 {
+		log.Printf("%d items could not be removed", len(result.Errors))
+		os.Exit(1)
+	}
+}
+
+// splitList splits a comma separated flag value, ignoring empty values
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// runTerraform runs a terraform command in a directory, replaced in unit tests
+var runTerraform = func(dir string, binary string, args ...string) error {
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// cleanup finishes the teardown of pending journal entries
+type cleanup struct {
+	journal          *common.TeardownJournal
+	cloudInfoService cloudinfo.CloudInfoServiceI // only required for schematics and project entries
+	timeoutMinutes   int                         // time to wait for each destroy or undeploy
+	pollInterval     time.Duration
+}
+
+// run tears down every pending entry of the journal, marking each entry complete once its teardown succeeded.
+// Returns the number of entries that could not be torn down.
+func (c *cleanup) run(dryRun bool) (int, error) {
+	pending, err := c.journal.Pending()
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		log.Printf("No pending teardowns in journal %s", c.journal.Path)
+		return 0, nil
+	}
+
+	failed := 0
+	for _, entry := range pending {
+		log.Printf("Pending %s teardown %s (test: %s, prefix: %s, recorded: %s)", entry.Type, entry.ID, entry.TestName, entry.Prefix, entry.Time.Format(time.RFC3339))
+		if dryRun {
+			continue
+		}
+		if teardownErr := c.teardown(entry); teardownErr != nil {
+			log.Printf("ERROR: teardown of %s failed: %s", entry.ID, teardownErr)
+			failed++
+			continue
+		}
+		if completeErr := c.journal.Complete(entry.ID); completeErr != nil {
+			return failed, completeErr
+		}
+		log.Printf("Teardown of %s completed", entry.ID)
+	}
+
+	if !dryRun {
+		if compactErr := c.journal.Compact(); compactErr != nil {
+			log.Printf("WARNING: failed to compact journal %s: %s", c.journal.Path, compactErr)
+		}
+	}
+	return failed, nil
+}
+
+// teardown removes what was created for a single journal entry
+func (c *cleanup) teardown(entry common.TeardownJournalEntry) error {
+	switch entry.Type {
+	case common.TeardownJournalTerraform:
+		return c.teardownTerraform(entry)
+	case common.TeardownJournalSchematics:
+		return c.teardownSchematics(entry)
+	case common.TeardownJournalProject:
+		return c.teardownProject(entry)
+	default:
+		return fmt.Errorf("unknown journal entry type %q", entry.Type)
+	}
+}
+
+// teardownTerraform runs terraform destroy in the directory of the test, using the recorded variables
+func (c *cleanup) teardownTerraform(entry common.TeardownJournalEntry) error {
+	if _, err := os.Stat(entry.TerraformDir); err != nil {
+		return fmt.Errorf("terraform directory of the test is no longer available: %w", err)
+	}
+	binary := entry.TerraformBinary
+	if binary == "" {
+		binary = "terraform"
+	}
+
+	if err := runTerraform(entry.TerraformDir, binary, "init", "-input=false"); err != nil {
+		return fmt.Errorf("terraform init failed: %w", err)
+	}
+	if entry.WorkspaceName != "" {
+		if err := runTerraform(entry.TerraformDir, binary, "workspace", "select", entry.WorkspaceName); err != nil {
+			return fmt.Errorf("terraform workspace select failed: %w", err)
+		}
+	}
+	destroyArgs := []string{"destroy", "-auto-approve", "-input=false"}
+	if entry.VarFile != "" {
+		destroyArgs = append(destroyArgs, fmt.Sprintf("-var-file=%s", entry.VarFile))
+	}
+	if err := runTerraform(entry.TerraformDir, binary, destroyArgs...); err != nil {
+		return fmt.Errorf("terraform destroy failed: %w", err)
+	}
+	return nil
+}
+
+// teardownSchematics destroys the resources of the test workspace and deletes the workspace
+func (c *cleanup) teardownSchematics(entry common.TeardownJournalEntry) error {
+	if c.cloudInfoService == nil {
+		return fmt.Errorf("no cloud info service available for schematics teardown")
+	}
+	workspaceID, location := entry.SchematicsWorkspaceID, entry.SchematicsWorkspaceLocation
+
+	destroyResult, err := c.cloudInfoService.CreateSchematicsDestroyJob(workspaceID, location)
+	if err != nil {
+		if isNotFoundError(err) {
+			log.Printf("Workspace %s no longer exists", workspaceID)
+			return nil
+		}
+		return fmt.Errorf("error creating destroy job for workspace %s: %w", workspaceID, err)
+	}
+	if destroyResult != nil && destroyResult.Activityid != nil {
+		status, waitErr := c.cloudInfoService.WaitForSchematicsJobCompletion(workspaceID, *destroyResult.Activityid, location, c.timeoutMinutes)
+		if waitErr != nil {
+			return fmt.Errorf("error waiting for destroy of workspace %s: %w", workspaceID, waitErr)
+		}
+		if status != cloudinfo.SchematicsJobStatusCompleted {
+			return fmt.Errorf("destroy of workspace %s finished with status %s", workspaceID, status)
+		}
+	}
+
+	if _, err := c.cloudInfoService.DeleteSchematicsWorkspace(workspaceID, location, false); err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("error deleting workspace %s: %w", workspaceID, err)
+	}
+	return nil
+}
+
+// teardownProject undeploys all deployed configurations of the test project, deletes the project and the catalog
+func (c *cleanup) teardownProject(entry common.TeardownJournalEntry) error {
+	if c.cloudInfoService == nil {
+		return fmt.Errorf("no cloud info service available for project teardown")
+	}
+
+	if entry.ProjectID != "" {
+		if err := c.undeployProject(entry.ProjectID); err != nil {
+			return err
+		}
+		if _, _, err := c.cloudInfoService.DeleteProject(entry.ProjectID); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("error deleting project %s: %w", entry.ProjectID, err)
+		}
+	}
+
+	if entry.CatalogID != "" {
+		if err := c.cloudInfoService.DeleteCatalog(entry.CatalogID); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("error deleting catalog %s: %w", entry.CatalogID, err)
+		}
+	}
+	return nil
+}
+
+// undeployProject undeploys the deployed configurations of a project and waits until none are undeploying
+func (c *cleanup) undeployProject(projectID string) error {
+	configs, err := c.cloudInfoService.GetProjectConfigs(projectID)
+	if err != nil {
+		if isNotFoundError(err) {
+			log.Printf("Project %s no longer exists", projectID)
+			return nil
+		}
+		return fmt.Errorf("error listing configurations of project %s: %w", projectID, err)
+	}
+
+	var undeploying []*cloudinfo.ConfigDetails
+	for _, config := range configs {
+		if config.ID == nil {
+			continue
+		}
+		details := &cloudinfo.ConfigDetails{ProjectID: projectID, ConfigID: *config.ID}
+		if _, deployed := c.cloudInfoService.IsConfigDeployed(details); !deployed {
+			continue
+		}
+		log.Printf("Undeploying configuration %s of project %s", *config.ID, projectID)
+		if _, _, undeployErr := c.cloudInfoService.UndeployConfig(details); undeployErr != nil {
+			return fmt.Errorf("error undeploying configuration %s of project %s: %w", *config.ID, projectID, undeployErr)
+		}
+		undeploying = append(undeploying, details)
+	}
+
+	endTime := time.Now().Add(time.Duration(c.timeoutMinutes) * time.Minute)
+	for len(undeploying) > 0 {
+		var still []*cloudinfo.ConfigDetails
+		for _, details := range undeploying {
+			if _, isUndeploying := c.cloudInfoService.IsUndeploying(details); isUndeploying {
+				still = append(still, details)
+			}
+		}
+		undeploying = still
+		if len(undeploying) == 0 {
+			break
+		}
+		if time.Now().After(endTime) {
+			return fmt.Errorf("timeout waiting for %d configuration(s) of project %s to undeploy", len(undeploying), projectID)
+		}
+		time.Sleep(c.pollInterval)
+	}
+	return nil
+}
+
+// isNotFoundError returns true if the error is an API response with status 404, which means that the resource was
+// already removed
+func isNotFoundError(err error) bool {
+	var httpProblem *core.HTTPProblem
+	return errors.As(err, &httpProblem) && httpProblem.Response != nil && httpProblem.Response.StatusCode == http.StatusNotFound
+}
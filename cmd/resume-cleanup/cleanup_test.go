@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	project "github.com/IBM/project-go-sdk/projectv1"
+	schematics "github.com/IBM/schematics-go-sdk/schematicsv1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// fakeCleanupService implements the cloud info calls used by the cleanup, other calls panic
+type fakeCleanupService struct {
+	cloudinfo.CloudInfoServiceI
+	calls []string
+}
+
+func (f *fakeCleanupService) CreateSchematicsDestroyJob(workspaceID, location string) (*schematics.WorkspaceActivityDestroyResult, error) {
+	f.calls = append(f.calls, "destroy "+workspaceID)
+	return &schematics.WorkspaceActivityDestroyResult{Activityid: core.StringPtr("job-1")}, nil
+}
+
+func (f *fakeCleanupService) WaitForSchematicsJobCompletion(workspaceID, jobID, location string, timeoutMinutes int) (string, error) {
+	return cloudinfo.SchematicsJobStatusCompleted, nil
+}
+
+func (f *fakeCleanupService) DeleteSchematicsWorkspace(workspaceID, location string, destroyResources bool) (string, error) {
+	f.calls = append(f.calls, "delete workspace "+workspaceID)
+	return "", nil
+}
+
+func (f *fakeCleanupService) GetProjectConfigs(projectID string) ([]project.ProjectConfigSummary, error) {
+	return []project.ProjectConfigSummary{{ID: core.StringPtr("config-1")}}, nil
+}
+
+func (f *fakeCleanupService) IsConfigDeployed(details *cloudinfo.ConfigDetails) (*project.ProjectConfigVersion, bool) {
+	return nil, true
+}
+
+func (f *fakeCleanupService) UndeployConfig(details *cloudinfo.ConfigDetails) (*project.ProjectConfigVersion, *core.DetailedResponse, error) {
+	f.calls = append(f.calls, "undeploy "+details.ConfigID)
+	return nil, nil, nil
+}
+
+func (f *fakeCleanupService) IsUndeploying(details *cloudinfo.ConfigDetails) (*project.ProjectConfigVersion, bool) {
+	return nil, false
+}
+
+func (f *fakeCleanupService) DeleteProject(projectID string) (*project.ProjectDeleteResponse, *core.DetailedResponse, error) {
+	f.calls = append(f.calls, "delete project "+projectID)
+	return nil, nil, nil
+}
+
+func (f *fakeCleanupService) DeleteCatalog(catalogID string) error {
+	f.calls = append(f.calls, "delete catalog "+catalogID)
+	return fmt.Errorf("error deleting catalog: %w", &core.HTTPProblem{
+		IBMProblem: &core.IBMProblem{Summary: "Not Found"},
+		Response:   &core.DetailedResponse{StatusCode: http.StatusNotFound},
+	})
+}
+
+func TestIsNotFoundError(t *testing.T) {
+	assert.True(t, isNotFoundError((&fakeCleanupService{}).DeleteCatalog("c-1")))
+	assert.False(t, isNotFoundError(&core.HTTPProblem{
+		IBMProblem: &core.IBMProblem{Summary: "Internal Server Error"},
+		Response:   &core.DetailedResponse{StatusCode: http.StatusInternalServerError},
+	}))
+	assert.False(t, isNotFoundError(fmt.Errorf("error deleting project p-404: not found")), "only the response status is checked")
+}
+
+func TestCleanupRun(t *testing.T) {
+	journal := common.NewTeardownJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	terraformDir := t.TempDir()
+	require.NoError(t, journal.Record(common.TeardownJournalEntry{ID: "tf", Type: common.TeardownJournalTerraform, TerraformDir: terraformDir, VarFile: "/tmp/vars.tfvars.json", WorkspaceName: "test"}))
+	require.NoError(t, journal.Record(common.TeardownJournalEntry{ID: "ws", Type: common.TeardownJournalSchematics, SchematicsWorkspaceID: "ws-1", SchematicsWorkspaceLocation: "us"}))
+	require.NoError(t, journal.Record(common.TeardownJournalEntry{ID: "proj", Type: common.TeardownJournalProject, ProjectID: "p-1", CatalogID: "c-1"}))
+	require.NoError(t, journal.Record(common.TeardownJournalEntry{ID: "gone", Type: common.TeardownJournalTerraform, TerraformDir: filepath.Join(terraformDir, "missing")}))
+	require.NoError(t, journal.Record(common.TeardownJournalEntry{ID: "done", Type: common.TeardownJournalProject, ProjectID: "p-2"}))
+	require.NoError(t, journal.Complete("done"))
+
+	var terraformCommands []string
+	originalRunTerraform := runTerraform
+	runTerraform = func(dir string, binary string, args ...string) error {
+		terraformCommands = append(terraformCommands, binary+" "+strings.Join(args, " "))
+		return nil
+	}
+	t.Cleanup(func() { runTerraform = originalRunTerraform })
+
+	service := &fakeCleanupService{}
+	c := &cleanup{journal: journal, cloudInfoService: service, timeoutMinutes: 1}
+
+	failed, err := c.run(true)
+	require.NoError(t, err)
+	assert.Zero(t, failed)
+	assert.Empty(t, terraformCommands, "dry run must not tear anything down")
+
+	failed, err = c.run(false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed, "the entry without a terraform directory can not be torn down")
+	assert.Equal(t, []string{
+		"terraform init -input=false",
+		"terraform workspace select test",
+		"terraform destroy -auto-approve -input=false -var-file=/tmp/vars.tfvars.json",
+	}, terraformCommands)
+	assert.Equal(t, []string{
+		"destroy ws-1",
+		"delete workspace ws-1",
+		"undeploy config-1",
+		"delete project p-1",
+		"delete catalog c-1",
+	}, service.calls)
+
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "gone", pending[0].ID)
+}
@@ -0,0 +1,55 @@
+// Command resume-cleanup finishes the teardown of tests that did not complete it, for example because the test
+// process was killed. It reads the teardown journal written by the test runners and, for every entry that is not
+// marked complete, runs terraform destroy in the test directory, destroys and deletes the schematics workspace, or
+// undeploys and deletes the project and catalog.
+//
+// Usage:
+//
+//	TF_VAR_ibmcloud_api_key=<api key> go run github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cmd/resume-cleanup [-journal <path>] [-dry-run]
+//
+// The journal defaults to the same location as the test runners, see common.DefaultTeardownJournalPath.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+const ibmcloudApiKeyVar = "TF_VAR_ibmcloud_api_key"
+
+func main() {
+	journalPath := flag.String("journal", common.DefaultTeardownJournalPath(), "path of the teardown journal")
+	dryRun := flag.Bool("dry-run", false, "only list the pending teardowns")
+	timeoutMinutes := flag.Int("timeout", 60, "minutes to wait for each destroy or undeploy to finish")
+	flag.Parse()
+
+	c := &cleanup{
+		journal:        common.NewTeardownJournal(*journalPath),
+		timeoutMinutes: *timeoutMinutes,
+		pollInterval:   30 * time.Second,
+	}
+
+	if !*dryRun {
+		// the cloud info service is only needed for schematics and project entries, terraform entries use the local state
+		cloudInfoService, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{})
+		if err != nil {
+			log.Printf("WARNING: could not create cloud info service, only terraform teardowns can be resumed: %s", err)
+		} else {
+			c.cloudInfoService = cloudInfoService
+		}
+	}
+
+	failed, err := c.run(*dryRun)
+	if err != nil {
+		log.Fatalf("Error resuming teardown: %s", err)
+	}
+	if failed > 0 {
+		log.Printf("%d teardown(s) could not be completed and remain in the journal", failed)
+		os.Exit(1)
+	}
+}
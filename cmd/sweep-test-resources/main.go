@@ -0,0 +1,88 @@
+// Command sweep-test-resources deletes test resources that were left behind in a shared test account, for example by
+// test runs that crashed before their teardown. It finds the resource instances, reclaimed instances, VPCs and resource
+// groups whose name starts with one of the given prefixes, or that have one of the given tags, and that are older than
+// the minimum age. The resources are printed and, after confirmation, deleted.
+//
+// Usage:
+//
+//	TF_VAR_ibmcloud_api_key=<api key> go run github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cmd/sweep-test-resources -prefix <prefix>[,<prefix>] [-tag <tag>] [-min-age-hours 24] [-vpc-regions <region>,<region>] [-dry-run] [-yes]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+)
+
+const ibmcloudApiKeyVar = "TF_VAR_ibmcloud_api_key"
+
+func main() {
+	prefixes := flag.String("prefix", "", "comma separated name prefixes of test resources")
+	tags := flag.String("tag", "", "comma separated user tags of test resources")
+	minAgeHours := flag.Int("min-age-hours", 24, "only resources created more than this many hours ago are deleted")
+	vpcRegions := flag.String("vpc-regions", "", "comma separated regions in which VPCs are deleted")
+	dryRun := flag.Bool("dry-run", false, "only list the stale resources")
+	yes := flag.Bool("yes", false, "delete without asking for confirmation")
+	flag.Parse()
+
+	options := cloudinfo.SweepOptions{
+		NamePrefixes: splitList(*prefixes),
+		Tags:         splitList(*tags),
+		MinAge:       time.Duration(*minAgeHours) * time.Hour,
+		VpcRegions:   splitList(*vpcRegions),
+	}
+
+	cloudInfoService, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{})
+	if err != nil {
+		log.Fatalf("Error creating cloud info service: %s", err)
+	}
+
+	found, err := cloudInfoService.FindStaleTestResources(options)
+	if err != nil {
+		log.Fatalf("Error finding stale test resources: %s", err)
+	}
+	cloudinfo.PrintStaleTestResources(found)
+	if found.Count() == 0 || *dryRun {
+		return
+	}
+	if !*yes && !confirm(os.Stdin, os.Stdout, found.Count()) {
+		log.Println("Nothing was deleted")
+		return
+	}
+
+	result := cloudInfoService.DeleteStaleTestResources(found)
+	log.Printf("Deleted %d resources", len(result.Deleted))
+	if len(result.Skipped) > 0 {
+		log.Printf("%d VPCs still contain resources and were skipped: %s", len(result.Skipped), strings.Join(result.Skipped, ", "))
+	}
+	if len(result.Errors) > 0 {
+		log.Printf("%d resources could not be deleted", len(result.Errors))
+		os.Exit(1)
+	}
+}
+
+// splitList splits a comma separated flag value, ignoring empty values
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// confirm asks whether the resources should be deleted, only `y` or `yes` confirms
+func confirm(in io.Reader, out io.Writer, count int) bool {
+	fmt.Fprintf(out, "Delete these %d resources? [y/N]: ", count)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"ci-", "test-"}, splitList(" ci-, ,test-,"))
+	assert.Nil(t, splitList(""))
+}
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	assert.True(t, confirm(strings.NewReader("Yes\n"), &out, 3))
+	assert.Equal(t, "Delete these 3 resources? [y/N]: ", out.String())
+	assert.True(t, confirm(strings.NewReader("y"), &out, 3))
+	assert.False(t, confirm(strings.NewReader("\n"), &out, 3))
+	assert.False(t, confirm(strings.NewReader("no\n"), &out, 3))
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// DefaultTeardownReserve is the default amount of time reserved before the `go test -timeout` deadline for tearing
+// down test resources. Test operations (apply, plan, deploy) are cancelled when only this amount of time is left.
+const DefaultTeardownReserve = 15 * time.Minute
+
+// GetTestDeadline returns the time by which test operations must be finished, so that the teardown reserve is left
+// before the deadline of the go test binary (`go test -timeout`). A teardown reserve of zero uses DefaultTeardownReserve.
+// The reserve is at most half of the time left before the deadline, so that a short deadline, for example the 10 minute
+// default of go test, still leaves time for the test operations.
+// Returns false if the test has no deadline.
+func GetTestDeadline(t *testing.T, teardownReserve time.Duration) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	deadline, ok := t.Deadline()
+	if !ok {
+		return time.Time{}, false
+	}
+	return reserveTeardown(deadline, teardownReserve, time.Now()), true
+}
+
+// reserveTeardown returns the deadline minus the teardown reserve, which is at most half of the time left at now
+func reserveTeardown(deadline time.Time, teardownReserve time.Duration, now time.Time) time.Time {
+	if teardownReserve <= 0 {
+		teardownReserve = DefaultTeardownReserve
+	}
+	if maxReserve := deadline.Sub(now) / 2; teardownReserve > maxReserve {
+		teardownReserve = max(maxReserve, 0)
+	}
+	return deadline.Add(-teardownReserve)
+}
+
+// NewTestContext returns a context for test operations derived from parent (context.Background() if nil), which is
+// cancelled when the test deadline minus the teardown reserve is reached.
+// The returned cancel function must be called to release resources once the operations are done.
+func NewTestContext(t *testing.T, parent context.Context, teardownReserve time.Duration) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if deadline, ok := GetTestDeadline(t, teardownReserve); ok {
+		return context.WithDeadline(parent, deadline)
+	}
+	return context.WithCancel(parent)
+}
+
+// NewTeardownContext returns a context for tearing down test resources. It keeps the values of parent (if supplied)
+// but is not cancelled when parent is, so that teardown still runs after the test operations were cancelled.
+// It is only bounded by the deadline of the go test binary itself.
+// The returned cancel function must be called to release resources once teardown is done.
+func NewTeardownContext(t *testing.T, parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	parent = context.WithoutCancel(parent)
+	if t != nil {
+		if deadline, ok := t.Deadline(); ok {
+			return context.WithDeadline(parent, deadline)
+		}
+	}
+	return context.WithCancel(parent)
+}
+
+// BoundTimeout returns the smaller of timeout and the time remaining before the deadline of ctx.
+// If ctx has no deadline the timeout is returned unchanged, and if the deadline has passed zero is returned.
+func BoundTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if ctx == nil {
+		return timeout
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0
+	}
+	if remaining < timeout {
+		return remaining
+	}
+	return timeout
+}
+
+// BoundTimeoutMinutes is the same as BoundTimeout for timeouts expressed in whole minutes, as used by the polling
+// functions. The result is rounded down, with a minimum of one minute so that a zero value is never mistaken for
+// "use the default timeout".
+func BoundTimeoutMinutes(ctx context.Context, timeoutMinutes int) int {
+	bounded := BoundTimeout(ctx, time.Duration(timeoutMinutes)*time.Minute)
+	minutes := int(bounded / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTestDeadline(t *testing.T) {
+	testDeadline, hasDeadline := t.Deadline()
+
+	deadline, ok := GetTestDeadline(t, 5*time.Minute)
+	assert.Equal(t, hasDeadline, ok)
+	if hasDeadline {
+		assert.False(t, deadline.After(testDeadline))
+		assert.False(t, deadline.Before(testDeadline.Add(-5*time.Minute)))
+	}
+
+	_, ok = GetTestDeadline(nil, time.Minute)
+	assert.False(t, ok)
+}
+
+func TestReserveTeardown(t *testing.T) {
+	now := time.Now()
+
+	t.Run("LongDeadline", func(t *testing.T) {
+		deadline := now.Add(2 * time.Hour)
+		assert.Equal(t, deadline.Add(-5*time.Minute), reserveTeardown(deadline, 5*time.Minute, now))
+		assert.Equal(t, deadline.Add(-DefaultTeardownReserve), reserveTeardown(deadline, 0, now))
+	})
+
+	t.Run("ShortDeadline", func(t *testing.T) {
+		// the default go test timeout is shorter than the default reserve
+		deadline := now.Add(10 * time.Minute)
+		assert.Equal(t, now.Add(5*time.Minute), reserveTeardown(deadline, 0, now))
+	})
+
+	t.Run("DeadlinePassed", func(t *testing.T) {
+		deadline := now.Add(-time.Minute)
+		assert.Equal(t, deadline, reserveTeardown(deadline, 0, now))
+	})
+}
+
+func TestNewTeardownContextNotCancelledWithParent(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	runCtx, cancelRun := NewTestContext(nil, parent, time.Minute)
+	defer cancelRun()
+	teardownCtx, cancelTeardown := NewTeardownContext(nil, parent)
+	defer cancelTeardown()
+
+	cancelParent()
+	assert.Error(t, runCtx.Err())
+	assert.NoError(t, teardownCtx.Err())
+}
+
+func TestBoundTimeout(t *testing.T) {
+	assert.Equal(t, time.Hour, BoundTimeout(nil, time.Hour))
+	assert.Equal(t, time.Hour, BoundTimeout(context.Background(), time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	assert.LessOrEqual(t, BoundTimeout(ctx, time.Hour), 10*time.Minute)
+	assert.Equal(t, time.Minute, BoundTimeout(ctx, time.Minute))
+	assert.Equal(t, 9, BoundTimeoutMinutes(ctx, 120))
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	assert.Equal(t, time.Duration(0), BoundTimeout(expired, time.Hour))
+	assert.Equal(t, 1, BoundTimeoutMinutes(expired, 120))
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lock files are only held while a few small files are read and written
+const (
+	fileLockTimeout = 2 * time.Minute
+	fileLockPoll    = 50 * time.Millisecond
+)
+
+// withFileLock runs fn while holding the exclusive lock of the file at path, shared by all processes locking the same
+// path. The lock file is created if it does not exist, and is left in place.
+func withFileLock(path string, fn func() error) error {
+	lockFile, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening lock file %s: %w", path, err)
+	}
+	defer lockFile.Close()
+
+	deadline := time.Now().Add(fileLockTimeout)
+	for {
+		locked, err := tryLockFile(lockFile)
+		if err != nil {
+			return fmt.Errorf("error locking %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out locking %s", path)
+		}
+		time.Sleep(fileLockPoll)
+	}
+	defer unlockFile(lockFile)
+
+	return fn()
+}
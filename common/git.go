@@ -496,6 +496,47 @@ func CloneAndCheckoutBranch(testing *testing.T, repoURL string, branch string, c
 	return nil
 }
 
+// CloneAndCheckoutTag clones a single tag of a repository into cloneDir, logging the clone to the test
+func CloneAndCheckoutTag(testing *testing.T, repoURL string, tag string, cloneDir string) error {
+	testing.Helper()
+	logger.Log(testing, fmt.Sprintf("Cloning %s at tag %s into %s", repoURL, tag, cloneDir))
+	authMethod, _ := GitAutoAuth(repoURL)
+	_, errClone := git.PlainClone(cloneDir, false, &git.CloneOptions{
+		URL:           repoURL,
+		ReferenceName: plumbing.NewTagReferenceName(tag),
+		SingleBranch:  true,
+		Auth:          authMethod,
+	})
+	if errClone != nil {
+		return fmt.Errorf("failed to clone repo at tag %s: %v", tag, errClone)
+	}
+
+	return nil
+}
+
+// GetRemoteTags returns the names of all tags in a remote repository, without cloning the repository
+func GetRemoteTags(repoURL string) ([]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+
+	authMethod, _ := GitAutoAuth(repoURL)
+	refs, err := remote.List(&git.ListOptions{Auth: authMethod})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote tags of %s: %w", repoURL, err)
+	}
+
+	tags := []string{}
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+
+	return tags, nil
+}
+
 // ChangesToBePush determines if there are any changes to push to the remote repository.
 // Returns a boolean indicating if there are changes and a slice of filenames that have changes.
 func ChangesToBePush(testing *testing.T, repoDir string) (bool, []string, error) {
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// InterruptTeardown is a teardown registered with RegisterInterruptTeardown. On the first SIGINT or SIGTERM received by
+// the test process the cancel function of every registered teardown is called to stop the in-flight operation of the
+// test. The teardown itself is run by the test: by its normal teardown path calling Run, or when the test finishes.
+// The process exits once every registered teardown has been unregistered. A second signal forces the process to exit
+// immediately.
+type InterruptTeardown struct {
+	name     string
+	cancel   context.CancelFunc
+	teardown func()
+	once     sync.Once
+
+	unregisterOnce sync.Once
+	unregistered   chan struct{} // closed by Unregister
+}
+
+// interruptHandler keeps track of the registered teardowns, the signal listener is started on the first registration
+var interruptHandler = struct {
+	sync.Mutex
+	started     bool
+	interrupted bool
+	teardowns   []*InterruptTeardown
+}{}
+
+// interruptExit is used to exit the process after an interrupt, replaced in unit tests
+var interruptExit = os.Exit
+
+// RegisterInterruptTeardown registers a teardown to run when the test process is interrupted.
+// The name is used in log messages, cancel (optional) should stop the in-flight operation and teardown should
+// destroy what the test created. The returned InterruptTeardown must be unregistered once the test is torn down,
+// and the test should call Run for its normal teardown so that the teardown is never run twice.
+// The teardown always runs on the goroutine of the test, never on the signal handler, as it may use the test: if the
+// test was interrupted and ends without calling Run, the teardown is run by a cleanup function of testing.
+func RegisterInterruptTeardown(testing *testing.T, name string, cancel context.CancelFunc, teardown func()) *InterruptTeardown {
+	registration := &InterruptTeardown{
+		name:         name,
+		cancel:       cancel,
+		teardown:     teardown,
+		unregistered: make(chan struct{}),
+	}
+
+	interruptHandler.Lock()
+	defer interruptHandler.Unlock()
+	interruptHandler.teardowns = append(interruptHandler.teardowns, registration)
+	if !interruptHandler.started {
+		interruptHandler.started = true
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go listenForInterrupts(signals)
+	}
+	if interruptHandler.interrupted && cancel != nil {
+		// the process is exiting, do not start new operations
+		cancel()
+	}
+	if testing != nil {
+		testing.Cleanup(registration.cleanup)
+	}
+
+	return registration
+}
+
+// Run runs the teardown. The teardown is only run once, concurrent calls wait until it has finished.
+func (registration *InterruptTeardown) Run() {
+	if registration == nil {
+		return
+	}
+	registration.once.Do(func() {
+		if registration.teardown != nil {
+			registration.teardown()
+		}
+	})
+}
+
+// Unregister removes the teardown so that it is not run on an interrupt
+func (registration *InterruptTeardown) Unregister() {
+	if registration == nil {
+		return
+	}
+	interruptHandler.Lock()
+	defer interruptHandler.Unlock()
+	for i, registered := range interruptHandler.teardowns {
+		if registered == registration {
+			interruptHandler.teardowns = append(interruptHandler.teardowns[:i], interruptHandler.teardowns[i+1:]...)
+			break
+		}
+	}
+	registration.unregisterOnce.Do(func() {
+		close(registration.unregistered)
+	})
+}
+
+// cleanup runs the teardown when the test ends after an interrupt without having run or unregistered it, for example
+// because the test failed with FailNow, and unregisters it
+func (registration *InterruptTeardown) cleanup() {
+	interruptHandler.Lock()
+	pending := interruptHandler.interrupted
+	if pending {
+		pending = false
+		for _, registered := range interruptHandler.teardowns {
+			if registered == registration {
+				pending = true
+				break
+			}
+		}
+	}
+	interruptHandler.Unlock()
+
+	if pending {
+		log.Printf("Running teardown for %s after interrupt", registration.name)
+		registration.Run()
+	}
+	registration.Unregister()
+}
+
+// IsInterrupted returns true once the test process received SIGINT or SIGTERM
+func IsInterrupted() bool {
+	interruptHandler.Lock()
+	defer interruptHandler.Unlock()
+	return interruptHandler.interrupted
+}
+
+// listenForInterrupts cancels the test operations on the first signal and forces an exit on the second.
+// Only log is used here and in handleInterrupt, as the tests may have finished.
+func listenForInterrupts(signals <-chan os.Signal) {
+	for sig := range signals {
+		if IsInterrupted() {
+			log.Printf("Received %s during teardown, exiting without completing teardown", sig)
+			interruptExit(1)
+			return
+		}
+		log.Printf("Received %s, cancelling test operations and running teardown. Send again to exit immediately", sig)
+		go handleInterrupt()
+	}
+}
+
+// handleInterrupt cancels the operations of all registered teardowns, waits for the tests to run and unregister their
+// teardowns, and exits
+func handleInterrupt() {
+	interruptHandler.Lock()
+	interruptHandler.interrupted = true
+	teardowns := append([]*InterruptTeardown{}, interruptHandler.teardowns...)
+	interruptHandler.Unlock()
+
+	for _, registration := range teardowns {
+		if registration.cancel != nil {
+			registration.cancel()
+		}
+	}
+
+	for _, registration := range teardowns {
+		<-registration.unregistered
+	}
+
+	log.Printf("Teardown after interrupt completed for %d test(s), exiting", len(teardowns))
+	interruptExit(1)
+}
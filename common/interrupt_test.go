@@ -0,0 +1,109 @@
+package common
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetInterruptHandler(t *testing.T) {
+	originalExit := interruptExit
+	t.Cleanup(func() {
+		interruptExit = originalExit
+		interruptHandler.Lock()
+		interruptHandler.interrupted = false
+		interruptHandler.teardowns = nil
+		interruptHandler.Unlock()
+	})
+}
+
+func TestHandleInterruptWaitsForTeardowns(t *testing.T) {
+	resetInterruptHandler(t)
+	exitCode := make(chan int, 1)
+	interruptExit = func(code int) { exitCode <- code }
+
+	var tornDown int32
+	cancelled := make(chan struct{})
+	first := RegisterInterruptTeardown(nil, "first", func() { close(cancelled) }, func() { atomic.AddInt32(&tornDown, 1) })
+	second := RegisterInterruptTeardown(nil, "second", nil, func() { atomic.AddInt32(&tornDown, 1) })
+	unregistered := RegisterInterruptTeardown(nil, "unregistered", func() { t.Error("unregistered teardown was cancelled") }, nil)
+	unregistered.Unregister()
+
+	go handleInterrupt()
+	<-cancelled
+	assert.True(t, IsInterrupted())
+
+	// the tests run their own teardown once their operation is cancelled
+	first.Run()
+	first.Unregister()
+	select {
+	case <-exitCode:
+		t.Fatal("exited before every teardown was unregistered")
+	case <-time.After(50 * time.Millisecond):
+	}
+	second.Run()
+	second.Unregister()
+
+	select {
+	case code := <-exitCode:
+		assert.Equal(t, 1, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not exit after every teardown was unregistered")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tornDown))
+
+	// the teardown is never run twice
+	first.Run()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tornDown))
+
+	// a test that registers after the interrupt is cancelled right away
+	late := int32(0)
+	RegisterInterruptTeardown(nil, "late", func() { atomic.AddInt32(&late, 1) }, nil).Unregister()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&late))
+}
+
+func TestInterruptTeardownCleanup(t *testing.T) {
+	resetInterruptHandler(t)
+
+	runs := 0
+	t.Run("NotInterrupted", func(t *testing.T) {
+		RegisterInterruptTeardown(t, "test", nil, func() { runs++ })
+	})
+	assert.Equal(t, 0, runs, "the teardown is not run by the cleanup of a test that was not interrupted")
+
+	interruptHandler.Lock()
+	interruptHandler.interrupted = true
+	interruptHandler.Unlock()
+	t.Run("Interrupted", func(t *testing.T) {
+		RegisterInterruptTeardown(t, "test", nil, func() { runs++ })
+	})
+	assert.Equal(t, 1, runs, "the teardown is run by the cleanup of an interrupted test")
+	t.Run("TornDown", func(t *testing.T) {
+		registration := RegisterInterruptTeardown(t, "test", nil, func() { runs++ })
+		registration.Run()
+		registration.Unregister()
+	})
+	assert.Equal(t, 2, runs, "the teardown is not run again by the cleanup")
+}
+
+func TestInterruptTeardownRunWithoutInterrupt(t *testing.T) {
+	resetInterruptHandler(t)
+
+	runs := 0
+	registration := RegisterInterruptTeardown(t, "test", nil, func() { runs++ })
+	registration.Run()
+	registration.Run()
+	registration.Unregister()
+	registration.Unregister()
+
+	assert.Equal(t, 1, runs)
+	assert.False(t, IsInterrupted())
+
+	var nilRegistration *InterruptTeardown
+	assert.NotPanics(t, func() {
+		nilRegistration.Run()
+		nilRegistration.Unregister()
+	})
+}
@@ -0,0 +1,334 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// LeaseDirEnvVar is the environment variable with the lease directory shared by the test processes, see LeaseManager
+const LeaseDirEnvVar = "TEST_LEASE_DIR"
+
+// DefaultLeaseTTL is the time after which a lease expires if it is not released or renewed
+const DefaultLeaseTTL = 6 * time.Hour
+
+const (
+	leaseFileSuffix   = ".lease"
+	leaseLockFileName = ".lock"
+)
+
+// ErrLeaseUnavailable is returned by TryAcquire when all slots of a lease are held
+var ErrLeaseUnavailable = errors.New("lease is held by other processes")
+
+var leaseNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// LeaseManager hands out named leases stored as files in a directory shared by test processes, so that separate
+// `go test` processes can coordinate, for example to not select the same region or to cap the number of Schematics
+// workspaces created at the same time. A lease has a number of slots, each held by at most one process.
+//
+// A lease is free again once it is released, once its TTL has passed, or once the process holding it has exited, if
+// that process ran on the same host.
+type LeaseManager struct {
+	Dir   string
+	TTL   time.Duration
+	Owner string // identifies the holder of the leases, default is the host name and process ID
+	mu    sync.Mutex
+}
+
+// Lease is a held slot of a named lease
+type Lease struct {
+	Name     string    `json:"name"`
+	Slot     int       `json:"slot"`
+	Owner    string    `json:"owner"`
+	Host     string    `json:"host"`
+	Pid      int       `json:"pid"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+	manager  *LeaseManager
+	path     string
+}
+
+var defaultLeaseManager struct {
+	sync.Mutex
+	manager *LeaseManager
+}
+
+// DefaultLeaseDir returns the directory used by the default lease manager, which is the value of the TEST_LEASE_DIR
+// environment variable, or `ibmcloud-terratest-wrapper-leases` in the temp directory.
+func DefaultLeaseDir() string {
+	if dir := os.Getenv(LeaseDirEnvVar); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "ibmcloud-terratest-wrapper-leases")
+}
+
+// GetDefaultLeaseManager returns the lease manager at DefaultLeaseDir shared by all tests of the process
+func GetDefaultLeaseManager() *LeaseManager {
+	defaultLeaseManager.Lock()
+	defer defaultLeaseManager.Unlock()
+	dir := DefaultLeaseDir()
+	if defaultLeaseManager.manager == nil || defaultLeaseManager.manager.Dir != dir {
+		defaultLeaseManager.manager = NewLeaseManager(dir, DefaultLeaseTTL)
+	}
+	return defaultLeaseManager.manager
+}
+
+// NewLeaseManager returns a lease manager storing its leases in dir, DefaultLeaseDir is used if dir is empty and
+// DefaultLeaseTTL if ttl is zero.
+func NewLeaseManager(dir string, ttl time.Duration) *LeaseManager {
+	if dir == "" {
+		dir = DefaultLeaseDir()
+	}
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	hostname, _ := os.Hostname()
+	return &LeaseManager{
+		Dir:   dir,
+		TTL:   ttl,
+		Owner: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// TryAcquire acquires a free slot of the named lease, which has maxHolders slots. ErrLeaseUnavailable is returned if
+// all slots are held.
+func (manager *LeaseManager) TryAcquire(name string, maxHolders int) (*Lease, error) {
+	if maxHolders < 1 {
+		maxHolders = 1
+	}
+	var lease *Lease
+	err := manager.withLock(func() error {
+		held, err := manager.liveLeases(name)
+		if err != nil {
+			return err
+		}
+		usedSlots := make(map[int]bool, len(held))
+		for _, heldLease := range held {
+			usedSlots[heldLease.Slot] = true
+		}
+		for slot := 0; slot < maxHolders; slot++ {
+			if usedSlots[slot] {
+				continue
+			}
+			lease, err = manager.writeLease(name, slot)
+			return err
+		}
+		return ErrLeaseUnavailable
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// Acquire waits until a slot of the named lease is free and acquires it, checking every pollInterval until the context
+// is done.
+func (manager *LeaseManager) Acquire(ctx context.Context, name string, maxHolders int, pollInterval time.Duration) (*Lease, error) {
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+	for {
+		lease, err := manager.TryAcquire(name, maxHolders)
+		if !errors.Is(err, ErrLeaseUnavailable) {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for lease %s: %w", name, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// HeldByOthers returns the number of slots of the named lease held by other owners
+func (manager *LeaseManager) HeldByOthers(name string) (int, error) {
+	count := 0
+	err := manager.withLock(func() error {
+		held, err := manager.liveLeases(name)
+		for _, lease := range held {
+			if lease.Owner != manager.Owner {
+				count++
+			}
+		}
+		return err
+	})
+	return count, err
+}
+
+// Held returns the number of slots of the named lease held by any owner, including the owner of the manager
+func (manager *LeaseManager) Held(name string) (int, error) {
+	count := 0
+	err := manager.withLock(func() error {
+		held, err := manager.liveLeases(name)
+		count = len(held)
+		return err
+	})
+	return count, err
+}
+
+// ReleaseOwned frees one slot of the named lease held by the owner of the manager, for leases acquired by another
+// manager of the same owner. It has no effect if the owner holds no slot.
+func (manager *LeaseManager) ReleaseOwned(name string) error {
+	return manager.withLock(func() error {
+		held, err := manager.liveLeases(name)
+		if err != nil {
+			return err
+		}
+		for _, lease := range held {
+			if lease.Owner != manager.Owner {
+				continue
+			}
+			if err := os.Remove(manager.leasePath(name, lease.Slot)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error releasing lease %s: %w", name, err)
+			}
+			return nil
+		}
+		return nil
+	})
+}
+
+// Renew extends the lease by the TTL of its manager
+func (lease *Lease) Renew() error {
+	return lease.manager.withLock(func() error {
+		current, err := readLease(lease.path)
+		if err != nil || current.Owner != lease.Owner {
+			return fmt.Errorf("lease %s is no longer held", lease.Name)
+		}
+		lease.Expires = time.Now().Add(lease.manager.TTL)
+		return writeLeaseFile(lease.path, lease)
+	})
+}
+
+// Release frees the lease. Releasing a lease that expired and was taken by another owner has no effect.
+func (lease *Lease) Release() error {
+	return lease.manager.withLock(func() error {
+		current, err := readLease(lease.path)
+		if err != nil || current.Owner != lease.Owner {
+			return nil
+		}
+		if err := os.Remove(lease.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error releasing lease %s: %w", lease.Name, err)
+		}
+		return nil
+	})
+}
+
+// liveLeases returns the leases of the name that are still held, removing expired leases and leases of exited processes.
+// It must be called with the directory lock held.
+func (manager *LeaseManager) liveLeases(name string) ([]*Lease, error) {
+	paths, err := filepath.Glob(filepath.Join(manager.Dir, leaseFileName(name)+".*"+leaseFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	hostname, _ := os.Hostname()
+
+	var live []*Lease
+	for _, path := range paths {
+		lease, readErr := readLease(path)
+		if readErr == nil && lease.Name != name {
+			continue // lease of another name with the same file name prefix
+		}
+		if readErr != nil || isStaleLease(lease, hostname) {
+			_ = os.Remove(path)
+			continue
+		}
+		live = append(live, lease)
+	}
+	return live, nil
+}
+
+// writeLease writes a lease of the manager's owner to the slot. It must be called with the directory lock held.
+func (manager *LeaseManager) writeLease(name string, slot int) (*Lease, error) {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	lease := &Lease{
+		Name:     name,
+		Slot:     slot,
+		Owner:    manager.Owner,
+		Host:     hostname,
+		Pid:      os.Getpid(),
+		Acquired: now,
+		Expires:  now.Add(manager.TTL),
+		manager:  manager,
+		path:     manager.leasePath(name, slot),
+	}
+	if err := writeLeaseFile(lease.path, lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// leasePath returns the path of the file of a slot of the named lease
+func (manager *LeaseManager) leasePath(name string, slot int) string {
+	return filepath.Join(manager.Dir, fmt.Sprintf("%s.%d%s", leaseFileName(name), slot, leaseFileSuffix))
+}
+
+// withLock runs fn holding the lock of the lease directory, shared with other processes. The lock is a file lock,
+// which the operating system releases if the process holding it crashes.
+func (manager *LeaseManager) withLock(fn func() error) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if err := os.MkdirAll(manager.Dir, 0700); err != nil {
+		return fmt.Errorf("error creating lease directory: %w", err)
+	}
+	return withFileLock(filepath.Join(manager.Dir, leaseLockFileName), fn)
+}
+
+// isStaleLease returns true if the lease expired or was held by a process of this host that has exited
+func isStaleLease(lease *Lease, hostname string) bool {
+	if time.Now().After(lease.Expires) {
+		return true
+	}
+	if lease.Host != hostname || lease.Pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(lease.Pid)
+	if err != nil {
+		return true
+	}
+	return errors.Is(process.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+func readLease(path string) (*Lease, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lease := &Lease{}
+	if err := json.Unmarshal(content, lease); err != nil {
+		return nil, fmt.Errorf("error decoding lease %s: %w", path, err)
+	}
+	return lease, nil
+}
+
+// writeLeaseFile writes the lease to a temporary file first, so that a lease file is never read half written
+func writeLeaseFile(path string, lease *Lease) error {
+	content, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("error encoding lease %s: %w", lease.Name, err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0600); err != nil {
+		return fmt.Errorf("error writing lease %s: %w", lease.Name, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("error writing lease %s: %w", lease.Name, err)
+	}
+	return nil
+}
+
+// leaseFileName returns the name as a file name, lease names may contain characters such as `/`
+func leaseFileName(name string) string {
+	return leaseNameInvalidChars.ReplaceAllString(strings.TrimSpace(name), "_")
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package common
+
+import (
+	"os"
+)
+
+// tryLockFile takes the lock of the file without waiting, and returns false if another process holds it. Windows has
+// no flock, the lock is a second file created exclusively, which is left behind if the process holding it crashes.
+func tryLockFile(file *os.File) (bool, error) {
+	heldFile, err := os.OpenFile(file.Name()+".held", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, heldFile.Close()
+}
+
+// unlockFile releases the lock taken by tryLockFile
+func unlockFile(file *os.File) {
+	_ = os.Remove(file.Name() + ".held")
+}
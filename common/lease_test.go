@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseManager(t *testing.T) {
+	dir := t.TempDir()
+	first := NewLeaseManager(dir, time.Hour)
+	first.Owner = "first"
+	second := NewLeaseManager(dir, time.Hour)
+	second.Owner = "second"
+
+	t.Run("Slots", func(t *testing.T) {
+		lease1, err := first.TryAcquire("schematics-workspaces", 2)
+		require.NoError(t, err)
+		lease2, err := second.TryAcquire("schematics-workspaces", 2)
+		require.NoError(t, err)
+		assert.NotEqual(t, lease1.Slot, lease2.Slot)
+
+		_, err = second.TryAcquire("schematics-workspaces", 2)
+		assert.ErrorIs(t, err, ErrLeaseUnavailable)
+
+		held, err := second.HeldByOthers("schematics-workspaces")
+		require.NoError(t, err)
+		assert.Equal(t, 1, held)
+
+		require.NoError(t, lease1.Release())
+		lease3, err := second.TryAcquire("schematics-workspaces", 2)
+		require.NoError(t, err)
+		assert.Equal(t, lease1.Slot, lease3.Slot)
+		require.NoError(t, lease2.Release())
+		require.NoError(t, lease3.Release())
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		short := NewLeaseManager(dir, time.Millisecond)
+		short.Owner = "short"
+		_, err := short.TryAcquire("region-us-south", 1)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		lease, err := first.TryAcquire("region-us-south", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "first", lease.Owner)
+		require.NoError(t, lease.Renew())
+		require.NoError(t, lease.Release())
+	})
+
+	t.Run("ExitedProcess", func(t *testing.T) {
+		hostname, _ := os.Hostname()
+		exited := &Lease{Name: "region-eu-de", Owner: "exited", Host: hostname, Pid: 999999999, Expires: time.Now().Add(time.Hour)}
+		require.NoError(t, writeLeaseFile(filepath.Join(dir, "region-eu-de.0.lease"), exited))
+
+		held, err := first.HeldByOthers("region-eu-de")
+		require.NoError(t, err)
+		assert.Equal(t, 0, held)
+	})
+
+	t.Run("AcquireTimeout", func(t *testing.T) {
+		lease, err := first.TryAcquire("projects", 1)
+		require.NoError(t, err)
+		defer lease.Release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = second.Acquire(ctx, "projects", 1, 5*time.Millisecond)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("LockFileLeftBehind", func(t *testing.T) {
+		// the lock file of a crashed process stays in the directory, but its lock was released
+		lockPath := filepath.Join(dir, leaseLockFileName)
+		require.NoError(t, os.WriteFile(lockPath, nil, 0600))
+
+		lease, err := first.TryAcquire("left-behind", 1)
+		require.NoError(t, err)
+		require.NoError(t, lease.Release())
+	})
+
+	t.Run("LockHeld", func(t *testing.T) {
+		lockFile, err := os.OpenFile(filepath.Join(dir, leaseLockFileName), os.O_CREATE|os.O_RDWR, 0600)
+		require.NoError(t, err)
+		defer lockFile.Close()
+		locked, err := tryLockFile(lockFile)
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		acquired := make(chan error, 1)
+		go func() {
+			lease, err := first.TryAcquire("lock-held", 1)
+			if err == nil {
+				err = lease.Release()
+			}
+			acquired <- err
+		}()
+		select {
+		case <-acquired:
+			t.Fatal("lease acquired while the directory was locked")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		unlockFile(lockFile)
+		require.NoError(t, <-acquired)
+	})
+}
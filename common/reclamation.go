@@ -0,0 +1,209 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrReclamationNotFound is returned while a destroyed instance has no reclamation yet, reclamations may appear
+// shortly after the destroy
+var ErrReclamationNotFound = errors.New("no reclamation found")
+
+// ReclamationPurgeOptions turns on the purge of the reclamations of resource instances after a successful destroy.
+// Services such as Key Protect, Object Storage or Databases keep destroyed instances in reclamation, where they
+// still block the creation of instances with the same name and count against quotas.
+type ReclamationPurgeOptions struct {
+	// Services are the CRN service names whose reclamations are purged, for example `kms`, `cloud-object-storage` or
+	// `databases-for-postgresql`. No reclamations are purged if empty.
+	Services []string
+	// CrnOutput is the name of a terraform output that holds the CRNs of the instances, as a string, list or map.
+	// If empty, the CRNs of the managed resources are collected from the terraform state before the destroy.
+	CrnOutput string
+	// RetryConfig is used for every purge, default ReclamationPurgeRetryConfig
+	RetryConfig *RetryConfig
+}
+
+// ReclamationPurger looks up and runs the reclaim action of reclamations, implemented by cloudinfo.CloudInfoService
+type ReclamationPurger interface {
+	GetReclamationIdFromCRN(CRN string) (string, error)
+	DeleteInstanceFromReclamationId(reclamationID string) error
+}
+
+// ReclamationPurgeSummary is the outcome of PurgeReclamations
+type ReclamationPurgeSummary struct {
+	Purged   []string         // CRNs of the purged instances
+	NotFound []string         // CRNs of instances that had no reclamation, for example because they were not destroyed
+	Failed   map[string]error // CRNs of instances whose purge failed
+}
+
+// String returns a summary for the test log
+func (summary *ReclamationPurgeSummary) String() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Reclamation purge: %d purged, %d without reclamation, %d failed", len(summary.Purged), len(summary.NotFound), len(summary.Failed)))
+	for _, crn := range summary.Purged {
+		builder.WriteString(fmt.Sprintf("\n  purged: %s", crn))
+	}
+	for _, crn := range summary.NotFound {
+		builder.WriteString(fmt.Sprintf("\n  no reclamation: %s", crn))
+	}
+	failedCRNs := make([]string, 0, len(summary.Failed))
+	for crn := range summary.Failed {
+		failedCRNs = append(failedCRNs, crn)
+	}
+	sort.Strings(failedCRNs)
+	for _, crn := range failedCRNs {
+		builder.WriteString(fmt.Sprintf("\n  failed: %s: %s", crn, summary.Failed[crn]))
+	}
+	return builder.String()
+}
+
+// ReclamationPurgeRetryConfig returns a retry configuration for reclamation purges. It retries for a few minutes, as
+// the reclamation of an instance may only appear some time after its destroy.
+func ReclamationPurgeRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:            6,
+		InitialDelay:          5 * time.Second,
+		MaxDelay:              60 * time.Second,
+		Strategy:              ExponentialBackoff,
+		Jitter:                true,
+		RetryableErrorChecker: IsRetryableError,
+		Logger:                nil,
+		OperationName:         "reclamation purge",
+	}
+}
+
+// PurgeReclamations runs the reclaim action of the reclamation of every CRN, retrying until the reclamation exists.
+// Errors do not stop the purge of the remaining CRNs, they are reported in the summary.
+func PurgeReclamations(purger ReclamationPurger, crns []string, config RetryConfig) *ReclamationPurgeSummary {
+	summary := &ReclamationPurgeSummary{Failed: make(map[string]error)}
+	baseOperationName := config.OperationName
+	for _, crn := range crns {
+		config.OperationName = fmt.Sprintf("%s of %s", baseOperationName, crn)
+		_, err := RetryWithConfig(config, func() (string, error) {
+			reclamationID, lookupErr := purger.GetReclamationIdFromCRN(crn)
+			if lookupErr != nil {
+				return "", lookupErr
+			}
+			if reclamationID == "" {
+				return "", ErrReclamationNotFound
+			}
+			return reclamationID, purger.DeleteInstanceFromReclamationId(reclamationID)
+		})
+		switch {
+		case err == nil:
+			summary.Purged = append(summary.Purged, crn)
+		case errors.Is(err, ErrReclamationNotFound):
+			summary.NotFound = append(summary.NotFound, crn)
+		default:
+			summary.Failed[crn] = err
+		}
+	}
+	return summary
+}
+
+// FilterInstanceCRNsByService returns the sorted, unique resource instance CRNs of the services. CRNs of resources
+// within an instance, for example a key or a bucket, are converted to the CRN of their instance.
+func FilterInstanceCRNsByService(crns []string, services []string) []string {
+	unique := make(map[string]bool)
+	for _, crn := range crns {
+		// crn:v1:<cname>:<ctype>:<service-name>:<location>:<scope>:<service-instance>:<resource-type>:<resource>
+		parts := strings.Split(crn, ":")
+		if len(parts) < 8 || parts[0] != "crn" || parts[7] == "" {
+			continue
+		}
+		if !StrArrayContains(services, parts[4]) {
+			continue
+		}
+		instanceCRN := strings.Join(parts[:8], ":") + "::"
+		unique[instanceCRN] = true
+	}
+	instanceCRNs := make([]string, 0, len(unique))
+	for crn := range unique {
+		instanceCRNs = append(instanceCRNs, crn)
+	}
+	sort.Strings(instanceCRNs)
+	return instanceCRNs
+}
+
+// CollectCRNsFromValue returns all CRN strings in a terraform output value, which may be a string, list or map
+func CollectCRNsFromValue(value interface{}) []string {
+	var crns []string
+	switch typed := value.(type) {
+	case string:
+		if strings.HasPrefix(typed, "crn:") {
+			crns = append(crns, typed)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			crns = append(crns, CollectCRNsFromValue(item)...)
+		}
+	case map[string]interface{}:
+		for _, item := range typed {
+			crns = append(crns, CollectCRNsFromValue(item)...)
+		}
+	}
+	return crns
+}
+
+// CollectCRNsFromState returns the `crn` and `id` attributes of the managed resources of a terraform state that are
+// CRNs. Both the state file format and the `terraform show -json` format are supported. Data sources are ignored, as
+// are CRNs of other resources that a resource refers to.
+func CollectCRNsFromState(stateJSON string) ([]string, error) {
+	var state interface{}
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return nil, fmt.Errorf("error parsing terraform state: %w", err)
+	}
+	return collectStateCRNs(state), nil
+}
+
+// collectStateCRNs walks the state and collects the CRNs of every managed resource
+func collectStateCRNs(node interface{}) []string {
+	var crns []string
+	switch typed := node.(type) {
+	case []interface{}:
+		for _, item := range typed {
+			crns = append(crns, collectStateCRNs(item)...)
+		}
+	case map[string]interface{}:
+		mode, isResource := typed["mode"].(string)
+		if !isResource {
+			for _, item := range typed {
+				crns = append(crns, collectStateCRNs(item)...)
+			}
+			return crns
+		}
+		if mode != "managed" {
+			return crns
+		}
+		// `terraform show -json` format
+		if values, ok := typed["values"].(map[string]interface{}); ok {
+			crns = append(crns, resourceCRNs(values)...)
+		}
+		// state file format
+		if instances, ok := typed["instances"].([]interface{}); ok {
+			for _, instance := range instances {
+				if instanceMap, ok := instance.(map[string]interface{}); ok {
+					if attributes, ok := instanceMap["attributes"].(map[string]interface{}); ok {
+						crns = append(crns, resourceCRNs(attributes)...)
+					}
+				}
+			}
+		}
+	}
+	return crns
+}
+
+// resourceCRNs returns the attributes of a resource that identify it by CRN
+func resourceCRNs(attributes map[string]interface{}) []string {
+	var crns []string
+	for _, name := range []string{"crn", "id"} {
+		if value, ok := attributes[name].(string); ok && strings.HasPrefix(value, "crn:") {
+			crns = append(crns, value)
+		}
+	}
+	return crns
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKmsCRN = "crn:v1:bluemix:public:kms:us-south:a/account:kms-guid::"
+	testCosCRN = "crn:v1:bluemix:public:cloud-object-storage:global:a/account:cos-guid::"
+)
+
+type fakeReclamationPurger struct {
+	reclamations map[string]string // CRN to reclamation ID
+	purged       []string
+	lookupErr    error
+}
+
+func (f *fakeReclamationPurger) GetReclamationIdFromCRN(crn string) (string, error) {
+	if f.lookupErr != nil {
+		return "", f.lookupErr
+	}
+	return f.reclamations[crn], nil
+}
+
+func (f *fakeReclamationPurger) DeleteInstanceFromReclamationId(reclamationID string) error {
+	f.purged = append(f.purged, reclamationID)
+	return nil
+}
+
+func TestCollectCRNsFromState(t *testing.T) {
+	t.Run("StateFile", func(t *testing.T) {
+		state := `{"version":4,"resources":[
+			{"mode":"managed","type":"ibm_resource_instance","instances":[{"attributes":{"id":"` + testCosCRN + `","crn":"` + testCosCRN + `"}}]},
+			{"mode":"managed","type":"ibm_cos_bucket","instances":[{"attributes":{"id":"bucket","crn":"crn:v1:bluemix:public:cloud-object-storage:global:a/account:cos-guid:bucket:b1","kms_key_crn":"crn:v1:bluemix:public:kms:us-south:a/account:other-guid:key:k1"}}]},
+			{"mode":"data","type":"ibm_resource_instance","instances":[{"attributes":{"crn":"` + testKmsCRN + `"}}]}
+		]}`
+		crns, err := CollectCRNsFromState(state)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{testCosCRN, testCosCRN, "crn:v1:bluemix:public:cloud-object-storage:global:a/account:cos-guid:bucket:b1"}, crns)
+	})
+
+	t.Run("ShowJson", func(t *testing.T) {
+		state := `{"format_version":"1.0","values":{"root_module":{"child_modules":[{"resources":[
+			{"mode":"managed","type":"ibm_kms_instance","values":{"crn":"` + testKmsCRN + `","id":"kms-guid"}},
+			{"mode":"data","type":"ibm_resource_instance","values":{"crn":"` + testCosCRN + `"}}
+		]}]}}}`
+		crns, err := CollectCRNsFromState(state)
+		require.NoError(t, err)
+		assert.Equal(t, []string{testKmsCRN}, crns)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := CollectCRNsFromState("not json")
+		assert.Error(t, err)
+	})
+}
+
+func TestCollectCRNsFromValue(t *testing.T) {
+	value := map[string]interface{}{
+		"kms": testKmsCRN,
+		"cos": []interface{}{testCosCRN, "not-a-crn", 3},
+	}
+	assert.ElementsMatch(t, []string{testKmsCRN, testCosCRN}, CollectCRNsFromValue(value))
+}
+
+func TestFilterInstanceCRNsByService(t *testing.T) {
+	crns := []string{
+		testCosCRN,
+		"crn:v1:bluemix:public:cloud-object-storage:global:a/account:cos-guid:bucket:b1",
+		testKmsCRN,
+		"crn:v1:bluemix:public:databases-for-postgresql:us-south:a/account:pg-guid::",
+		"crn:v1:bluemix:public:is:us-south:a/account::vpc:r006-1",
+		"not-a-crn",
+	}
+	assert.Equal(t, []string{testCosCRN, testKmsCRN}, FilterInstanceCRNsByService(crns, []string{"kms", "cloud-object-storage", "is"}))
+	assert.Empty(t, FilterInstanceCRNsByService(crns, nil))
+}
+
+func TestPurgeReclamations(t *testing.T) {
+	t.Setenv("SKIP_RETRY_DELAYS", "true")
+	config := ReclamationPurgeRetryConfig()
+	config.MaxRetries = 2
+
+	t.Run("PurgedAndNotFound", func(t *testing.T) {
+		purger := &fakeReclamationPurger{reclamations: map[string]string{testKmsCRN: "reclamation-1"}}
+		summary := PurgeReclamations(purger, []string{testKmsCRN, testCosCRN}, config)
+		assert.Equal(t, []string{testKmsCRN}, summary.Purged)
+		assert.Equal(t, []string{testCosCRN}, summary.NotFound)
+		assert.Empty(t, summary.Failed)
+		assert.Equal(t, []string{"reclamation-1"}, purger.purged)
+		assert.Contains(t, summary.String(), "1 purged, 1 without reclamation, 0 failed")
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		purger := &fakeReclamationPurger{lookupErr: errors.New("403 forbidden")}
+		summary := PurgeReclamations(purger, []string{testKmsCRN}, config)
+		assert.Empty(t, summary.Purged)
+		require.Contains(t, summary.Failed, testKmsCRN)
+		assert.Contains(t, summary.String(), "failed: "+testKmsCRN)
+	})
+}
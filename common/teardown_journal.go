@@ -0,0 +1,305 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TeardownJournalPathEnvVar is the environment variable that overrides the location of the teardown journal
+const TeardownJournalPathEnvVar = "TEARDOWN_JOURNAL_PATH"
+
+// Types of teardown journal entries, one for each kind of test runner
+const (
+	TeardownJournalTerraform  = "terraform"
+	TeardownJournalSchematics = "schematics"
+	TeardownJournalProject    = "project"
+)
+
+// TeardownJournalEntry records what a test created, so that teardown can be finished after the test process died.
+// A test appends an entry when it starts creating resources, may append more entries with the same ID as it creates
+// more, and appends a final entry with Complete set once its teardown has finished.
+type TeardownJournalEntry struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type,omitempty"`
+	Time     time.Time `json:"time"`
+	TestName string    `json:"test_name,omitempty"`
+	Prefix   string    `json:"prefix,omitempty"`
+
+	// terraform tests (testhelper)
+	TerraformDir    string `json:"terraform_dir,omitempty"`    // directory the terraform commands were run in
+	TerraformBinary string `json:"terraform_binary,omitempty"` // terraform (or tofu) binary, default `terraform`
+	WorkspaceName   string `json:"workspace_name,omitempty"`   // terraform workspace, if a workspace was used
+	WorkspacePath   string `json:"workspace_path,omitempty"`   // location of the state file
+	VarFile         string `json:"var_file,omitempty"`         // terraform variables of the test, see WriteVarFile
+
+	// schematics tests (testschematic)
+	SchematicsWorkspaceID       string `json:"schematics_workspace_id,omitempty"`
+	SchematicsWorkspaceLocation string `json:"schematics_workspace_location,omitempty"`
+
+	// projects and addon tests (testprojects, testaddons)
+	ProjectID string `json:"project_id,omitempty"`
+	CatalogID string `json:"catalog_id,omitempty"` // only set if the test deletes the catalog in its teardown
+
+	ResourceGroup string `json:"resource_group,omitempty"`
+	Complete      bool   `json:"complete,omitempty"`
+}
+
+// TeardownJournal is a local file of JSON lines with one TeardownJournalEntry per line. Entries are appended while
+// holding a file lock, so that concurrent tests can share a journal, and entries with the same ID are merged when the
+// journal is read. Complete entries are removed by Compact.
+type TeardownJournal struct {
+	Path string
+	mu   sync.Mutex
+}
+
+var defaultTeardownJournal struct {
+	sync.Mutex
+	journal *TeardownJournal
+}
+
+// DefaultTeardownJournalPath returns the location of the journal used by the test runners, which is the value of the
+// TEARDOWN_JOURNAL_PATH environment variable, or `ibmcloud-terratest-wrapper-teardown.jsonl` in the temp directory.
+func DefaultTeardownJournalPath() string {
+	if path := os.Getenv(TeardownJournalPathEnvVar); path != "" {
+		return path
+	}
+	return filepath.Join(os.TempDir(), "ibmcloud-terratest-wrapper-teardown.jsonl")
+}
+
+// GetDefaultTeardownJournal returns the journal at DefaultTeardownJournalPath shared by all test runners of the process.
+// The complete entries of earlier runs are removed when the journal is first opened, so that it does not keep growing.
+func GetDefaultTeardownJournal() *TeardownJournal {
+	defaultTeardownJournal.Lock()
+	defer defaultTeardownJournal.Unlock()
+	path := DefaultTeardownJournalPath()
+	if defaultTeardownJournal.journal == nil || defaultTeardownJournal.journal.Path != path {
+		defaultTeardownJournal.journal = NewTeardownJournal(path)
+		if err := defaultTeardownJournal.journal.Compact(); err != nil {
+			log.Printf("WARNING: could not compact teardown journal: %s", err)
+		}
+	}
+	return defaultTeardownJournal.journal
+}
+
+// NewTeardownJournal returns a journal stored at path, DefaultTeardownJournalPath is used if path is empty
+func NewTeardownJournal(path string) *TeardownJournal {
+	if path == "" {
+		path = DefaultTeardownJournalPath()
+	}
+	return &TeardownJournal{Path: path}
+}
+
+// NewTeardownJournalID returns a unique ID for a journal entry
+func NewTeardownJournalID(prefix string) string {
+	return fmt.Sprintf("%s-%d-%s", prefix, time.Now().UnixNano(), UniqueId())
+}
+
+// Record appends an entry to the journal. Only the fields that are set are stored, when the journal is read they are
+// merged into the earlier entries with the same ID.
+func (journal *TeardownJournal) Record(entry TeardownJournalEntry) error {
+	if entry.ID == "" {
+		return fmt.Errorf("teardown journal entry has no ID")
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding teardown journal entry: %w", err)
+	}
+
+	return journal.withLock(func() error {
+		file, err := os.OpenFile(journal.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("error opening teardown journal %s: %w", journal.Path, err)
+		}
+		defer file.Close()
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("error writing teardown journal %s: %w", journal.Path, err)
+		}
+		return nil
+	})
+}
+
+// Complete marks the entry with the given ID as complete, it is then no longer returned by Pending, and removes the
+// variable file of the entry as the variables are no longer needed
+func (journal *TeardownJournal) Complete(id string) error {
+	if err := journal.Record(TeardownJournalEntry{ID: id, Complete: true}); err != nil {
+		return err
+	}
+	if err := os.Remove(journal.varFilePath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing teardown journal variables: %w", err)
+	}
+	return nil
+}
+
+// WriteVarFile stores terraform variables for a journal entry in a `.tfvars.json` file next to the journal, readable only
+// by the current user, and returns its path. The file is removed when the entry is completed.
+func (journal *TeardownJournal) WriteVarFile(id string, vars map[string]interface{}) (string, error) {
+	content, err := json.Marshal(vars)
+	if err != nil {
+		return "", fmt.Errorf("error encoding terraform variables for teardown journal: %w", err)
+	}
+	if err := os.MkdirAll(journal.varFileDir(), 0700); err != nil {
+		return "", fmt.Errorf("error creating teardown journal variable directory: %w", err)
+	}
+	path := journal.varFilePath(id)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("error writing teardown journal variables: %w", err)
+	}
+	// the permissions of an existing file are not changed by OpenFile, the variables may contain secrets
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return "", fmt.Errorf("error writing teardown journal variables: %w", err)
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("error writing teardown journal variables: %w", err)
+	}
+	return path, nil
+}
+
+// Read returns the merged entries of the journal in the order they were first recorded.
+// A journal that does not exist has no entries.
+func (journal *TeardownJournal) Read() ([]TeardownJournalEntry, error) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	return journal.read()
+}
+
+// Pending returns the entries of the journal that are not complete
+func (journal *TeardownJournal) Pending() ([]TeardownJournalEntry, error) {
+	entries, err := journal.Read()
+	if err != nil {
+		return nil, err
+	}
+	var pending []TeardownJournalEntry
+	for _, entry := range entries {
+		if !entry.Complete {
+			pending = append(pending, entry)
+		}
+	}
+	return pending, nil
+}
+
+// Compact rewrites the journal with only the pending entries, removing the variable files of complete entries.
+// The journal is not rewritten if it has no complete entries.
+func (journal *TeardownJournal) Compact() error {
+	return journal.withLock(func() error {
+		entries, err := journal.read()
+		if err != nil {
+			return err
+		}
+		hasComplete := false
+		for _, entry := range entries {
+			hasComplete = hasComplete || entry.Complete
+		}
+		if !hasComplete {
+			return nil
+		}
+
+		tempPath := journal.Path + ".tmp"
+		file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("error compacting teardown journal %s: %w", journal.Path, err)
+		}
+		for _, entry := range entries {
+			if entry.Complete {
+				if entry.VarFile != "" {
+					_ = os.Remove(entry.VarFile)
+				}
+				continue
+			}
+			line, err := json.Marshal(entry)
+			if err == nil {
+				_, err = file.Write(append(line, '\n'))
+			}
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("error compacting teardown journal %s: %w", journal.Path, err)
+			}
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("error compacting teardown journal %s: %w", journal.Path, err)
+		}
+		return os.Rename(tempPath, journal.Path)
+	})
+}
+
+// withLock runs fn holding the lock of the journal, shared with the other processes writing to it
+func (journal *TeardownJournal) withLock(fn func() error) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(journal.Path), 0700); err != nil {
+		return fmt.Errorf("error creating teardown journal directory: %w", err)
+	}
+	return withFileLock(journal.Path+".lock", fn)
+}
+
+// read parses the journal, the caller must hold the lock
+func (journal *TeardownJournal) read() ([]TeardownJournalEntry, error) {
+	file, err := os.Open(journal.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening teardown journal %s: %w", journal.Path, err)
+	}
+	defer file.Close()
+
+	var ids []string
+	entries := make(map[string]*TeardownJournalEntry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record TeardownJournalEntry
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("error parsing teardown journal %s line %d: %w", journal.Path, lineNumber, err)
+		}
+		entry, exists := entries[record.ID]
+		if !exists {
+			entry = &TeardownJournalEntry{}
+			entries[record.ID] = entry
+			ids = append(ids, record.ID)
+		}
+		// decoding again into the existing entry only overwrites the fields that were set in this record
+		if err := json.Unmarshal(line, entry); err != nil {
+			return nil, fmt.Errorf("error parsing teardown journal %s line %d: %w", journal.Path, lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading teardown journal %s: %w", journal.Path, err)
+	}
+
+	result := make([]TeardownJournalEntry, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, *entries[id])
+	}
+	return result, nil
+}
+
+// varFileDir returns the directory for the variable files of the journal
+func (journal *TeardownJournal) varFileDir() string {
+	return journal.Path + ".vars"
+}
+
+// varFilePath returns the location of the variable file of an entry
+func (journal *TeardownJournal) varFilePath(id string) string {
+	return filepath.Join(journal.varFileDir(), id+".tfvars.json")
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeardownJournalMergesEntries(t *testing.T) {
+	journal := NewTeardownJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+
+	entries, err := journal.Read()
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a missing journal has no entries")
+
+	require.NoError(t, journal.Record(TeardownJournalEntry{ID: "a", Type: TeardownJournalTerraform, TerraformDir: "/tmp/base"}))
+	require.NoError(t, journal.Record(TeardownJournalEntry{ID: "b", Type: TeardownJournalSchematics, SchematicsWorkspaceID: "ws-1", SchematicsWorkspaceLocation: "us"}))
+	require.NoError(t, journal.Record(TeardownJournalEntry{ID: "a", TerraformDir: "/tmp/upgrade"}))
+	require.NoError(t, journal.Complete("b"))
+	assert.Error(t, journal.Record(TeardownJournalEntry{}), "entries must have an ID")
+
+	entries, err = journal.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].ID)
+	assert.Equal(t, TeardownJournalTerraform, entries[0].Type, "fields not set in later records are kept")
+	assert.Equal(t, "/tmp/upgrade", entries[0].TerraformDir)
+	assert.True(t, entries[1].Complete)
+	assert.Equal(t, "ws-1", entries[1].SchematicsWorkspaceID)
+
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "a", pending[0].ID)
+}
+
+func TestTeardownJournalCompact(t *testing.T) {
+	journal := NewTeardownJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+
+	varFile, err := journal.WriteVarFile("done", map[string]interface{}{"prefix": "test"})
+	require.NoError(t, err)
+	content, err := os.ReadFile(varFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"prefix": "test"}`, string(content))
+	info, err := os.Stat(varFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, journal.Record(TeardownJournalEntry{ID: "done", Type: TeardownJournalTerraform, VarFile: varFile}))
+	require.NoError(t, journal.Record(TeardownJournalEntry{ID: "pending", Type: TeardownJournalProject, ProjectID: "p-1"}))
+	require.NoError(t, journal.Complete("done"))
+	assert.NoFileExists(t, varFile, "the variables are removed when the entry is complete")
+
+	require.NoError(t, journal.Compact())
+
+	entries, err := journal.Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "p-1", entries[0].ProjectID)
+	assert.NoFileExists(t, varFile)
+}
+
+func TestTeardownJournalCompactWhileRecording(t *testing.T) {
+	journal := NewTeardownJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	require.NoError(t, journal.Record(TeardownJournalEntry{ID: "done", Type: TeardownJournalTerraform}))
+	require.NoError(t, journal.Complete("done"))
+
+	// entries recorded by another process, which has its own journal value, must not be lost by the compaction
+	other := NewTeardownJournal(journal.Path)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, other.Record(TeardownJournalEntry{ID: fmt.Sprintf("pending-%d", i), Type: TeardownJournalTerraform}))
+		}(i)
+	}
+	require.NoError(t, journal.Compact())
+	wg.Wait()
+
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, 10)
+}
+
+func TestGetDefaultTeardownJournalPrunesCompleteEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	previous := NewTeardownJournal(path)
+	require.NoError(t, previous.Record(TeardownJournalEntry{ID: "done", Type: TeardownJournalTerraform}))
+	require.NoError(t, previous.Record(TeardownJournalEntry{ID: "pending", Type: TeardownJournalTerraform}))
+	require.NoError(t, previous.Complete("done"))
+
+	t.Setenv(TeardownJournalPathEnvVar, path)
+	entries, err := GetDefaultTeardownJournal().Read()
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "complete entries of earlier runs are removed when the journal is opened")
+	assert.Equal(t, "pending", entries[0].ID)
+}
+
+func TestDefaultTeardownJournalPath(t *testing.T) {
+	t.Setenv(TeardownJournalPathEnvVar, "/tmp/custom-journal.jsonl")
+	assert.Equal(t, "/tmp/custom-journal.jsonl", DefaultTeardownJournalPath())
+	assert.Equal(t, "/tmp/custom-journal.jsonl", GetDefaultTeardownJournal().Path)
+
+	t.Setenv(TeardownJournalPathEnvVar, "")
+	assert.Equal(t, filepath.Join(os.TempDir(), "ibmcloud-terratest-wrapper-teardown.jsonl"), DefaultTeardownJournalPath())
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"fmt"
 	"regexp"
 	"sort"
 	"strconv"
@@ -273,3 +274,67 @@ func GetLatestVersionByConstraint(versions []string, target string) string {
 
 	return ""
 }
+
+// ResolveVersions resolves a list of requested versions against the available versions (for example the tags of a
+// repository) and returns the matching available versions, de-duplicated and sorted from oldest to newest.
+//
+// Each requested version can be an exact version or tag (e.g., "v1.2.3") or any constraint supported by
+// GetLatestVersionByConstraint (e.g., "^3.0.0" or ">=3.0.0"), in which case the latest matching version is used.
+//
+// Returns an error if a requested version can not be resolved.
+func ResolveVersions(availableVersions []string, requested []string) ([]string, error) {
+	resolved := []string{}
+	seen := make(map[string]bool)
+
+	for _, request := range requested {
+		version := ""
+		for _, available := range availableVersions {
+			if available == request {
+				version = available
+				break
+			}
+		}
+		if version == "" {
+			version = GetLatestVersionByConstraint(availableVersions, request)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("no version found matching %q", request)
+		}
+		if !seen[version] {
+			seen[version] = true
+			resolved = append(resolved, version)
+		}
+	}
+
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return compareSemver(resolved[i], resolved[j]) < 0
+	})
+
+	return resolved, nil
+}
+
+// compareSemver compares two versions with an optional "v" prefix, returning -1, 0 or 1.
+// Invalid versions are considered older than valid versions.
+func compareSemver(a string, b string) int {
+	aMajor, aMinor, aPatch, aValid := parseSemver(strings.TrimPrefix(a, "v"))
+	bMajor, bMinor, bPatch, bValid := parseSemver(strings.TrimPrefix(b, "v"))
+	if !aValid || !bValid {
+		switch {
+		case aValid == bValid:
+			return 0
+		case !aValid:
+			return -1
+		default:
+			return 1
+		}
+	}
+	for _, diff := range []int{aMajor - bMajor, aMinor - bMinor, aPatch - bPatch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
@@ -70,3 +70,24 @@ func TestMatchVersion(t *testing.T) {
 		assert.Equal(t, tt.expected, result, tt.description)
 	}
 }
+
+func TestResolveVersions(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.2.0", "v2.0.0", "v2.3.1", "v3.0.0", "v3.1.0", "latest"}
+
+	t.Run("ExplicitAndConstraints", func(t *testing.T) {
+		resolved, err := ResolveVersions(tags, []string{"^3.0.0", "v1.2.0", "~2.3.0"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v1.2.0", "v2.3.1", "v3.1.0"}, resolved)
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		resolved, err := ResolveVersions(tags, []string{">=3.0.0", "v3.1.0"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v3.1.0"}, resolved)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := ResolveVersions(tags, []string{"^4.0.0"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,17 @@
+package testaddons
+
+import (
+	"context"
+)
+
+// startRunContext creates the context of a test run from Context, which is cancelled by the returned function.
+// The deploy of the addon stops waiting once the run context is cancelled.
+func (options *TestAddonOptions) startRunContext() context.CancelFunc {
+	parent := options.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	var cancel context.CancelFunc
+	options.runContext, cancel = context.WithCancel(parent)
+	return cancel
+}
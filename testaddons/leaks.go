@@ -0,0 +1,46 @@
+package testaddons
+
+import (
+	"fmt"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+)
+
+// startLeakDetection takes the snapshot of the resources of the test resource group and the VPCs of the default region
+// before the test creates any, if CheckForLeaks is set. Errors are logged and disable the leak check, they do not fail
+// the test.
+func (options *TestAddonOptions) startLeakDetection() {
+	if !options.CheckForLeaks || options.SkipTestTearDown {
+		return
+	}
+
+	snapshotOptions := testhelper.GetLeakCheckOptions(options.LeakCheckOptions, options.ResourceGroup, defaultRegion, options.Prefix)
+	detector, err := testhelper.StartLeakCheck(options.CloudInfoService, options.Prefix, snapshotOptions)
+	if err != nil {
+		options.Logger.ShortWarn(fmt.Sprintf("Skipping leak check: %s", err))
+		return
+	}
+	options.leakDetector = detector
+}
+
+// checkForLeaks fails the test for every resource created since startLeakDetection whose name or tags contain the
+// test prefix. It is only run if the test resources were undeployed.
+func (options *TestAddonOptions) checkForLeaks() {
+	detector := options.leakDetector
+	options.leakDetector = nil
+	if detector == nil {
+		return
+	}
+
+	options.Logger.ShortInfo("Checking for leaked resources")
+	leaked, err := testhelper.FinishLeakCheck(options.Testing, detector)
+	if err != nil {
+		options.Logger.ShortWarn(fmt.Sprintf("Leak check failed: %s", err))
+	}
+	if len(leaked) > 0 {
+		options.Logger.ShortError(cloudinfo.FormatLeakedResources(leaked))
+	} else if err == nil {
+		options.Logger.ShortInfo("No leaked resources found")
+	}
+}
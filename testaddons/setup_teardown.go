@@ -144,6 +144,9 @@ func (options *TestAddonOptions) testSetup() error {
 		options.CloudInfoService = cloudInfoSvc
 	}
 
+	// snapshot the account resources before the catalog and project are created
+	options.startLeakDetection()
+
 	// get current branch and repo url and validate branch exists for offering import
 	// Use the cloudinfo helper to prepare offering import (validates branch exists)
 	branchUrl, repo, branch, err := options.CloudInfoService.PrepareOfferingImport()
@@ -183,6 +186,9 @@ func (options *TestAddonOptions) testSetup() error {
 		return err
 	}
 
+	if err := options.acquireProjectLease(); err != nil {
+		return err
+	}
 	project, projectConfig, err := cloudinfo.SetupProject(cloudinfo.SetupProjectOptions{
 		CurrentProject:           options.currentProject,
 		CurrentProjectConfig:     options.currentProjectConfig,
@@ -202,10 +208,12 @@ func (options *TestAddonOptions) testSetup() error {
 		Testing:                  options.Testing,
 	})
 	if err != nil {
+		options.releaseProjectLease()
 		return err
 	}
 	options.currentProject = project
 	options.currentProjectConfig = projectConfig
+	options.recordTeardownJournal()
 
 	return nil
 }
@@ -373,11 +381,16 @@ func (options *TestAddonOptions) testTearDown() {
 	}
 
 	if options.executeResourceTearDown() {
+		// the leak check runs once the project is deleted, which is also a resource instance
+		defer options.checkForLeaks()
+
 		err := options.RunPreUndeployHook()
 		if err != nil {
 			options.Logger.ShortWarn(fmt.Sprintf("Pre Undeploy hook failed: %s", err))
 		}
 
+		options.runUnlockers()
+
 		err = options.Undeploy()
 		if err != nil {
 			options.Logger.ShortWarn(fmt.Sprintf("Undeploy resources failed: %s", err))
@@ -388,7 +401,10 @@ func (options *TestAddonOptions) testTearDown() {
 		}
 	}
 
-	if options.executeProjectTearDown() {
+	// the teardown journal entry is only completed if the project and catalog are both removed
+	executeProjectTearDown := options.executeProjectTearDown()
+	teardownComplete := executeProjectTearDown
+	if executeProjectTearDown {
 		// Project cleanup logic: always clean up projects since we're not sharing them
 		if options.currentProject != nil && options.currentProject.ID != nil {
 			options.Logger.ShortInfo(fmt.Sprintf("Deleting the project %s with ID %s", options.ProjectName, *options.currentProject.ID))
@@ -436,9 +452,11 @@ func (options *TestAddonOptions) testTearDown() {
 
 			if assert.NoError(options.Testing, err) {
 				options.Logger.ShortInfo(fmt.Sprintf("Deleted Test Project: %s", options.currentProjectConfig.ProjectName))
+				options.releaseProjectLease()
 			} else {
 				errorMsg := fmt.Sprintf("Project deletion failed: %v", err)
 				options.lastTeardownErrors = append(options.lastTeardownErrors, errorMsg)
+				teardownComplete = false
 				projectURL := fmt.Sprintf("https://cloud.ibm.com/projects/%s/configurations", *options.currentProject.ID)
 				options.Logger.ShortWarn(fmt.Sprintf("Error deleting Test Project: %s\nProject Console: %s", err, projectURL))
 			}
@@ -456,6 +474,7 @@ func (options *TestAddonOptions) testTearDown() {
 		if err != nil {
 			errorMsg := fmt.Sprintf("Catalog deletion failed: %v", err)
 			options.lastTeardownErrors = append(options.lastTeardownErrors, errorMsg)
+			teardownComplete = false
 			options.Logger.ErrorWithContext(fmt.Sprintf("Error deleting the catalog: %v", err))
 			options.Testing.Fail()
 		} else {
@@ -468,4 +487,8 @@ func (options *TestAddonOptions) testTearDown() {
 			options.Logger.ShortInfo("No catalog to delete")
 		}
 	}
+
+	if teardownComplete {
+		options.completeTeardownJournal()
+	}
 }
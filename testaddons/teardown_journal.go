@@ -0,0 +1,46 @@
+package testaddons
+
+import (
+	"fmt"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// recordTeardownJournal records the test project, and the catalog if it is not shared, in the teardown journal once
+// they are created, so that they can still be removed with the resume-cleanup command if the test process dies.
+// Nothing is recorded if SkipTestTearDown is set, journal errors are logged and do not fail the test.
+func (options *TestAddonOptions) recordTeardownJournal() {
+	if options.SkipTestTearDown || options.currentProject == nil || options.currentProject.ID == nil {
+		return
+	}
+
+	options.teardownJournalID = common.NewTeardownJournalID(options.Prefix)
+	entry := common.TeardownJournalEntry{
+		ID:            options.teardownJournalID,
+		Type:          common.TeardownJournalProject,
+		Prefix:        options.Prefix,
+		ProjectID:     *options.currentProject.ID,
+		ResourceGroup: options.ResourceGroup,
+	}
+	// a shared catalog is kept for other tests and is not removed in teardown
+	if options.catalog != nil && options.catalog.ID != nil && (options.SharedCatalog == nil || !*options.SharedCatalog) {
+		entry.CatalogID = *options.catalog.ID
+	}
+	if options.Testing != nil {
+		entry.TestName = options.Testing.Name()
+	}
+	if err := common.GetDefaultTeardownJournal().Record(entry); err != nil {
+		options.Logger.ShortWarn(fmt.Sprintf("Failed to record project in the teardown journal: %s", err))
+	}
+}
+
+// completeTeardownJournal marks the journal entry of the test project and catalog as complete
+func (options *TestAddonOptions) completeTeardownJournal() {
+	if options.teardownJournalID == "" {
+		return
+	}
+	if err := common.GetDefaultTeardownJournal().Complete(options.teardownJournalID); err != nil {
+		options.Logger.ShortWarn(fmt.Sprintf("Failed to complete project in the teardown journal: %s", err))
+	}
+	options.teardownJournalID = ""
+}
@@ -168,6 +168,12 @@ func (options *TestAddonOptions) runAddonTest(enhancedReporting bool) error {
 		}
 	}()
 
+	// on SIGINT/SIGTERM stop waiting for the deploy and undeploy/delete the project, the teardown is only run once
+	cancelRun := options.startRunContext()
+	defer cancelRun()
+	interruptTeardown := common.RegisterInterruptTeardown(options.Testing, fmt.Sprintf("addon test %s", options.Prefix), cancelRun, options.TestTearDown)
+	defer interruptTeardown.Unregister()
+
 	if !options.SkipTestTearDown {
 		// ensure we always run the test tear down, even if a panic occurs
 		defer func() {
@@ -198,7 +204,7 @@ func (options *TestAddonOptions) runAddonTest(enhancedReporting bool) error {
 
 				options.Testing.Fail()
 			}
-			options.TestTearDown()
+			interruptTeardown.Run()
 		}()
 	}
 
@@ -389,6 +395,8 @@ func (options *TestAddonOptions) runAddonTest(enhancedReporting bool) error {
 		Logger:               options.Logger.GetUnderlyingLogger(),
 		Testing:              options.Testing,
 		DeployTimeoutMinutes: options.DeployTimeoutMinutes,
+		Context:              options.runContext,
+		TeardownReserve:      options.TeardownReserve,
 		StackPollTimeSeconds: 60,
 	}
 
@@ -1583,7 +1591,10 @@ func (options *TestAddonOptions) TriggerUnDeploy() []error {
 	}
 	if !options.SkipUndeploy {
 		readyForUndeploy := false
-		timeoutEndTime := time.Now().Add(time.Duration(options.DeployTimeoutMinutes) * time.Minute)
+		// undeploy is bounded by the go test deadline only, so it still runs after the deploy was cancelled
+		teardownCtx, cancelTeardown := common.NewTeardownContext(options.Testing, options.Context)
+		defer cancelTeardown()
+		timeoutEndTime := time.Now().Add(common.BoundTimeout(teardownCtx, time.Duration(options.DeployTimeoutMinutes)*time.Minute))
 
 		// while not ready for undeploy and timeout not reached, keep checking
 		for !readyForUndeploy && time.Now().Before(timeoutEndTime) {
@@ -0,0 +1,55 @@
+package testhelper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+const (
+	// projectLeaseName is the lease that caps the projects of concurrent project and addon tests
+	projectLeaseName         = "projects"
+	projectLeasePollInterval = 30 * time.Second
+)
+
+// AcquireProjectLease waits for one of the maxConcurrentProjects slots shared by the test processes, before a project
+// is created, and returns the slot to free with ReleaseProjectLease. No slot is needed if maxConcurrentProjects is not
+// positive, and nil is returned. The wait ends when ctx is done. Used by project and addon tests.
+func AcquireProjectLease(ctx context.Context, maxConcurrentProjects int, testLogger common.Logger) (*common.Lease, error) {
+	if maxConcurrentProjects <= 0 {
+		return nil, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	testLogger.ShortInfo(fmt.Sprintf("Waiting for one of %d project slots", maxConcurrentProjects))
+	lease, err := common.GetDefaultLeaseManager().Acquire(ctx, projectLeaseName, maxConcurrentProjects, projectLeasePollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for a project slot: %w", err)
+	}
+	return lease, nil
+}
+
+// ReleaseProjectLease frees the project slot once the project is deleted, or was not created
+func ReleaseProjectLease(lease *common.Lease, testLogger common.Logger) {
+	if lease == nil {
+		return
+	}
+	if err := lease.Release(); err != nil {
+		testLogger.ShortWarn(err.Error())
+	}
+}
+
+// releaseRegionLease frees the region selected for the test during setup, so that other tests can select it
+func (options *TestOptions) releaseRegionLease() {
+	if !options.regionLeased {
+		return
+	}
+	if err := ReleaseRegionLease(options.CloudInfoService, options.Region); err != nil {
+		logger.Log(options.Testing, "WARNING: ", err)
+	}
+	options.regionLeased = false
+}
@@ -0,0 +1,231 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Action types used in a ConsistencyReport to describe the kind of change that was identified for a resource
+const (
+	ConsistencyActionCreate  = "create"
+	ConsistencyActionUpdate  = "update"
+	ConsistencyActionDelete  = "delete"
+	ConsistencyActionReplace = "replace"
+)
+
+// ConsistencyViolation describes a single resource that was identified by CheckConsistency as having an
+// unexpected change in the plan.
+type ConsistencyViolation struct {
+	Address    string   `json:"address"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	ActionType string   `json:"action_type"` // one of create, update, delete or replace
+	Actions    []string `json:"actions"`     // raw terraform actions of the change, for example ["delete","create"]
+	Before     string   `json:"before"`      // sanitized JSON of attributes before the change
+	After      string   `json:"after"`       // sanitized JSON of attributes after the change
+	Exempted   bool     `json:"exempted"`    // true if the resource matched an exemption and did not fail the test
+
+	// attribute paths that differ between before and after, only populated for updates
+	ChangedAttributes []string `json:"changed_attributes,omitempty"`
+	// attributes that forced a replacement, only populated for replacements when terraform supplied the replace paths
+	ReplaceCauses []ReplaceCause `json:"replace_causes,omitempty"`
+}
+
+// ConsistencyReport is the structured result of CheckConsistency, listing every resource that had a create, update,
+// delete or replace action in the plan that is considered by the consistency rules.
+// Exempted resources are included in the report with Exempted set to true.
+type ConsistencyReport struct {
+	IsUpgradeTest bool                   `json:"is_upgrade_test"`
+	Violations    []ConsistencyViolation `json:"violations"`
+	Warnings      []string               `json:"warnings,omitempty"` // for example unused exemptions from an exemption file
+
+	// upgrade tests only: suggested moved blocks for destroyed resources that were created at a new address
+	MovedBlockSuggestions []MovedBlockSuggestion `json:"moved_block_suggestions,omitempty"`
+}
+
+// HasFailures returns true if the report contains at least one violation that was not exempted
+func (report *ConsistencyReport) HasFailures() bool {
+	return len(report.Failures()) > 0
+}
+
+// Failures returns only the violations that were not exempted
+func (report *ConsistencyReport) Failures() []ConsistencyViolation {
+	if report == nil {
+		return nil
+	}
+	var failures []ConsistencyViolation
+	for _, violation := range report.Violations {
+		if !violation.Exempted {
+			failures = append(failures, violation)
+		}
+	}
+	return failures
+}
+
+// Exempted returns only the violations that were exempted
+func (report *ConsistencyReport) Exempted() []ConsistencyViolation {
+	if report == nil {
+		return nil
+	}
+	var exempted []ConsistencyViolation
+	for _, violation := range report.Violations {
+		if violation.Exempted {
+			exempted = append(exempted, violation)
+		}
+	}
+	return exempted
+}
+
+// ViolationsByAction returns all violations (exempted or not) that have the given action type
+func (report *ConsistencyReport) ViolationsByAction(actionType string) []ConsistencyViolation {
+	if report == nil {
+		return nil
+	}
+	var violations []ConsistencyViolation
+	for _, violation := range report.Violations {
+		if violation.ActionType == actionType {
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+// String renders the report as plain text suitable for test logs
+func (report *ConsistencyReport) String() string {
+	if report == nil {
+		return "Consistency Report: not available"
+	}
+
+	var sb strings.Builder
+	testType := "CONSISTENCY"
+	if report.IsUpgradeTest {
+		testType = "UPGRADE"
+	}
+	sb.WriteString(fmt.Sprintf("%s Report: %d failure(s), %d exempted\n", testType, len(report.Failures()), len(report.Exempted())))
+	for _, violation := range report.Violations {
+		status := "FAILED"
+		if violation.Exempted {
+			status = "EXEMPT"
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s (%s)\n", status, violation.Address, violation.ActionType))
+		if len(violation.ChangedAttributes) > 0 {
+			sb.WriteString(fmt.Sprintf("  Changed attributes: %s\n", strings.Join(violation.ChangedAttributes, ", ")))
+		}
+		if len(violation.ReplaceCauses) > 0 {
+			sb.WriteString(fmt.Sprintf("  Replaced because of: %s\n", strings.Join(getReplaceCausePaths(violation.ReplaceCauses), ", ")))
+			for _, cause := range violation.ReplaceCauses {
+				sb.WriteString(fmt.Sprintf("    %s\n", cause))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("  Before: %s\n", violation.Before))
+		sb.WriteString(fmt.Sprintf("  After: %s\n", violation.After))
+	}
+	for _, warning := range report.Warnings {
+		sb.WriteString(fmt.Sprintf("[WARNING] %s\n", warning))
+	}
+	if len(report.MovedBlockSuggestions) > 0 {
+		sb.WriteString("Suggested moved blocks:\n")
+		sb.WriteString(report.MovedBlocksHCL())
+	}
+
+	return sb.String()
+}
+
+// MovedBlocksHCL returns all suggested moved blocks as HCL that can be pasted into the module
+func (report *ConsistencyReport) MovedBlocksHCL() string {
+	if report == nil {
+		return ""
+	}
+	var blocks []string
+	for _, suggestion := range report.MovedBlockSuggestions {
+		blocks = append(blocks, suggestion.HCL())
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// JSON renders the report as an indented JSON string
+func (report *ConsistencyReport) JSON() (string, error) {
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling consistency report: %w", err)
+	}
+	return string(reportBytes), nil
+}
+
+// Markdown renders the report as a Markdown document, with a summary table followed by the before/after details
+// of each violation. This is intended to be published by CI systems (for example as a pull request comment).
+func (report *ConsistencyReport) Markdown() string {
+	if report == nil {
+		return "_Consistency report not available_\n"
+	}
+
+	var sb strings.Builder
+	title := "Consistency Report"
+	if report.IsUpgradeTest {
+		title = "Upgrade Consistency Report"
+	}
+	sb.WriteString(fmt.Sprintf("## %s\n\n", title))
+
+	if len(report.Violations) == 0 {
+		sb.WriteString("No resource changes identified.\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("**Failures:** %d | **Exempted:** %d\n\n", len(report.Failures()), len(report.Exempted())))
+	sb.WriteString("| Status | Address | Action |\n")
+	sb.WriteString("|---|---|---|\n")
+	for _, violation := range report.Violations {
+		status := "❌ failed"
+		if violation.Exempted {
+			status = "⚪ exempt"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | `%s` | %s |\n", status, violation.Address, violation.ActionType))
+	}
+
+	for _, warning := range report.Warnings {
+		sb.WriteString(fmt.Sprintf("\n> ⚠️ %s\n", warning))
+	}
+
+	if len(report.MovedBlockSuggestions) > 0 {
+		sb.WriteString(fmt.Sprintf("\n### Suggested moved blocks\n\n```hcl\n%s```\n", report.MovedBlocksHCL()))
+	}
+
+	for _, violation := range report.Violations {
+		sb.WriteString(fmt.Sprintf("\n### `%s` (%s)\n\n", violation.Address, violation.ActionType))
+		if len(violation.ReplaceCauses) > 0 {
+			sb.WriteString(fmt.Sprintf("Replaced because of: %s\n\n", strings.Join(getReplaceCausePaths(violation.ReplaceCauses), ", ")))
+			sb.WriteString("| Attribute | Before | After |\n")
+			sb.WriteString("|---|---|---|\n")
+			for _, cause := range violation.ReplaceCauses {
+				sb.WriteString(fmt.Sprintf("| `%s` | `%s` | `%s` |\n", cause.Path, cause.Before, cause.After))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("<details><summary>Before / After</summary>\n\n")
+		sb.WriteString(fmt.Sprintf("Before:\n```json\n%s\n```\n\n", violation.Before))
+		sb.WriteString(fmt.Sprintf("After:\n```json\n%s\n```\n", violation.After))
+		sb.WriteString("\n</details>\n")
+	}
+
+	return sb.String()
+}
+
+// getConsistencyActionType converts the terraform actions of a change into a single action type used in the report.
+// Returns an empty string for changes that are not considered in consistency checks (no-op, read).
+func getConsistencyActionType(actions tfjson.Actions) string {
+	switch {
+	case actions.Replace():
+		return ConsistencyActionReplace
+	case actions.Delete():
+		return ConsistencyActionDelete
+	case actions.Update():
+		return ConsistencyActionUpdate
+	case actions.Create():
+		return ConsistencyActionCreate
+	default:
+		return ""
+	}
+}
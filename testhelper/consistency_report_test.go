@@ -0,0 +1,117 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResourceChange(address string, resourceType string, actions tfjson.Actions, before interface{}, after interface{}) *tfjson.ResourceChange {
+	return &tfjson.ResourceChange{
+		Address: address,
+		Type:    resourceType,
+		Name:    "test",
+		Change: &tfjson.Change{
+			Actions: actions,
+			Before:  before,
+			After:   after,
+		},
+	}
+}
+
+func TestCheckConsistencyReport(t *testing.T) {
+	plan := &terraform.PlanStruct{
+		ResourceChangesMap: map[string]*tfjson.ResourceChange{
+			"null_resource.noop": newTestResourceChange("null_resource.noop", "null_resource", tfjson.Actions{tfjson.ActionNoop},
+				map[string]interface{}{"id": "1"}, map[string]interface{}{"id": "1"}),
+			"null_resource.updated": newTestResourceChange("null_resource.updated", "null_resource", tfjson.Actions{tfjson.ActionUpdate},
+				map[string]interface{}{"id": "1", "triggers": "a"}, map[string]interface{}{"id": "1", "triggers": "b"}),
+			"null_resource.replaced": newTestResourceChange("null_resource.replaced", "null_resource", tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate},
+				map[string]interface{}{"id": "1"}, map[string]interface{}{"id": "2"}),
+			"null_resource.created": newTestResourceChange("null_resource.created", "null_resource", tfjson.Actions{tfjson.ActionCreate},
+				nil, map[string]interface{}{"id": "3"}),
+		},
+	}
+
+	options := &TestOptions{
+		Testing:        t,
+		IgnoreUpdates:  Exemptions{List: []string{"null_resource.updated"}},
+		IgnoreDestroys: Exemptions{List: []string{"null_resource.replaced"}},
+		IgnoreAdds:     Exemptions{List: []string{"null_resource.created"}},
+	}
+
+	t.Run("ConsistencyTest", func(t *testing.T) {
+		report := CheckConsistency(plan, options)
+		require.NotNil(t, report)
+		assert.False(t, report.HasFailures())
+		require.Len(t, report.Violations, 3)
+		assert.Len(t, report.Exempted(), 3)
+
+		// report is sorted by address
+		assert.Equal(t, "null_resource.created", report.Violations[0].Address)
+		assert.Equal(t, ConsistencyActionCreate, report.Violations[0].ActionType)
+		assert.Equal(t, "null_resource.replaced", report.Violations[1].Address)
+		assert.Equal(t, ConsistencyActionReplace, report.Violations[1].ActionType)
+		assert.Equal(t, []string{"delete", "create"}, report.Violations[1].Actions)
+		assert.Equal(t, "null_resource.updated", report.Violations[2].Address)
+		assert.Equal(t, ConsistencyActionUpdate, report.Violations[2].ActionType)
+		assert.Contains(t, report.Violations[2].Before, "\"a\"")
+		assert.Contains(t, report.Violations[2].After, "\"b\"")
+	})
+
+	t.Run("UpgradeTestIgnoresCreates", func(t *testing.T) {
+		options.IsUpgradeTest = true
+		defer func() { options.IsUpgradeTest = false }()
+
+		report := CheckConsistency(plan, options)
+		assert.True(t, report.IsUpgradeTest)
+		assert.Len(t, report.Violations, 2)
+		assert.Empty(t, report.ViolationsByAction(ConsistencyActionCreate))
+	})
+}
+
+func TestConsistencyReportRendering(t *testing.T) {
+	report := &ConsistencyReport{
+		Violations: []ConsistencyViolation{
+			{Address: "module.a.null_resource.one", ActionType: ConsistencyActionDelete, Before: `{"id":"1"}`, After: `null`},
+			{Address: "module.a.null_resource.two", ActionType: ConsistencyActionUpdate, Before: `{"x":1}`, After: `{"x":2}`, Exempted: true},
+		},
+	}
+
+	assert.True(t, report.HasFailures())
+	assert.Len(t, report.Failures(), 1)
+	assert.Len(t, report.Exempted(), 1)
+
+	t.Run("Text", func(t *testing.T) {
+		text := report.String()
+		assert.Contains(t, text, "1 failure(s), 1 exempted")
+		assert.Contains(t, text, "[FAILED] module.a.null_resource.one (delete)")
+		assert.Contains(t, text, "[EXEMPT] module.a.null_resource.two (update)")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		jsonString, err := report.JSON()
+		require.NoError(t, err)
+		var decoded ConsistencyReport
+		require.NoError(t, json.Unmarshal([]byte(jsonString), &decoded))
+		assert.Equal(t, *report, decoded)
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		markdown := report.Markdown()
+		assert.Contains(t, markdown, "## Consistency Report")
+		assert.Contains(t, markdown, "| `module.a.null_resource.one` | delete |")
+		assert.Contains(t, markdown, "### `module.a.null_resource.two` (update)")
+	})
+
+	t.Run("NilReport", func(t *testing.T) {
+		var nilReport *ConsistencyReport
+		assert.False(t, nilReport.HasFailures())
+		assert.NotEmpty(t, nilReport.String())
+		assert.NotEmpty(t, nilReport.Markdown())
+	})
+}
@@ -0,0 +1,40 @@
+package testhelper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// getRunContext returns the context used for the terraform operations of the test, creating it on first use.
+// The context is derived from TestOptions.Context and is cancelled when the go test deadline minus the
+// TeardownReserve is reached.
+func (options *TestOptions) getRunContext() context.Context {
+	if options.runContext == nil {
+		options.runContext, options.cancelRunContext = common.NewTestContext(options.Testing, options.Context, options.TeardownReserve)
+		if deadline, ok := options.runContext.Deadline(); ok {
+			logger.Log(options.Testing, fmt.Sprintf("Terraform operations will be cancelled at %s to reserve time for teardown", deadline.Format("15:04:05")))
+		}
+		// on SIGINT/SIGTERM cancel the running terraform command and destroy the test resources
+		options.interruptTeardown = common.RegisterInterruptTeardown(options.Testing, fmt.Sprintf("terraform test %s", options.Prefix), options.cancelRunContext, options.runTestTearDown)
+	}
+	return options.runContext
+}
+
+// releaseRunContext cancels and clears the run context, a new one is created for the next test run
+func (options *TestOptions) releaseRunContext() {
+	if options.cancelRunContext != nil {
+		options.cancelRunContext()
+	}
+	options.interruptTeardown.Unregister()
+	options.runContext = nil
+	options.cancelRunContext = nil
+	options.interruptTeardown = nil
+}
+
+// getTeardownContext returns a context for teardown, which is not cancelled with the run context
+func (options *TestOptions) getTeardownContext() (context.Context, context.CancelFunc) {
+	return common.NewTeardownContext(options.Testing, options.Context)
+}
@@ -0,0 +1,231 @@
+package testhelper
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// ExemptionFileDateFormat is the date format used for the `expires` field of an exemption file entry
+const ExemptionFileDateFormat = "2006-01-02"
+
+// ExemptionFile represents a file of consistency check exemptions, which can be written in YAML or JSON.
+// Each entry must document why it exists, so that exemptions can be reviewed and removed when no longer needed.
+//
+// Example:
+//
+//	ignore_updates:
+//	  - address: "module.*.ibm_resource_instance.instance"
+//	    attributes: ["parameters.last_updated"]
+//	    reason: "last_updated is refreshed by the provider on every apply"
+//	    issue: "https://github.com/org/repo/issues/123"
+//	    expires: "2026-12-31"
+//	ignore_destroys:
+//	  - address: "time_sleep.wait_for_authorization_policy"
+//	    reason: "sleep is recreated when the policy changes"
+type ExemptionFile struct {
+	IgnoreAdds     []ExemptionFileEntry `yaml:"ignore_adds" json:"ignore_adds"`
+	IgnoreUpdates  []ExemptionFileEntry `yaml:"ignore_updates" json:"ignore_updates"`
+	IgnoreDestroys []ExemptionFileEntry `yaml:"ignore_destroys" json:"ignore_destroys"`
+}
+
+// ExemptionFileEntry is a single exemption in an ExemptionFile
+type ExemptionFileEntry struct {
+	// REQUIRED: resource address, either exact or a glob style pattern (see Exemptions.Patterns)
+	Address string `yaml:"address" json:"address"`
+	// OPTIONAL: only valid for `ignore_updates`, attribute paths to ignore instead of the whole resource (see AttributeExemption)
+	Attributes []string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	// REQUIRED: justification for the exemption
+	Reason string `yaml:"reason" json:"reason"`
+	// OPTIONAL: link to an issue tracking the removal of the exemption
+	Issue string `yaml:"issue,omitempty" json:"issue,omitempty"`
+	// OPTIONAL: date the exemption expires, in the format YYYY-MM-DD. The exemption is valid up to and including this date.
+	Expires string `yaml:"expires,omitempty" json:"expires,omitempty"`
+}
+
+// LoadExemptionFile reads and validates an exemption file. Both YAML and JSON files are supported.
+func LoadExemptionFile(filePath string) (*ExemptionFile, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading exemption file %s: %w", filePath, err)
+	}
+
+	file := &ExemptionFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("error parsing exemption file %s: %w", filePath, err)
+	}
+
+	if err := file.validate(); err != nil {
+		return nil, fmt.Errorf("invalid exemption file %s: %w", filePath, err)
+	}
+
+	return file, nil
+}
+
+// validate checks that all required fields are set and that the dates are in the correct format
+func (file *ExemptionFile) validate() error {
+	sections := []struct {
+		name    string
+		entries []ExemptionFileEntry
+	}{
+		{"ignore_adds", file.IgnoreAdds},
+		{"ignore_updates", file.IgnoreUpdates},
+		{"ignore_destroys", file.IgnoreDestroys},
+	}
+	for _, s := range sections {
+		section := s.name
+		for i, entry := range s.entries {
+			if entry.Address == "" {
+				return fmt.Errorf("%s[%d]: address is required", section, i)
+			}
+			if entry.Reason == "" {
+				return fmt.Errorf("%s[%d] (%s): reason is required", section, i, entry.Address)
+			}
+			if len(entry.Attributes) > 0 && section != "ignore_updates" {
+				return fmt.Errorf("%s[%d] (%s): attributes are only supported for ignore_updates", section, i, entry.Address)
+			}
+			if entry.Expires != "" {
+				if _, err := time.Parse(ExemptionFileDateFormat, entry.Expires); err != nil {
+					return fmt.Errorf("%s[%d] (%s): invalid expires date %q, expected format YYYY-MM-DD", section, i, entry.Address, entry.Expires)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// IsExpired returns true if the entry has an expiry date that is before the given time.
+// Entries without an expiry date never expire.
+func (entry ExemptionFileEntry) IsExpired(now time.Time) bool {
+	if entry.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse(ExemptionFileDateFormat, entry.Expires)
+	if err != nil {
+		return false
+	}
+	// valid up to and including the expiry date
+	return !now.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// String returns a short description of the entry for use in logs
+func (entry ExemptionFileEntry) String() string {
+	description := entry.Address
+	if len(entry.Attributes) > 0 {
+		description = fmt.Sprintf("%s %v", description, entry.Attributes)
+	}
+	description = fmt.Sprintf("%s (reason: %s", description, entry.Reason)
+	if entry.Issue != "" {
+		description = fmt.Sprintf("%s, issue: %s", description, entry.Issue)
+	}
+	if entry.Expires != "" {
+		description = fmt.Sprintf("%s, expires: %s", description, entry.Expires)
+	}
+	return description + ")"
+}
+
+// toExemptions converts the entry to an Exemptions value that matches the same resources
+func (entry ExemptionFileEntry) toExemptions() Exemptions {
+	if len(entry.Attributes) > 0 {
+		return Exemptions{Attributes: []AttributeExemption{{Address: entry.Address, Paths: entry.Attributes}}}
+	}
+	return Exemptions{Patterns: []string{entry.Address}}
+}
+
+// mergeExemptionEntries returns a new Exemptions value with all non-expired entries merged into the given exemptions.
+// Expired entries are returned separately and are not merged.
+func mergeExemptionEntries(exemptions Exemptions, entries []ExemptionFileEntry, now time.Time) (Exemptions, []ExemptionFileEntry) {
+	var expired []ExemptionFileEntry
+	merged := exemptions
+	for _, entry := range entries {
+		if entry.IsExpired(now) {
+			expired = append(expired, entry)
+			continue
+		}
+		merged = merged.merge(entry.toExemptions())
+	}
+	return merged, expired
+}
+
+// merge returns a new Exemptions value containing the exemptions of both values, neither value is modified
+func (exemptions Exemptions) merge(other Exemptions) Exemptions {
+	return Exemptions{
+		List:          append(append([]string{}, exemptions.List...), other.List...),
+		Patterns:      append(append([]string{}, exemptions.Patterns...), other.Patterns...),
+		RegexPatterns: append(append([]string{}, exemptions.RegexPatterns...), other.RegexPatterns...),
+		Attributes:    append(append([]AttributeExemption{}, exemptions.Attributes...), other.Attributes...),
+	}
+}
+
+// isExemptionEntryUsed returns true if the entry matched at least one of the given violations
+func isExemptionEntryUsed(entry ExemptionFileEntry, violations []ConsistencyViolation, actionTypes ...string) bool {
+	exemptions := entry.toExemptions()
+	for _, violation := range violations {
+		if !violation.Exempted {
+			continue
+		}
+		for _, actionType := range actionTypes {
+			if violation.ActionType == actionType &&
+				(exemptions.IsExemptedResource(violation.Address) || len(exemptions.attributePathsForAddress(violation.Address)) > 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyExemptionFile loads the exemption file configured in the options (if any) and merges its entries into the
+// exemption lists of the options. Expired entries are not merged and fail the test.
+// Returns the loaded file, or nil if no file was configured or it could not be loaded.
+func applyExemptionFile(options *CheckConsistencyOptions, now time.Time) *ExemptionFile {
+	if options.ExemptionFile == "" {
+		return nil
+	}
+
+	file, err := LoadExemptionFile(options.ExemptionFile)
+	if err != nil {
+		assert.Fail(options.Testing, err.Error())
+		return nil
+	}
+
+	var expiredAdds, expiredUpdates, expiredDestroys []ExemptionFileEntry
+	options.IgnoreAdds, expiredAdds = mergeExemptionEntries(options.IgnoreAdds, file.IgnoreAdds, now)
+	options.IgnoreUpdates, expiredUpdates = mergeExemptionEntries(options.IgnoreUpdates, file.IgnoreUpdates, now)
+	options.IgnoreDestroys, expiredDestroys = mergeExemptionEntries(options.IgnoreDestroys, file.IgnoreDestroys, now)
+
+	for _, entry := range append(append(expiredAdds, expiredUpdates...), expiredDestroys...) {
+		assert.Fail(options.Testing, fmt.Sprintf("Exemption in %s has expired, remove it or extend the expiry date: %s", options.ExemptionFile, entry))
+	}
+
+	return file
+}
+
+// getUnusedExemptionWarnings returns a warning for every non-expired entry in the exemption file that did not match
+// any resource in the report
+func getUnusedExemptionWarnings(file *ExemptionFile, report *ConsistencyReport, now time.Time) []string {
+	var warnings []string
+	if file == nil {
+		return warnings
+	}
+
+	check := func(entries []ExemptionFileEntry, section string, actionTypes ...string) {
+		for _, entry := range entries {
+			if entry.IsExpired(now) || isExemptionEntryUsed(entry, report.Violations, actionTypes...) {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("Unused exemption in %s: %s", section, entry))
+		}
+	}
+
+	// adds are not checked in upgrade tests, so these exemptions can not be used
+	if !report.IsUpgradeTest {
+		check(file.IgnoreAdds, "ignore_adds", ConsistencyActionCreate)
+	}
+	check(file.IgnoreUpdates, "ignore_updates", ConsistencyActionUpdate)
+	check(file.IgnoreDestroys, "ignore_destroys", ConsistencyActionDelete, ConsistencyActionReplace)
+
+	return warnings
+}
@@ -0,0 +1,126 @@
+package testhelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExemptionFile(t *testing.T, name string, content string) string {
+	filePath := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestLoadExemptionFile(t *testing.T) {
+	t.Run("YAML", func(t *testing.T) {
+		filePath := writeExemptionFile(t, "exemptions.yaml", `
+ignore_updates:
+  - address: "module.*.ibm_resource_instance.instance"
+    attributes: ["parameters.last_updated"]
+    reason: "refreshed on every apply"
+    issue: "https://github.com/org/repo/issues/1"
+    expires: "2099-12-31"
+ignore_destroys:
+  - address: "time_sleep.wait"
+    reason: "recreated with the policy"
+`)
+		file, err := LoadExemptionFile(filePath)
+		require.NoError(t, err)
+		require.Len(t, file.IgnoreUpdates, 1)
+		assert.Equal(t, []string{"parameters.last_updated"}, file.IgnoreUpdates[0].Attributes)
+		assert.Equal(t, "2099-12-31", file.IgnoreUpdates[0].Expires)
+		require.Len(t, file.IgnoreDestroys, 1)
+		assert.Empty(t, file.IgnoreAdds)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		filePath := writeExemptionFile(t, "exemptions.json", `{"ignore_adds": [{"address": "null_resource.foo", "reason": "always added"}]}`)
+		file, err := LoadExemptionFile(filePath)
+		require.NoError(t, err)
+		require.Len(t, file.IgnoreAdds, 1)
+		assert.Equal(t, "null_resource.foo", file.IgnoreAdds[0].Address)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := map[string]string{
+			"missing reason":      `{"ignore_adds": [{"address": "null_resource.foo"}]}`,
+			"missing address":     `{"ignore_adds": [{"reason": "why"}]}`,
+			"bad date":            `{"ignore_adds": [{"address": "a", "reason": "why", "expires": "31/12/2099"}]}`,
+			"attributes on a add": `{"ignore_adds": [{"address": "a", "reason": "why", "attributes": ["x"]}]}`,
+		}
+		for name, content := range invalid {
+			_, err := LoadExemptionFile(writeExemptionFile(t, "exemptions.json", content))
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		_, err := LoadExemptionFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestExemptionFileEntryIsExpired(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	assert.False(t, ExemptionFileEntry{}.IsExpired(now))
+	assert.False(t, ExemptionFileEntry{Expires: "2026-06-15"}.IsExpired(now), "valid on the expiry date")
+	assert.True(t, ExemptionFileEntry{Expires: "2026-06-14"}.IsExpired(now))
+}
+
+func TestMergeExemptionEntries(t *testing.T) {
+	now := time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+	programmatic := Exemptions{List: []string{"null_resource.code"}}
+	entries := []ExemptionFileEntry{
+		{Address: "module.*.null_resource.file", Reason: "pattern"},
+		{Address: "ibm_resource_instance.instance", Attributes: []string{"tags"}, Reason: "attribute"},
+		{Address: "null_resource.old", Reason: "expired", Expires: "2026-01-01"},
+	}
+
+	merged, expired := mergeExemptionEntries(programmatic, entries, now)
+
+	assert.Equal(t, []string{"null_resource.code"}, programmatic.List)
+	assert.Empty(t, programmatic.Patterns, "original exemptions should not be modified")
+	assert.True(t, merged.IsExemptedResource("null_resource.code"))
+	assert.True(t, merged.IsExemptedResource("module.a.null_resource.file"))
+	assert.False(t, merged.IsExemptedResource("null_resource.old"))
+	assert.Equal(t, []AttributeExemption{{Address: "ibm_resource_instance.instance", Paths: []string{"tags"}}}, merged.Attributes)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "null_resource.old", expired[0].Address)
+}
+
+func TestCheckConsistencyWithExemptionFile(t *testing.T) {
+	filePath := writeExemptionFile(t, "exemptions.yaml", `
+ignore_updates:
+  - address: "null_resource.*"
+    reason: "triggers change on every run"
+ignore_destroys:
+  - address: "time_sleep.unused"
+    reason: "no longer needed"
+`)
+
+	plan := &terraform.PlanStruct{
+		ResourceChangesMap: map[string]*tfjson.ResourceChange{
+			"null_resource.updated": newTestResourceChange("null_resource.updated", "null_resource", tfjson.Actions{tfjson.ActionUpdate},
+				map[string]interface{}{"triggers": "a"}, map[string]interface{}{"triggers": "b"}),
+		},
+	}
+
+	options := &TestOptions{
+		Testing:       t,
+		ExemptionFile: filePath,
+	}
+
+	report := CheckConsistency(plan, options)
+	assert.False(t, report.HasFailures())
+	assert.Len(t, report.Exempted(), 1)
+	require.Len(t, report.Warnings, 1)
+	assert.Contains(t, report.Warnings[0], "time_sleep.unused")
+}
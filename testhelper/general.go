@@ -17,11 +17,16 @@ import (
 
 const ForceTestRegionEnvName = "FORCE_TEST_REGION"
 
+const ForceTestZoneEnvName = "FORCE_TEST_ZONE"
+
 // TesthelperTerraformOptions options object for optional variables to set
 // primarily used for mocking external services in test cases
 type TesthelperTerraformOptions struct {
 	CloudInfoService              cloudinfo.CloudInfoServiceI
 	ExcludeActivityTrackerRegions bool
+	// RegionLeases excludes regions selected by other test processes sharing the lease directory, only used if
+	// CloudInfoService is not supplied. Default is to coordinate if the TEST_LEASE_DIR environment variable is set.
+	RegionLeases *common.LeaseManager
 }
 
 // GetBestVpcRegion is a method that will determine a region available
@@ -39,6 +44,8 @@ func GetBestVpcRegion(apiKey string, prefsFilePath string, defaultRegion string)
 // The determination can be influenced by specifying a prefsFilePath pointed to a valid YAML file.
 // If an OS ENV is found called FORCE_TEST_REGION then it will be used without querying.
 // Options data can also be called to supply the service to use that implements the correct interface.
+// Regions selected by other test processes are excluded if region leases are enabled, see RegionLeases. The selected
+// region stays leased until it is released with ReleaseRegionLease.
 // Returns a string representing an IBM Cloud region name, and error.
 func GetBestVpcRegionO(apiKey string, prefsFilePath string, defaultRegion string, options TesthelperTerraformOptions) (string, error) {
 	// If there is an OS ENV found to force the region, simply return that value and short-circuit this routine
@@ -90,6 +97,22 @@ func GetBestVpcRegionO(apiKey string, prefsFilePath string, defaultRegion string
 	return bestregion, nil
 }
 
+// ReleaseRegionLease releases the lease of a region selected by GetBestVpcRegionO or GetBestPowerSystemsRegionO, so
+// that other test processes can select the region again. The lease is released with cloudInfoService if it supports
+// region leases, otherwise with the lease manager of the TEST_LEASE_DIR environment variable. Test runners call it
+// during teardown.
+func ReleaseRegionLease(cloudInfoService cloudinfo.CloudInfoServiceI, region string) error {
+	if releaser, ok := cloudInfoService.(interface {
+		ReleaseRegionLease(region string) error
+	}); ok {
+		return releaser.ReleaseRegionLease(region)
+	}
+	if os.Getenv(common.LeaseDirEnvVar) == "" {
+		return nil
+	}
+	return cloudinfo.ReleaseRegionLease(common.GetDefaultLeaseManager(), region)
+}
+
 // GetBestPowerSystemsRegion is a method that will determine a region available
 // to the caller account that currently contains the least amount of deployed PowerVS Cloud Connections.
 // The determination can be influenced by specifying a prefsFilePath pointed to a valid YAML file.
@@ -105,6 +128,8 @@ func GetBestPowerSystemsRegion(apiKey string, prefsFilePath string, defaultRegio
 // The determination can be influenced by specifying a prefsFilePath pointed to a valid YAML file.
 // If an OS ENV is found called FORCE_TEST_REGION then it will be used without querying.
 // Options data can also be called to supply the service to use that implements the correct interface.
+// Regions selected by other test processes are excluded if region leases are enabled, see RegionLeases. The selected
+// region stays leased until it is released with ReleaseRegionLease.
 // Returns a string representing an IBM Cloud region name, and error.
 func GetBestPowerSystemsRegionO(apiKey string, prefsFilePath string, defaultRegion string, options TesthelperTerraformOptions) (string, error) {
 	// set up initial best region as default
@@ -152,6 +177,61 @@ func GetBestPowerSystemsRegionO(apiKey string, prefsFilePath string, defaultRegi
 	return bestregion, nil
 }
 
+// vpcZoneSelector is implemented by a CloudInfoService that can select VPC zones, see cloudinfo.CloudInfoService.GetLeastVpcTestZone
+type vpcZoneSelector interface {
+	GetLeastVpcTestZone(region string) (string, error)
+}
+
+// GetBestVpcZone is a method that will determine the zone of a VPC region
+// that currently contains the least amount of instances, subnets and bare metal servers.
+// If an OS ENV is found called FORCE_TEST_ZONE then it will be used without querying.
+// This function assumes that all default Options will be used.
+// Returns a string representing an IBM Cloud zone name, and error.
+func GetBestVpcZone(apiKey string, region string, defaultZone string) (string, error) {
+	return GetBestVpcZoneO(apiKey, region, defaultZone, TesthelperTerraformOptions{})
+}
+
+// GetBestVpcZoneO is a method that will determine the zone of a VPC region
+// that currently contains the least amount of instances, subnets and bare metal servers.
+// If an OS ENV is found called FORCE_TEST_ZONE then it will be used without querying.
+// Options data can also be called to supply the service to use that implements the correct interface.
+// Returns a string representing an IBM Cloud zone name, and error.
+func GetBestVpcZoneO(apiKey string, region string, defaultZone string, options TesthelperTerraformOptions) (string, error) {
+	// If there is an OS ENV found to force the zone, simply return that value and short-circuit this routine
+	forceZone, isForcePresent := os.LookupEnv(ForceTestZoneEnvName)
+	if isForcePresent {
+		return forceZone, nil
+	}
+
+	cloudSvc, cloudSvcErr := configureCloudInfoService(apiKey, "", options)
+	if cloudSvcErr != nil {
+		log.Println("Error creating CloudInfoService for testhelper")
+		return defaultZone, cloudSvcErr
+	}
+
+	zoneSelector, ok := cloudSvc.(vpcZoneSelector)
+	if !ok {
+		log.Println("CloudInfoService does not support zone selection, using default zone:", defaultZone)
+		return defaultZone, fmt.Errorf("CloudInfoService %T does not support zone selection", cloudSvc)
+	}
+
+	bestzone, getErr := zoneSelector.GetLeastVpcTestZone(region)
+	if getErr != nil {
+		log.Println("Error getting best zone")
+		return defaultZone, getErr
+	}
+
+	// regardless of error, if the bestzone returned is empty use default
+	if len(bestzone) > 0 {
+		log.Println("Best zone was found!:", bestzone)
+	} else {
+		log.Println("Dynamic zone not found, using default zone:", defaultZone)
+		return defaultZone, nil
+	}
+
+	return bestzone, nil
+}
+
 // configureCloudInfoService is a private function that will configure and set up a new CloudInfoService for testhelper
 func configureCloudInfoService(apiKey string, prefsFilePath string, options TesthelperTerraformOptions) (cloudinfo.CloudInfoServiceI, error) {
 	var cloudSvc cloudinfo.CloudInfoServiceI
@@ -162,7 +242,8 @@ func configureCloudInfoService(apiKey string, prefsFilePath string, options Test
 	} else {
 		// set up new service based on supplied values
 		svcOptions := cloudinfo.CloudInfoServiceOptions{
-			ApiKey: apiKey, //pragma: allowlist secret
+			ApiKey:       apiKey, //pragma: allowlist secret
+			RegionLeases: options.RegionLeases,
 		}
 		cloudSvcRef, svcErr := cloudinfo.NewCloudInfoServiceWithKey(svcOptions)
 		if svcErr != nil {
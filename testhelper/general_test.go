@@ -122,6 +122,16 @@ func (mock *cloudInfoServiceMock) GetLeastPowerConnectionZone() (string, error)
 	return "", errors.New("mock no matching file name")
 }
 
+func (mock *cloudInfoServiceMock) GetLeastVpcTestZone(region string) (string, error) {
+	switch region {
+	case "error-region":
+		return "", errors.New("mock Error Msg")
+	case "empty-region":
+		return "", nil
+	}
+	return region + "-2", nil
+}
+
 func (mock *cloudInfoServiceMock) HasRegionData() bool {
 	return false
 }
@@ -708,3 +718,62 @@ func TestGetTarIncludePatterns(t *testing.T) {
 		})
 	}
 }
+
+func TestBestVpcZone(t *testing.T) {
+	infoSvc := cloudInfoServiceMock{}
+	options := TesthelperTerraformOptions{CloudInfoService: &infoSvc}
+
+	t.Run("Found", func(t *testing.T) {
+		bestzone, err := GetBestVpcZoneO("FAKEKEY", "us-south", "us-south-1", options)
+		assert.Nil(t, err, "Must not return error")
+		assert.Equal(t, "us-south-2", bestzone, "Should return best zone")
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		bestzone, err := GetBestVpcZoneO("FAKEKEY", "error-region", "error-region-1", options)
+		assert.NotNil(t, err, "Error condition should have returned error")
+		assert.Equal(t, "error-region-1", bestzone, "Error condition should return default zone")
+
+		bestzone, err = GetBestVpcZoneO("FAKEKEY", "empty-region", "empty-region-1", options)
+		assert.Nil(t, err, "Empty condition should NOT have returned error")
+		assert.Equal(t, "empty-region-1", bestzone, "Empty condition should return default zone")
+	})
+
+	t.Run("NotSupported", func(t *testing.T) {
+		unsupported := TesthelperTerraformOptions{CloudInfoService: &struct{ cloudinfo.CloudInfoServiceI }{}}
+		bestzone, err := GetBestVpcZoneO("FAKEKEY", "us-south", "us-south-1", unsupported)
+		assert.NotNil(t, err, "Must return error")
+		assert.Equal(t, "us-south-1", bestzone, "Should return default zone")
+	})
+
+	t.Run("Forced", func(t *testing.T) {
+		t.Setenv(ForceTestZoneEnvName, "forced-zone")
+		bestzone, err := GetBestVpcZoneO("FAKEKEY", "us-south", "us-south-1", options)
+		assert.Nil(t, err, "Must not return error")
+		assert.Equal(t, "forced-zone", bestzone, "Should return FORCED zone")
+	})
+}
+
+func TestOptionsDefaultWithVarsZone(t *testing.T) {
+	t.Setenv("TF_VAR_ibmcloud_api_key", "12345")
+	t.Setenv(ForceTestZoneEnvName, "forced-zone")
+
+	options := TestOptionsDefaultWithVars(&TestOptions{
+		Testing:        t,
+		TerraformDir:   sample1,
+		Prefix:         "zone",
+		Region:         "us-south",
+		SelectBestZone: true,
+	})
+	assert.Equal(t, "forced-zone", options.Zone)
+	assert.Equal(t, "forced-zone", options.TerraformVars["zone"])
+
+	options = TestOptionsDefaultWithVars(&TestOptions{
+		Testing:      t,
+		TerraformDir: sample1,
+		Prefix:       "zone",
+		Region:       "us-south",
+	})
+	assert.Empty(t, options.Zone)
+	assert.NotContains(t, options.TerraformVars, "zone")
+}
@@ -0,0 +1,110 @@
+package testhelper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// GetLeakCheckOptions returns the scope of the leak check snapshots of a test: the LeakCheckOptions of the test if
+// set, otherwise the resource instances of the resource group of the test and of the resource groups whose name starts
+// with the test prefix, for example `<prefix>-resource-group`, and the VPCs of its region. Used by all test runners,
+// so that leak checks do not list every resource of the account by default.
+func GetLeakCheckOptions(leakCheckOptions *cloudinfo.ResourceSnapshotOptions, resourceGroup string, region string, prefix string) cloudinfo.ResourceSnapshotOptions {
+	if leakCheckOptions != nil {
+		return *leakCheckOptions
+	}
+	snapshotOptions := cloudinfo.ResourceSnapshotOptions{}
+	if resourceGroup != "" {
+		snapshotOptions.ResourceGroupNames = []string{resourceGroup}
+	}
+	if prefix != "" {
+		snapshotOptions.ResourceGroupPrefixes = []string{prefix}
+	}
+	if region != "" {
+		snapshotOptions.VpcRegions = []string{region}
+	}
+	return snapshotOptions
+}
+
+// StartLeakCheck takes the snapshot of the resources in the scope of snapshotOptions before a test creates any, and
+// returns the detector to pass to FinishLeakCheck after the teardown of the test
+func StartLeakCheck(service cloudinfo.CloudInfoServiceI, prefix string, snapshotOptions cloudinfo.ResourceSnapshotOptions) (*cloudinfo.LeakDetector, error) {
+	snapshotter, ok := service.(cloudinfo.ResourceSnapshotter)
+	if !ok {
+		return nil, fmt.Errorf("CloudInfoService can not take resource snapshots")
+	}
+	detector := cloudinfo.NewLeakDetector(snapshotter, prefix, snapshotOptions)
+	if err := detector.Start(); err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// FinishLeakCheck takes the snapshot after the teardown of a test and fails the test for every resource created since
+// StartLeakCheck whose name or tags contain the test prefix. Returns the leaked resources, and the error of the check
+// which does not fail the test.
+func FinishLeakCheck(t *testing.T, detector *cloudinfo.LeakDetector) ([]cloudinfo.SnapshotResource, error) {
+	leaked, err := detector.Check()
+	if len(leaked) > 0 {
+		assert.Fail(t, "Test resources leaked", cloudinfo.FormatLeakedResources(leaked))
+	}
+	return leaked, err
+}
+
+// startLeakDetection takes the snapshot of the account resources before the test creates any, if CheckForLeaks is set.
+// Errors are logged and disable the leak check, they do not fail the test.
+func (options *TestOptions) startLeakDetection() {
+	if !options.CheckForLeaks || options.SkipTestTearDown || options.leakDetector != nil {
+		return
+	}
+
+	service := options.CloudInfoService
+	if _, ok := service.(cloudinfo.ResourceSnapshotter); !ok {
+		cacheEnabled := true
+		if options.CacheEnabled != nil {
+			cacheEnabled = *options.CacheEnabled
+		}
+		cloudInfoSvc, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{
+			CacheEnabled: cacheEnabled,
+			CacheTTL:     options.CacheTTL,
+		})
+		if err != nil {
+			logger.Log(options.Testing, "WARNING: Error creating CloudInfoService for testhelper, skipping leak check: ", err)
+			return
+		}
+		service = cloudInfoSvc
+	}
+
+	snapshotOptions := GetLeakCheckOptions(options.LeakCheckOptions, options.ResourceGroup, options.Region, options.Prefix)
+	detector, err := StartLeakCheck(service, options.Prefix, snapshotOptions)
+	if err != nil {
+		logger.Log(options.Testing, "WARNING: skipping leak check: ", err)
+		return
+	}
+	options.leakDetector = detector
+}
+
+// checkForLeaks fails the test for every resource created since startLeakDetection whose name or tags contain the
+// test prefix. It is run after the destroy, and not if the resources were kept for debugging.
+func (options *TestOptions) checkForLeaks() {
+	detector := options.leakDetector
+	options.leakDetector = nil
+	if detector == nil {
+		return
+	}
+	if options.Testing.Failed() && common.DoNotDestroyOnFailure() {
+		logger.Log(options.Testing, "Skipping leak check, resources of the failed test were not destroyed")
+		return
+	}
+
+	logger.Log(options.Testing, "START: Leak check")
+	if _, err := FinishLeakCheck(options.Testing, detector); err != nil {
+		logger.Log(options.Testing, "WARNING: leak check failed: ", err)
+	}
+	logger.Log(options.Testing, "END: Leak check")
+}
@@ -0,0 +1,19 @@
+package testhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+)
+
+func TestGetLeakCheckOptions(t *testing.T) {
+	assert.Equal(t, cloudinfo.ResourceSnapshotOptions{ResourceGroupNames: []string{"test-rg"}, ResourceGroupPrefixes: []string{"abc-1"}, VpcRegions: []string{"eu-de"}},
+		GetLeakCheckOptions(nil, "test-rg", "eu-de", "abc-1"))
+	assert.Equal(t, cloudinfo.ResourceSnapshotOptions{ResourceGroupPrefixes: []string{"abc-1"}},
+		GetLeakCheckOptions(nil, "", "", "abc-1"), "the resource groups created by the test are checked")
+	assert.True(t, GetLeakCheckOptions(nil, "", "", "").IsEmpty(), "without resource group, region or prefix nothing is checked")
+
+	custom := &cloudinfo.ResourceSnapshotOptions{CrnServiceNames: []string{"kms"}}
+	assert.Equal(t, *custom, GetLeakCheckOptions(custom, "test-rg", "eu-de", "abc-1"))
+}
@@ -0,0 +1,86 @@
+package testhelper
+
+import (
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// ExpectedChanges lists the resources that may change in a plan, see TestOptions.ExpectedModifiedChanges.
+// Resources are matched the same way as consistency exemptions, so `Updates` can also be limited to attributes.
+// Replacements are destroys, and must be listed in `Destroys`.
+type ExpectedChanges struct {
+	Adds     Exemptions
+	Updates  Exemptions
+	Destroys Exemptions
+}
+
+// getExpectedChangesConsistencyOptions returns the consistency options of the test with the expected changes added to
+// the exemptions. The exemption file is only used by the consistency checks, so that its expired entries are not
+// reported twice.
+func (options *TestOptions) getExpectedChangesConsistencyOptions(expected ExpectedChanges) *CheckConsistencyOptions {
+	checkOptions := options.GetCheckConsistencyOptions()
+	checkOptions.ExemptionFile = ""
+	checkOptions.IgnoreAdds = checkOptions.IgnoreAdds.merge(expected.Adds)
+	checkOptions.IgnoreUpdates = checkOptions.IgnoreUpdates.merge(expected.Updates)
+	checkOptions.IgnoreDestroys = checkOptions.IgnoreDestroys.merge(expected.Destroys)
+	return checkOptions
+}
+
+// getModifiedTerraformOptions returns a copy of the terraform options of the test that uses ModifiedTerraformVars, so
+// that all other options supplied by the test are kept
+func (options *TestOptions) getModifiedTerraformOptions() *terraform.Options {
+	if options.TerraformOptions == nil {
+		return terraform.WithDefaultRetryableErrors(options.Testing, &terraform.Options{
+			TerraformDir:    options.TerraformDir,
+			TerraformBinary: options.TerraformBinary,
+			Vars:            options.ModifiedTerraformVars,
+		})
+	}
+	modifiedOptions := *options.TerraformOptions
+	modifiedOptions.Vars = options.ModifiedTerraformVars
+	// apply the variables, and not a plan file left by an earlier plan
+	modifiedOptions.PlanFilePath = ""
+	return &modifiedOptions
+}
+
+// runModifiedApply applies ModifiedTerraformVars, and checks the consistency of a plan after the apply. If
+// ExpectedModifiedChanges is set the modified apply is planned and checked against the expected changes first.
+// Errors fail the test.
+func (options *TestOptions) runModifiedApply() error {
+	logger.Log(options.Testing, "Running modified apply with terraform vars")
+	options.TerraformOptions = options.getModifiedTerraformOptions()
+
+	if options.ExpectedModifiedChanges != nil {
+		logger.Log(options.Testing, "START: Modify Plan / Expected Changes Check")
+		plan, err := options.runTestPlan()
+		if err != nil {
+			return err
+		}
+		options.LastModifiedChangesReport = CheckConsistency(plan, options.getExpectedChangesConsistencyOptions(*options.ExpectedModifiedChanges))
+		if options.LastModifiedChangesReport.HasFailures() {
+			logger.Log(options.Testing, "Unexpected changes in modified apply:\n"+options.LastModifiedChangesReport.String())
+		}
+		logger.Log(options.Testing, "FINISHED: Modify Plan / Expected Changes Check")
+		options.TerraformOptions.PlanFilePath = ""
+	}
+
+	logger.Log(options.Testing, "START: Modify Apply")
+	options.recordTeardownJournal()
+	_, err := terraform.ApplyContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
+	assert.Nil(options.Testing, err, "Failed", err)
+	logger.Log(options.Testing, "FINISHED: Modify Apply")
+	if err != nil {
+		return err
+	}
+
+	logger.Log(options.Testing, "START: Modify Apply Consistency Check")
+	plan, err := options.runTestPlan()
+	if err != nil {
+		return err
+	}
+	options.modifiedApplyPlan = plan
+	options.LastConsistencyReport = CheckConsistency(plan, options)
+	logger.Log(options.Testing, "FINISHED: Modify Apply Consistency Check")
+	return nil
+}
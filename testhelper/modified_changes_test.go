@@ -0,0 +1,79 @@
+package testhelper
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectedChangesConsistencyOptions(t *testing.T) {
+	options := &TestOptions{
+		Testing:       t,
+		IgnoreUpdates: Exemptions{List: []string{"null_resource.refresh"}},
+		ExemptionFile: "exemptions.yaml",
+	}
+	expected := ExpectedChanges{
+		Adds:     Exemptions{List: []string{"module.vpc.ibm_is_public_gateway.gateway"}},
+		Updates:  Exemptions{Attributes: []AttributeExemption{{Address: "module.vpc.ibm_is_subnet.*", Paths: []string{"public_gateway"}}}},
+		Destroys: Exemptions{Patterns: []string{"module.vpc.ibm_is_vpc_address_prefix.*"}},
+	}
+	plan := newTestPlan(
+		newTestResourceChange("null_resource.refresh", "null_resource", tfjson.Actions{tfjson.ActionUpdate},
+			map[string]interface{}{"triggers": "a"}, map[string]interface{}{"triggers": "b"}),
+		newTestResourceChange("module.vpc.ibm_is_public_gateway.gateway", "ibm_is_public_gateway", tfjson.Actions{tfjson.ActionCreate},
+			nil, map[string]interface{}{"name": "gateway"}),
+		newTestResourceChange("module.vpc.ibm_is_subnet.subnet", "ibm_is_subnet", tfjson.Actions{tfjson.ActionUpdate},
+			map[string]interface{}{"name": "subnet", "public_gateway": nil}, map[string]interface{}{"name": "subnet", "public_gateway": "gateway"}),
+		newTestResourceChange("module.vpc.ibm_is_vpc_address_prefix.prefix", "ibm_is_vpc_address_prefix", tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate},
+			map[string]interface{}{"cidr": "10.0.0.0/24"}, map[string]interface{}{"cidr": "10.1.0.0/24"}),
+		newTestResourceChange("module.vpc.ibm_is_vpc.vpc", "ibm_is_vpc", tfjson.Actions{tfjson.ActionNoop},
+			map[string]interface{}{"name": "vpc"}, map[string]interface{}{"name": "vpc"}),
+	)
+
+	checkOptions := options.getExpectedChangesConsistencyOptions(expected)
+	assert.Empty(t, checkOptions.ExemptionFile, "the exemption file is only used by the consistency checks")
+	report := CheckConsistency(plan, checkOptions)
+	require.NotNil(t, report)
+	assert.False(t, report.HasFailures())
+	assert.Len(t, report.Exempted(), 4)
+	assert.Len(t, report.ViolationsByAction(ConsistencyActionReplace), 1)
+
+	// the exemptions of the test are not changed
+	assert.Equal(t, Exemptions{List: []string{"null_resource.refresh"}}, options.IgnoreUpdates)
+	assert.Empty(t, options.IgnoreAdds.List)
+	assert.Equal(t, "exemptions.yaml", options.ExemptionFile)
+}
+
+func TestModifiedTerraformOptions(t *testing.T) {
+	options := &TestOptions{
+		Testing:               t,
+		TerraformDir:          "examples/basic",
+		ModifiedTerraformVars: map[string]interface{}{"prefix": "modified"},
+		TerraformOptions: &terraform.Options{
+			TerraformDir:             "examples/basic",
+			TerraformBinary:          "tofu",
+			Vars:                     map[string]interface{}{"prefix": "original"},
+			EnvVars:                  map[string]string{"TF_LOG": "DEBUG"},
+			RetryableTerraformErrors: map[string]string{".*timeout.*": "retry on timeout"},
+			MaxRetries:               5,
+			Upgrade:                  true,
+			PlanFilePath:             "terratest-plan-file-1",
+		},
+	}
+
+	modified := options.getModifiedTerraformOptions()
+	assert.Equal(t, map[string]interface{}{"prefix": "modified"}, modified.Vars)
+	assert.Empty(t, modified.PlanFilePath)
+	assert.Equal(t, "tofu", modified.TerraformBinary)
+	assert.Equal(t, map[string]string{"TF_LOG": "DEBUG"}, modified.EnvVars)
+	assert.Equal(t, map[string]string{".*timeout.*": "retry on timeout"}, modified.RetryableTerraformErrors)
+	assert.Equal(t, 5, modified.MaxRetries)
+	assert.True(t, modified.Upgrade)
+
+	// the original options are not changed
+	assert.Equal(t, map[string]interface{}{"prefix": "original"}, options.TerraformOptions.Vars)
+	assert.Equal(t, "terratest-plan-file-1", options.TerraformOptions.PlanFilePath)
+}
@@ -0,0 +1,304 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// Kinds of breaking changes identified by CompareModuleInterfaces
+const (
+	ModuleChangeRemovedVariable     = "removed_variable"
+	ModuleChangeNewRequiredVariable = "new_required_variable"
+	ModuleChangeVariableNowRequired = "variable_now_required"
+	ModuleChangeVariableType        = "changed_variable_type"
+	ModuleChangeRemovedOutput       = "removed_output"
+)
+
+// ModuleInterface is the public interface of a terraform module, its input variables and outputs
+type ModuleInterface struct {
+	Variables map[string]ModuleVariable
+	Outputs   map[string]bool
+}
+
+// ModuleVariable is an input variable of a terraform module
+type ModuleVariable struct {
+	Name       string
+	Type       string // type constraint with whitespace removed, `any` if not set
+	HasDefault bool   // variables without a default are required
+}
+
+// ModuleInterfaceChange is a single breaking change between two versions of a module interface
+type ModuleInterfaceChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+// ModuleInterfaceReport is the result of comparing two versions of a module interface
+type ModuleInterfaceReport struct {
+	Changes []ModuleInterfaceChange `json:"changes"`
+}
+
+// HasBreakingChanges returns true if any breaking changes were identified
+func (report *ModuleInterfaceReport) HasBreakingChanges() bool {
+	return report != nil && len(report.Changes) > 0
+}
+
+// String renders the report as plain text suitable for test logs
+func (report *ModuleInterfaceReport) String() string {
+	if !report.HasBreakingChanges() {
+		return "Module Interface Report: no breaking changes"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Module Interface Report: %d breaking change(s)\n", len(report.Changes)))
+	for _, change := range report.Changes {
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", change.Kind, change.Name, change.Detail))
+	}
+	return sb.String()
+}
+
+// moduleInterfaceSchema is the schema of the variable and output blocks of terraform JSON files
+var moduleInterfaceSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+	},
+}
+
+// LoadModuleInterface parses all terraform (`*.tf` and `*.tf.json`) files in a directory and returns the variables
+// and outputs that are declared. No terraform commands or cloud calls are made.
+// Returns an error if the directory does not exist or does not contain terraform files.
+func LoadModuleInterface(dir string) (*ModuleInterface, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error reading terraform directory: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("terraform directory %s is not a directory", dir)
+	}
+	tfFiles, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing terraform files in %s: %w", dir, err)
+	}
+	tfJSONFiles, err := filepath.Glob(filepath.Join(dir, "*.tf.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing terraform files in %s: %w", dir, err)
+	}
+	if len(tfFiles) == 0 && len(tfJSONFiles) == 0 {
+		return nil, fmt.Errorf("no terraform files found in %s", dir)
+	}
+
+	moduleInterface := &ModuleInterface{
+		Variables: make(map[string]ModuleVariable),
+		Outputs:   make(map[string]bool),
+	}
+
+	parser := hclparse.NewParser()
+	for _, tfFile := range tfFiles {
+		file, diags := parser.ParseHCLFile(tfFile)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("error parsing %s: %s", tfFile, diags.Error())
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if len(block.Labels) != 1 {
+				continue
+			}
+			switch block.Type {
+			case "variable":
+				variable := ModuleVariable{Name: block.Labels[0], Type: "any"}
+				if typeAttr, exists := block.Body.Attributes["type"]; exists {
+					variable.Type = removeWhitespace(string(typeAttr.Expr.Range().SliceBytes(file.Bytes)))
+				}
+				_, variable.HasDefault = block.Body.Attributes["default"]
+				moduleInterface.Variables[variable.Name] = variable
+			case "output":
+				moduleInterface.Outputs[block.Labels[0]] = true
+			}
+		}
+	}
+
+	for _, tfJSONFile := range tfJSONFiles {
+		file, diags := parser.ParseJSONFile(tfJSONFile)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("error parsing %s: %s", tfJSONFile, diags.Error())
+		}
+		if err := addJSONModuleInterface(moduleInterface, file); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", tfJSONFile, err)
+		}
+	}
+
+	return moduleInterface, nil
+}
+
+// addJSONModuleInterface adds the variables and outputs declared in the body of a terraform JSON file. The type of a
+// variable is a string containing the type expression in JSON.
+func addJSONModuleInterface(moduleInterface *ModuleInterface, file *hcl.File) error {
+	content, _, diags := file.Body.PartialContent(moduleInterfaceSchema)
+	if diags.HasErrors() {
+		return diags
+	}
+	for _, block := range content.Blocks {
+		if block.Type == "output" {
+			moduleInterface.Outputs[block.Labels[0]] = true
+			continue
+		}
+		variable := ModuleVariable{Name: block.Labels[0], Type: "any"}
+		attributes, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return diags
+		}
+		if typeAttr, exists := attributes["type"]; exists {
+			var typeExpr string
+			if err := json.Unmarshal(typeAttr.Expr.Range().SliceBytes(file.Bytes), &typeExpr); err != nil {
+				return fmt.Errorf("type of variable %s is not a string: %w", variable.Name, err)
+			}
+			variable.Type = removeWhitespace(typeExpr)
+		}
+		_, variable.HasDefault = attributes["default"]
+		moduleInterface.Variables[variable.Name] = variable
+	}
+	return nil
+}
+
+// CompareModuleInterfaces returns the breaking changes from the base interface to the new interface:
+// removed variables, new required variables, variables that no longer have a default, changed variable types and removed outputs.
+func CompareModuleInterfaces(base *ModuleInterface, current *ModuleInterface) *ModuleInterfaceReport {
+	report := &ModuleInterfaceReport{}
+
+	for name, baseVariable := range base.Variables {
+		currentVariable, exists := current.Variables[name]
+		if !exists {
+			report.Changes = append(report.Changes, ModuleInterfaceChange{Kind: ModuleChangeRemovedVariable, Name: name, Detail: "variable was removed"})
+			continue
+		}
+		if baseVariable.Type != currentVariable.Type {
+			report.Changes = append(report.Changes, ModuleInterfaceChange{Kind: ModuleChangeVariableType, Name: name,
+				Detail: fmt.Sprintf("type changed from %s to %s", baseVariable.Type, currentVariable.Type)})
+		}
+		if baseVariable.HasDefault && !currentVariable.HasDefault {
+			report.Changes = append(report.Changes, ModuleInterfaceChange{Kind: ModuleChangeVariableNowRequired, Name: name, Detail: "default value was removed"})
+		}
+	}
+
+	for name, currentVariable := range current.Variables {
+		if _, exists := base.Variables[name]; !exists && !currentVariable.HasDefault {
+			report.Changes = append(report.Changes, ModuleInterfaceChange{Kind: ModuleChangeNewRequiredVariable, Name: name, Detail: "new variable without a default value"})
+		}
+	}
+
+	for name := range base.Outputs {
+		if !current.Outputs[name] {
+			report.Changes = append(report.Changes, ModuleInterfaceChange{Kind: ModuleChangeRemovedOutput, Name: name, Detail: "output was removed"})
+		}
+	}
+
+	// map iteration is random, keep report output stable
+	sort.Slice(report.Changes, func(i, j int) bool {
+		if report.Changes[i].Kind != report.Changes[j].Kind {
+			return report.Changes[i].Kind < report.Changes[j].Kind
+		}
+		return report.Changes[i].Name < report.Changes[j].Name
+	})
+
+	return report
+}
+
+// CheckModuleInterface compares the module interface in baseDir with the one in currentDir and fails the test for
+// every breaking change. This can be used standalone as it does not make any cloud calls.
+func CheckModuleInterface(t *testing.T, baseDir string, currentDir string) (*ModuleInterfaceReport, error) {
+	base, err := LoadModuleInterface(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	current, err := LoadModuleInterface(currentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := CompareModuleInterfaces(base, current)
+	for _, change := range report.Changes {
+		assert.Fail(t, fmt.Sprintf("Breaking change to module interface (%s) %s: %s", change.Kind, change.Name, change.Detail))
+	}
+
+	return report, nil
+}
+
+// removeWhitespace removes all whitespace so that type expressions can be compared regardless of formatting
+func removeWhitespace(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// RunTestModuleInterface compares the public interface (variables and outputs) of the TerraformDir module in the base
+// branch with the current branch, failing the test for any breaking changes. This does not apply any terraform or make any cloud
+// calls. The check is skipped using the same rules as RunTestUpgrade, for example a "BREAKING CHANGE" commit message,
+// in which case UpgradeTestSkipped is set to true.
+func (options *TestOptions) RunTestModuleInterface() (*ModuleInterfaceReport, error) {
+	skipped, err := ShouldSkipUpgradeTest(options.Testing, options.BaseTerraformRepo, options.BaseTerraformBranch)
+	if err != nil {
+		return nil, err
+	}
+	options.UpgradeTestSkipped = skipped
+	if skipped {
+		return nil, nil
+	}
+
+	gitRoot, err := common.GitRootPath(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git root path: %v", err)
+	}
+
+	baseRepo, baseBranch := common.GetBaseRepoAndBranch(options.BaseTerraformRepo, options.BaseTerraformBranch)
+	if baseBranch == "" || baseRepo == "" {
+		return nil, fmt.Errorf("failed to get default repo and branch: %s %s", baseRepo, baseBranch)
+	}
+
+	baseTempDir, err := os.MkdirTemp("", fmt.Sprintf("terraform-base-%s", options.Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for base branch: %v", err)
+	}
+	defer os.RemoveAll(baseTempDir) // clean up
+
+	if cloneErr := common.CloneAndCheckoutBranch(options.Testing, baseRepo, baseBranch, baseTempDir); cloneErr != nil {
+		return nil, cloneErr
+	}
+
+	// TerraformDir is relative to the git root, or an absolute path within it
+	relativeTerraformDir := strings.TrimPrefix(options.TerraformDir, gitRoot)
+	return options.checkModuleInterface(path.Join(baseTempDir, relativeTerraformDir), path.Join(gitRoot, relativeTerraformDir))
+}
+
+// checkModuleInterface runs CheckModuleInterface for the upgrade tests and stores the result in LastModuleInterfaceReport
+func (options *TestOptions) checkModuleInterface(baseDir string, currentDir string) (*ModuleInterfaceReport, error) {
+	logger.Log(options.Testing, "Checking module interface for breaking changes...")
+	report, err := CheckModuleInterface(options.Testing, baseDir, currentDir)
+	if err != nil {
+		assert.Nilf(options.Testing, err, "Module interface check has failed")
+		return nil, err
+	}
+	options.LastModuleInterfaceReport = report
+	logger.Log(options.Testing, report.String())
+
+	return report, nil
+}
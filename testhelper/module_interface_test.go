@@ -0,0 +1,146 @@
+package testhelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModuleFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestCompareModuleInterfaces(t *testing.T) {
+	baseDir := writeModuleFiles(t, map[string]string{
+		"variables.tf": `
+variable "prefix" {
+  type = string
+}
+variable "tags" {
+  type    = list(string)
+  default = []
+}
+variable "region" {
+  type    = string
+  default = "us-south"
+}
+variable "removed" {
+  default = null
+}
+`,
+		"outputs.tf": `
+output "id" {
+  value = "1"
+}
+output "removed_output" {
+  value = "2"
+}
+`,
+	})
+
+	currentDir := writeModuleFiles(t, map[string]string{
+		"variables.tf": `
+variable "prefix" {
+  type = string
+}
+variable "tags" {
+  type    = list( string )
+  default = []
+}
+variable "region" {
+  type = map(string)
+}
+variable "new_required" {
+  type = string
+}
+variable "new_optional" {
+  type    = string
+  default = null
+}
+`,
+		"outputs.tf": `
+output "id" {
+  value = "1"
+}
+`,
+		"main.tf": `
+output "new_output" {
+  value = "3"
+}
+`,
+	})
+
+	base, err := LoadModuleInterface(baseDir)
+	require.NoError(t, err)
+	assert.Len(t, base.Variables, 4)
+	assert.Equal(t, "list(string)", base.Variables["tags"].Type)
+	assert.Equal(t, "any", base.Variables["removed"].Type)
+	assert.False(t, base.Variables["prefix"].HasDefault)
+
+	current, err := LoadModuleInterface(currentDir)
+	require.NoError(t, err)
+	assert.True(t, current.Outputs["new_output"], "outputs in any .tf file should be found")
+
+	report := CompareModuleInterfaces(base, current)
+	assert.True(t, report.HasBreakingChanges())
+	assert.Equal(t, []ModuleInterfaceChange{
+		{Kind: ModuleChangeVariableType, Name: "region", Detail: "type changed from string to map(string)"},
+		{Kind: ModuleChangeNewRequiredVariable, Name: "new_required", Detail: "new variable without a default value"},
+		{Kind: ModuleChangeRemovedOutput, Name: "removed_output", Detail: "output was removed"},
+		{Kind: ModuleChangeRemovedVariable, Name: "removed", Detail: "variable was removed"},
+		{Kind: ModuleChangeVariableNowRequired, Name: "region", Detail: "default value was removed"},
+	}, report.Changes)
+	assert.Contains(t, report.String(), "5 breaking change(s)")
+}
+
+func TestCompareModuleInterfacesNoChanges(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"variables.tf": "variable \"prefix\" {\n  type = string\n}\n",
+	})
+
+	report, err := CheckModuleInterface(t, dir, dir)
+	require.NoError(t, err)
+	assert.False(t, report.HasBreakingChanges())
+}
+
+func TestLoadModuleInterfaceInvalid(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"variables.tf": "variable \"prefix\" {\n",
+	})
+
+	_, err := LoadModuleInterface(dir)
+	assert.Error(t, err)
+
+	_, err = LoadModuleInterface(filepath.Join(dir, "missing"))
+	assert.Error(t, err, "missing directory")
+
+	_, err = LoadModuleInterface(t.TempDir())
+	assert.ErrorContains(t, err, "no terraform files found", "directory without terraform files")
+}
+
+func TestLoadModuleInterfaceJSON(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"variables.tf": "variable \"prefix\" {\n  type = string\n}\n",
+		"extra.tf.json": `{
+  "variable": {
+    "tags": {"type": "list( string )", "default": []},
+    "region": {"description": "region of the resources"}
+  },
+  "output": {"id": {"value": "${var.prefix}"}}
+}`,
+	})
+
+	moduleInterface, err := LoadModuleInterface(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ModuleVariable{Name: "prefix", Type: "string"}, moduleInterface.Variables["prefix"])
+	assert.Equal(t, ModuleVariable{Name: "tags", Type: "list(string)", HasDefault: true}, moduleInterface.Variables["tags"])
+	assert.Equal(t, ModuleVariable{Name: "region", Type: "any"}, moduleInterface.Variables["region"])
+	assert.True(t, moduleInterface.Outputs["id"])
+}
@@ -0,0 +1,156 @@
+package testhelper
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// MovedBlockSuggestion is a suggested terraform `moved` block for a resource that is destroyed at one address and
+// created with the same type at another address in an upgrade plan
+type MovedBlockSuggestion struct {
+	From              string   `json:"from"`               // address of the destroyed resource
+	To                string   `json:"to"`                 // address of the created resource
+	Type              string   `json:"type"`               // terraform resource type of both resources
+	MatchedAttributes []string `json:"matched_attributes"` // attributes with the same value in both resources
+}
+
+// HCL returns the suggestion as a ready to paste terraform `moved` block
+func (suggestion MovedBlockSuggestion) HCL() string {
+	return fmt.Sprintf("moved {\n  from = %s\n  to   = %s\n}\n", suggestion.From, suggestion.To)
+}
+
+// SuggestMovedBlocks pairs up resources that are destroyed (without being replaced) with resources of the same type
+// that are created in the plan, and returns a `moved` block suggestion for each pair.
+//
+// Candidates are matched by their identifying attributes: resources with a different `name` are never paired, and
+// otherwise the create with the most top level attribute values in common with the destroyed resource is chosen.
+// If there is exactly one destroy and one create of a type they are paired even without common attributes.
+func SuggestMovedBlocks(plan *terraform.PlanStruct) []MovedBlockSuggestion {
+	var suggestions []MovedBlockSuggestion
+	if plan == nil {
+		return suggestions
+	}
+
+	deletesByType := make(map[string][]*tfjson.ResourceChange)
+	createsByType := make(map[string][]*tfjson.ResourceChange)
+	for _, resource := range plan.ResourceChangesMap {
+		if resource.Change == nil || resource.Mode == tfjson.DataResourceMode {
+			continue
+		}
+		key := resource.ProviderName + "/" + resource.Type
+		switch {
+		case resource.Change.Actions.Delete():
+			deletesByType[key] = append(deletesByType[key], resource)
+		case resource.Change.Actions.Create():
+			createsByType[key] = append(createsByType[key], resource)
+		}
+	}
+
+	for key, deletes := range deletesByType {
+		creates := createsByType[key]
+		if len(creates) == 0 {
+			continue
+		}
+		// map iteration is random, keep pairing stable
+		sort.Slice(deletes, func(i, j int) bool { return deletes[i].Address < deletes[j].Address })
+		sort.Slice(creates, func(i, j int) bool { return creates[i].Address < creates[j].Address })
+		onlyPair := len(deletes) == 1 && len(creates) == 1
+
+		paired := make(map[string]bool)
+		for _, deleted := range deletes {
+			var best *tfjson.ResourceChange
+			var bestMatches []string
+			for _, created := range creates {
+				if paired[created.Address] {
+					continue
+				}
+				matches, compatible := getMatchingIdentifyingAttributes(deleted.Change, created.Change)
+				if !compatible {
+					continue
+				}
+				if best == nil || len(matches) > len(bestMatches) {
+					best = created
+					bestMatches = matches
+				}
+			}
+			if best == nil || (len(bestMatches) == 0 && !onlyPair) {
+				continue
+			}
+			paired[best.Address] = true
+			suggestions = append(suggestions, MovedBlockSuggestion{
+				From:              deleted.Address,
+				To:                best.Address,
+				Type:              deleted.Type,
+				MatchedAttributes: bestMatches,
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].From < suggestions[j].From
+	})
+
+	return suggestions
+}
+
+// getMatchingIdentifyingAttributes returns the sorted names of the top level scalar attributes that have the same value
+// before the delete and after the create. Returns false if both resources have a `name` attribute with different values,
+// as they are then considered different resources.
+func getMatchingIdentifyingAttributes(deleted *tfjson.Change, created *tfjson.Change) ([]string, bool) {
+	before, beforeOk := deleted.Before.(map[string]interface{})
+	after, afterOk := created.After.(map[string]interface{})
+	if !beforeOk || !afterOk {
+		return nil, true
+	}
+
+	beforeName, beforeHasName := before["name"]
+	afterName, afterHasName := after["name"]
+	if beforeHasName && afterHasName && beforeName != nil && afterName != nil && !reflect.DeepEqual(beforeName, afterName) {
+		return nil, false
+	}
+
+	var matches []string
+	for key, beforeValue := range before {
+		afterValue, exists := after[key]
+		if !exists || beforeValue == nil || afterValue == nil || !isScalarAttributeValue(beforeValue) {
+			continue
+		}
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			matches = append(matches, key)
+		}
+	}
+	sort.Strings(matches)
+
+	return matches, true
+}
+
+// isScalarAttributeValue returns true for string, number and boolean values
+func isScalarAttributeValue(value interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool, int:
+		return true
+	default:
+		return false
+	}
+}
+
+// getMovedBlocksMessage builds the part of a consistency failure message with the suggested `moved` block,
+// returns an empty string if there is no suggestion
+func getMovedBlocksMessage(suggestion *MovedBlockSuggestion) string {
+	if suggestion == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\nResource appears to have moved to %s", suggestion.To))
+	if len(suggestion.MatchedAttributes) > 0 {
+		sb.WriteString(fmt.Sprintf(" (matching attributes: %s)", strings.Join(suggestion.MatchedAttributes, ", ")))
+	}
+	sb.WriteString(", consider adding this moved block:\n")
+	sb.WriteString(suggestion.HCL())
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package testhelper
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestMovedBlocks(t *testing.T) {
+	deleteAction := tfjson.Actions{tfjson.ActionDelete}
+	createAction := tfjson.Actions{tfjson.ActionCreate}
+
+	changes := []*tfjson.ResourceChange{
+		// renamed into a module, same name
+		newTestResourceChange("ibm_is_vpc.vpc", "ibm_is_vpc", deleteAction,
+			map[string]interface{}{"name": "my-vpc", "resource_group": "rg1", "id": "r006-1"}, nil),
+		newTestResourceChange(`module.vpc.ibm_is_vpc.vpc["a"]`, "ibm_is_vpc", createAction,
+			nil, map[string]interface{}{"name": "my-vpc", "resource_group": "rg1"}),
+		newTestResourceChange("module.vpc.ibm_is_vpc.other", "ibm_is_vpc", createAction,
+			nil, map[string]interface{}{"name": "other-vpc", "resource_group": "rg1"}),
+		// different name, not a move
+		newTestResourceChange("ibm_is_subnet.old", "ibm_is_subnet", deleteAction,
+			map[string]interface{}{"name": "subnet-a"}, nil),
+		newTestResourceChange("ibm_is_subnet.new", "ibm_is_subnet", createAction,
+			nil, map[string]interface{}{"name": "subnet-b"}),
+		// single delete and create of a type without common attributes
+		newTestResourceChange("null_resource.old", "null_resource", deleteAction,
+			map[string]interface{}{"id": "1"}, nil),
+		newTestResourceChange("null_resource.new", "null_resource", createAction,
+			nil, map[string]interface{}{}),
+		// replacements are not moves
+		newTestResourceChange("time_sleep.wait", "time_sleep", tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate},
+			map[string]interface{}{"create_duration": "30s"}, map[string]interface{}{"create_duration": "30s"}),
+	}
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+	for _, change := range changes {
+		plan.ResourceChangesMap[change.Address] = change
+	}
+
+	suggestions := SuggestMovedBlocks(plan)
+	require.Len(t, suggestions, 2)
+
+	assert.Equal(t, "ibm_is_vpc.vpc", suggestions[0].From)
+	assert.Equal(t, `module.vpc.ibm_is_vpc.vpc["a"]`, suggestions[0].To)
+	assert.Equal(t, []string{"name", "resource_group"}, suggestions[0].MatchedAttributes)
+	assert.Equal(t, "moved {\n  from = ibm_is_vpc.vpc\n  to   = module.vpc.ibm_is_vpc.vpc[\"a\"]\n}\n", suggestions[0].HCL())
+
+	assert.Equal(t, "null_resource.old", suggestions[1].From)
+	assert.Equal(t, "null_resource.new", suggestions[1].To)
+	assert.Empty(t, suggestions[1].MatchedAttributes)
+
+	assert.Empty(t, SuggestMovedBlocks(nil))
+}
+
+func TestConsistencyReportMovedBlocks(t *testing.T) {
+	report := &ConsistencyReport{
+		IsUpgradeTest: true,
+		Violations: []ConsistencyViolation{
+			{Address: "null_resource.a", ActionType: ConsistencyActionDelete},
+			{Address: "null_resource.b", ActionType: ConsistencyActionDelete},
+		},
+		MovedBlockSuggestions: []MovedBlockSuggestion{
+			{From: "null_resource.a", To: "module.m.null_resource.a", Type: "null_resource"},
+			{From: "null_resource.b", To: "module.m.null_resource.b", Type: "null_resource"},
+		},
+	}
+
+	hcl := report.MovedBlocksHCL()
+	assert.Equal(t, "moved {\n  from = null_resource.a\n  to   = module.m.null_resource.a\n}\n\nmoved {\n  from = null_resource.b\n  to   = module.m.null_resource.b\n}\n", hcl)
+	assert.Contains(t, report.String(), "Suggested moved blocks:")
+	assert.Contains(t, report.Markdown(), "```hcl\n"+hcl+"```")
+	assert.Contains(t, getMovedBlocksMessage(&report.MovedBlockSuggestions[0]), "consider adding this moved block")
+	assert.Empty(t, getMovedBlocksMessage(nil))
+}
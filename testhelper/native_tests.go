@@ -0,0 +1,262 @@
+package testhelper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// Status of a run block, a test file or of all tests, in a NativeTestResult
+const (
+	NativeTestStatusPass  = "pass"
+	NativeTestStatusFail  = "fail"
+	NativeTestStatusError = "error"
+	NativeTestStatusSkip  = "skip"
+)
+
+// NativeTestDiagnostic is a warning or error reported by `terraform test`
+type NativeTestDiagnostic struct {
+	Severity string `json:"severity"` // error or warning
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+}
+
+// NativeTestRun is the result of a run block of a test file
+type NativeTestRun struct {
+	File        string // path of the test file, for example tests/main.tftest.hcl
+	Name        string // name of the run block
+	Status      string // one of the NativeTestStatus values, empty if the run did not complete
+	Diagnostics []NativeTestDiagnostic
+}
+
+// NativeTestResult is the result of RunTerraformNativeTests
+type NativeTestResult struct {
+	Status string // status of all tests, empty if the summary was not reported
+	Runs   []NativeTestRun
+
+	// Diagnostics that do not belong to a run block, for example an invalid test file
+	Diagnostics []NativeTestDiagnostic
+}
+
+// Failed returns true if a run block or the tests as a whole did not pass or skip
+func (result *NativeTestResult) Failed() bool {
+	if result.Status != NativeTestStatusPass && result.Status != NativeTestStatusSkip {
+		return true
+	}
+	for _, run := range result.Runs {
+		if run.Status != NativeTestStatusPass && run.Status != NativeTestStatusSkip {
+			return true
+		}
+	}
+	return false
+}
+
+// nativeTestMessage is a line of the machine-readable output of `terraform test -json`
+type nativeTestMessage struct {
+	Type       string                `json:"type"`
+	TestFile   string                `json:"@testfile"`
+	TestRun    string                `json:"@testrun"`
+	Abstract   map[string][]string   `json:"test_abstract"`
+	Run        *nativeTestRunMessage `json:"test_run"`
+	Summary    *nativeTestSummary    `json:"test_summary"`
+	Diagnostic *NativeTestDiagnostic `json:"diagnostic"`
+}
+
+type nativeTestRunMessage struct {
+	Path     string `json:"path"`
+	Run      string `json:"run"`
+	Progress string `json:"progress"`
+	Status   string `json:"status"`
+}
+
+type nativeTestSummary struct {
+	Status string `json:"status"`
+}
+
+// RunTerraformNativeTests runs the `.tftest.hcl` files of TerraformDir with `terraform test -json` (or `tofu test` if
+// TerraformBinary is tofu) in the temporary working directory of the test, and runs a subtest of options.Testing for
+// every run block, named `<test file>/<run block>`, that fails with the diagnostics of the run block.
+// Variables are taken from TerraformVars and the VarFiles of TerraformOptions, and the Prefix and Region of the test are
+// also set as the `prefix` and `region` variables of test files that declare them but do not get them from TerraformVars.
+// terraform destroys the resources of the run blocks, so the teardown of the test is not run, only the temporary working
+// directory is removed.
+// Extra arguments are passed to `terraform test`, for example `-filter=tests/basic.tftest.hcl`.
+// Returns an error if terraform could not be run, failed tests are reported to options.Testing and in the result.
+func (options *TestOptions) RunTerraformNativeTests(args ...string) (*NativeTestResult, error) {
+	defer options.finishNativeTests()
+	if err := options.testSetup(); err != nil {
+		return nil, err
+	}
+
+	logger.Log(options.Testing, "START: Init / Native Tests")
+	testOptions := options.getNativeTestTerraformOptions()
+	if _, err := terraform.InitContextE(options.Testing, options.getRunContext(), testOptions); err != nil {
+		assert.Nil(options.Testing, err, "Failed", err)
+		return nil, err
+	}
+
+	testArgs := getNativeTestArgs(testOptions, args)
+	// the JSON output is reported as subtests and is not logged, and failed tests are not retried as their resources
+	// were already created and destroyed by terraform
+	testOptions.Logger = logger.Discard
+	testOptions.RetryableTerraformErrors = nil
+	output, testErr := terraform.RunTerraformCommandAndGetStdoutContextE(options.Testing, options.getRunContext(), testOptions, testArgs...)
+
+	result := parseNativeTestOutput(output)
+	if result.Status == "" && testErr != nil {
+		// terraform stopped before reporting a result, for example because of an invalid argument
+		assert.Nil(options.Testing, testErr, "Failed", testErr)
+		return result, testErr
+	}
+	options.reportNativeTestResult(result)
+	logger.Log(options.Testing, fmt.Sprintf("FINISHED: Init / Native Tests, status %s", result.Status))
+
+	return result, nil
+}
+
+// finishNativeTests releases the run context and the region lease, and removes the temporary working directory of
+// the native tests
+func (options *TestOptions) finishNativeTests() {
+	options.releaseRunContext()
+	options.releaseRegionLease()
+	if options.tempWorkingDir != "" {
+		if err := os.RemoveAll(options.tempWorkingDir); err != nil {
+			logger.Log(options.Testing, "WARNING: could not remove temporary working directory: ", err)
+		}
+		options.tempWorkingDir = ""
+	}
+}
+
+// getNativeTestArgs returns the arguments of `terraform test` with the variables and variable files of the options,
+// followed by the extra arguments
+func getNativeTestArgs(testOptions *terraform.Options, args []string) []string {
+	testArgs := append([]string{"test", "-json", "-no-color"}, terraform.FormatTerraformVarsAsArgs(testOptions.Vars)...)
+	for _, varFile := range testOptions.VarFiles {
+		testArgs = append(testArgs, "-var-file="+varFile)
+	}
+	return append(testArgs, args...)
+}
+
+// getNativeTestTerraformOptions returns a copy of the terraform options of the test that sets the prefix and region
+// variables as environment variables, so that they are ignored by test files that do not declare them
+func (options *TestOptions) getNativeTestTerraformOptions() *terraform.Options {
+	testOptions := *options.TerraformOptions
+	testOptions.EnvVars = make(map[string]string, len(options.TerraformOptions.EnvVars)+2)
+	for key, value := range options.TerraformOptions.EnvVars {
+		testOptions.EnvVars[key] = value
+	}
+	defaults := map[string]string{"prefix": options.Prefix, "region": options.Region}
+	for name, value := range defaults {
+		envName := "TF_VAR_" + name
+		if _, found := testOptions.Vars[name]; found || value == "" {
+			continue
+		}
+		if _, found := testOptions.EnvVars[envName]; !found {
+			testOptions.EnvVars[envName] = value
+		}
+	}
+	return &testOptions
+}
+
+// reportNativeTestResult runs a subtest for each run block of the result, and fails the test for diagnostics that do
+// not belong to a run block
+func (options *TestOptions) reportNativeTestResult(result *NativeTestResult) {
+	for _, diagnostic := range result.Diagnostics {
+		if diagnostic.Severity == "error" {
+			assert.Fail(options.Testing, fmt.Sprintf("terraform test: %s", formatNativeTestDiagnostic(diagnostic)))
+		} else {
+			logger.Log(options.Testing, fmt.Sprintf("terraform test: %s", formatNativeTestDiagnostic(diagnostic)))
+		}
+	}
+
+	for _, run := range result.Runs {
+		options.Testing.Run(run.File+"/"+run.Name, func(t *testing.T) {
+			for _, diagnostic := range run.Diagnostics {
+				t.Log(formatNativeTestDiagnostic(diagnostic))
+			}
+			switch run.Status {
+			case NativeTestStatusPass:
+			case NativeTestStatusSkip:
+				t.Skip("run block was skipped")
+			case "":
+				t.Error("run block did not complete")
+			default:
+				t.Errorf("run block status is %s", run.Status)
+			}
+		})
+	}
+}
+
+// parseNativeTestOutput reads the machine-readable output of `terraform test -json`, lines that are not JSON are ignored
+func parseNativeTestOutput(output string) *NativeTestResult {
+	result := &NativeTestResult{}
+	runIndexes := make(map[string]int)
+	getRun := func(file string, name string) *NativeTestRun {
+		key := file + "/" + name
+		index, found := runIndexes[key]
+		if !found {
+			index = len(result.Runs)
+			runIndexes[key] = index
+			result.Runs = append(result.Runs, NativeTestRun{File: file, Name: name})
+		}
+		return &result.Runs[index]
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var message nativeTestMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			continue
+		}
+		switch message.Type {
+		case "test_abstract":
+			// lists every run block before the tests start
+			files := make([]string, 0, len(message.Abstract))
+			for file := range message.Abstract {
+				files = append(files, file)
+			}
+			sort.Strings(files)
+			for _, file := range files {
+				for _, name := range message.Abstract[file] {
+					getRun(file, name)
+				}
+			}
+		case "test_run":
+			if message.Run != nil && message.Run.Progress == "complete" {
+				getRun(message.Run.Path, message.Run.Run).Status = message.Run.Status
+			}
+		case "diagnostic":
+			if message.Diagnostic == nil {
+				continue
+			}
+			if message.TestFile != "" && message.TestRun != "" {
+				run := getRun(message.TestFile, message.TestRun)
+				run.Diagnostics = append(run.Diagnostics, *message.Diagnostic)
+			} else {
+				result.Diagnostics = append(result.Diagnostics, *message.Diagnostic)
+			}
+		case "test_summary":
+			if message.Summary != nil {
+				result.Status = message.Summary.Status
+			}
+		}
+	}
+	return result
+}
+
+func formatNativeTestDiagnostic(diagnostic NativeTestDiagnostic) string {
+	text := fmt.Sprintf("%s: %s", diagnostic.Severity, diagnostic.Summary)
+	if diagnostic.Detail != "" {
+		text += "\n" + diagnostic.Detail
+	}
+	return text
+}
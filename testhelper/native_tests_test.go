@@ -0,0 +1,101 @@
+package testhelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNativeTestOutput = `{"@level":"info","@message":"Terraform 1.9.5","@module":"terraform.ui","type":"version","terraform":"1.9.5","ui":"1.2"}
+{"@level":"info","@message":"Found 2 files and 3 run blocks","@module":"terraform.ui","type":"test_abstract","test_abstract":{"tests/vpc.tftest.hcl":["create_vpc","check_subnets"],"tests/basic.tftest.hcl":["plan_only"]}}
+{"@level":"info","@message":"tests/basic.tftest.hcl... in progress","@module":"terraform.ui","@testfile":"tests/basic.tftest.hcl","type":"test_file","test_file":{"path":"tests/basic.tftest.hcl","progress":"starting"}}
+{"@level":"info","@message":"  \"plan_only\"... in progress","@module":"terraform.ui","@testfile":"tests/basic.tftest.hcl","@testrun":"plan_only","type":"test_run","test_run":{"path":"tests/basic.tftest.hcl","run":"plan_only","progress":"starting","elapsed":0}}
+{"@level":"info","@message":"  \"plan_only\"... pass","@module":"terraform.ui","@testfile":"tests/basic.tftest.hcl","@testrun":"plan_only","type":"test_run","test_run":{"path":"tests/basic.tftest.hcl","run":"plan_only","progress":"complete","status":"pass"}}
+{"@level":"info","@message":"  \"create_vpc\"... pass","@module":"terraform.ui","@testfile":"tests/vpc.tftest.hcl","@testrun":"create_vpc","type":"test_run","test_run":{"path":"tests/vpc.tftest.hcl","run":"create_vpc","progress":"complete","status":"pass"}}
+{"@level":"error","@message":"Error: Test assertion failed","@module":"terraform.ui","@testfile":"tests/vpc.tftest.hcl","@testrun":"check_subnets","type":"diagnostic","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"expected 3 subnets"}}
+{"@level":"info","@message":"  \"check_subnets\"... fail","@module":"terraform.ui","@testfile":"tests/vpc.tftest.hcl","@testrun":"check_subnets","type":"test_run","test_run":{"path":"tests/vpc.tftest.hcl","run":"check_subnets","progress":"complete","status":"fail"}}
+{"@level":"warn","@message":"Warning: Deprecated attribute","@module":"terraform.ui","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Deprecated attribute","detail":""}}
+not a json line
+{"@level":"info","@message":"Failure! 2 passed, 1 failed.","@module":"terraform.ui","type":"test_summary","test_summary":{"status":"fail","passed":2,"failed":1,"errored":0,"skipped":0}}
+`
+
+func TestParseNativeTestOutput(t *testing.T) {
+	result := parseNativeTestOutput(testNativeTestOutput)
+
+	assert.Equal(t, NativeTestStatusFail, result.Status)
+	assert.True(t, result.Failed())
+	require.Len(t, result.Runs, 3)
+
+	// run blocks are listed in the order of the files
+	assert.Equal(t, NativeTestRun{File: "tests/basic.tftest.hcl", Name: "plan_only", Status: NativeTestStatusPass}, result.Runs[0])
+	assert.Equal(t, NativeTestRun{File: "tests/vpc.tftest.hcl", Name: "create_vpc", Status: NativeTestStatusPass}, result.Runs[1])
+	assert.Equal(t, NativeTestRun{
+		File:   "tests/vpc.tftest.hcl",
+		Name:   "check_subnets",
+		Status: NativeTestStatusFail,
+		Diagnostics: []NativeTestDiagnostic{
+			{Severity: "error", Summary: "Test assertion failed", Detail: "expected 3 subnets"},
+		},
+	}, result.Runs[2])
+	assert.Equal(t, []NativeTestDiagnostic{{Severity: "warning", Summary: "Deprecated attribute"}}, result.Diagnostics)
+
+	t.Run("Passed", func(t *testing.T) {
+		result := &NativeTestResult{Status: NativeTestStatusPass, Runs: []NativeTestRun{
+			{Name: "one", Status: NativeTestStatusPass},
+			{Name: "two", Status: NativeTestStatusSkip},
+		}}
+		assert.False(t, result.Failed())
+
+		result.Runs = append(result.Runs, NativeTestRun{Name: "interrupted"})
+		assert.True(t, result.Failed())
+	})
+
+	t.Run("NoSummary", func(t *testing.T) {
+		result := parseNativeTestOutput("")
+		assert.Empty(t, result.Status)
+		assert.True(t, result.Failed())
+	})
+}
+
+func TestNativeTestTerraformOptions(t *testing.T) {
+	options := &TestOptions{
+		Testing: t,
+		Prefix:  "native-abc123",
+		Region:  "eu-de",
+		TerraformOptions: &terraform.Options{
+			TerraformBinary: "tofu",
+			Vars:            map[string]interface{}{"region": "us-south"},
+			EnvVars:         map[string]string{"TF_LOG": "DEBUG"},
+		},
+	}
+
+	testOptions := options.getNativeTestTerraformOptions()
+	assert.Equal(t, "tofu", testOptions.TerraformBinary)
+	assert.Equal(t, map[string]string{"TF_LOG": "DEBUG", "TF_VAR_prefix": "native-abc123"}, testOptions.EnvVars,
+		"region is not set as it is a variable of the test")
+	assert.Equal(t, map[string]string{"TF_LOG": "DEBUG"}, options.TerraformOptions.EnvVars, "the test options are not changed")
+}
+
+func TestNativeTestArgs(t *testing.T) {
+	testOptions := &terraform.Options{
+		Vars:     map[string]interface{}{"prefix": "native"},
+		VarFiles: []string{"tests/test.tfvars"},
+	}
+	assert.Equal(t, []string{"test", "-json", "-no-color", "-var", "prefix=native", "-var-file=tests/test.tfvars", "-filter=tests/basic.tftest.hcl"},
+		getNativeTestArgs(testOptions, []string{"-filter=tests/basic.tftest.hcl"}))
+}
+
+func TestFinishNativeTests(t *testing.T) {
+	tempDir := t.TempDir()
+	workingDir := filepath.Join(tempDir, "terraform-native")
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, "examples", "basic"), 0755))
+	options := &TestOptions{Testing: t, tempWorkingDir: workingDir}
+
+	options.finishNativeTests()
+	assert.NoDirExists(t, workingDir, "the temporary working directory is removed")
+	assert.Empty(t, options.tempWorkingDir)
+}
@@ -0,0 +1,280 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OutputType is the terraform type of an output value, see OutputSpec
+type OutputType string
+
+const (
+	OutputTypeAny    OutputType = ""
+	OutputTypeString OutputType = "string"
+	OutputTypeNumber OutputType = "number"
+	OutputTypeBool   OutputType = "bool"
+	OutputTypeList   OutputType = "list"   // terraform list, set or tuple
+	OutputTypeObject OutputType = "object" // terraform map or object
+)
+
+// OutputSpec describes an expected terraform output, see ValidateTerraformOutputSpecs.
+// Values are never printed in errors, as outputs can be sensitive.
+type OutputSpec struct {
+	// Name of the output, or for Paths the dot separated path in the parent value, where list indexes are numbers and `*`
+	// matches every element of a list or object, for example `subnets.*.crn`
+	Name string
+	// Type of the value, OutputTypeAny to not check the type
+	Type OutputType
+	// Optional allows the value to be missing or null, the other checks only apply if it is set
+	Optional bool
+	// NonEmpty requires strings, lists and objects to not be empty
+	NonEmpty bool
+	// Pattern is a regular expression that string values must match, for example `^crn:v1:`
+	Pattern string
+	// AllowedValues are the only values allowed, compared as JSON so numbers of any Go type match
+	AllowedValues []interface{}
+	// Paths are checks of values nested in the value
+	Paths []OutputSpec
+}
+
+// ValidateTerraformOutputSpecs checks the outputs, for example LastTestTerraformOutputs, against the specs.
+// Returns an error listing every output that does not match its spec, or nil.
+func ValidateTerraformOutputSpecs(outputs map[string]interface{}, specs ...OutputSpec) error {
+	var errs []error
+	for _, spec := range specs {
+		value, found := outputs[spec.Name]
+		normalized, err := getPlanComparableValue(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("output '%s': value can not be read: %w", spec.Name, err))
+			continue
+		}
+		errs = append(errs, validateOutputValue(spec, "output '"+spec.Name+"'", normalized, found)...)
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateOutputs checks LastTestTerraformOutputs against the specs, see ValidateTerraformOutputSpecs
+func (options *TestOptions) ValidateOutputs(specs ...OutputSpec) error {
+	return ValidateTerraformOutputSpecs(options.LastTestTerraformOutputs, specs...)
+}
+
+// validateOutputValue checks a normalized value against the spec, name describes the value in errors
+func validateOutputValue(spec OutputSpec, name string, value interface{}, found bool) []error {
+	if !found || value == nil {
+		if spec.Optional {
+			return nil
+		}
+		if !found {
+			return []error{fmt.Errorf("%s was not found", name)}
+		}
+		return []error{fmt.Errorf("%s was not expected to be nil", name)}
+	}
+
+	var errs []error
+	if actual := getOutputType(value); spec.Type != OutputTypeAny && actual != spec.Type {
+		// the other checks depend on the type
+		return []error{fmt.Errorf("%s is of type %s, expected %s", name, actual, spec.Type)}
+	}
+
+	if spec.NonEmpty {
+		switch typed := value.(type) {
+		case string:
+			if len(strings.TrimSpace(typed)) == 0 {
+				errs = append(errs, fmt.Errorf("%s was not expected to be a blank string", name))
+			}
+		case []interface{}:
+			if len(typed) == 0 {
+				errs = append(errs, fmt.Errorf("%s was not expected to be an empty list", name))
+			}
+		case map[string]interface{}:
+			if len(typed) == 0 {
+				errs = append(errs, fmt.Errorf("%s was not expected to be an empty object", name))
+			}
+		}
+	}
+
+	if spec.Pattern != "" {
+		re, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid pattern %s: %w", name, spec.Pattern, err))
+		} else if text, ok := value.(string); !ok {
+			errs = append(errs, fmt.Errorf("%s is of type %s, pattern %s only applies to strings", name, getOutputType(value), spec.Pattern))
+		} else if !re.MatchString(text) {
+			errs = append(errs, fmt.Errorf("%s does not match pattern %s", name, spec.Pattern))
+		}
+	}
+
+	if len(spec.AllowedValues) > 0 && !isAllowedOutputValue(value, spec.AllowedValues) {
+		errs = append(errs, fmt.Errorf("%s is not one of the %d allowed values", name, len(spec.AllowedValues)))
+	}
+
+	for _, pathSpec := range spec.Paths {
+		segments := strings.Split(pathSpec.Name, ".")
+		errs = append(errs, validateOutputPath(pathSpec, name, segments, value)...)
+	}
+	return errs
+}
+
+// validateOutputPath walks the path segments in the value and checks the values found against the spec
+func validateOutputPath(spec OutputSpec, name string, segments []string, value interface{}) []error {
+	if len(segments) == 0 {
+		return validateOutputValue(spec, name, value, true)
+	}
+
+	segment := segments[0]
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if segment == "*" {
+			var errs []error
+			keys := make([]string, 0, len(typed))
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				errs = append(errs, validateOutputPath(spec, name+"."+key, segments[1:], typed[key])...)
+			}
+			return errs
+		}
+		element, found := typed[segment]
+		if !found {
+			return validateOutputValue(spec, name+"."+strings.Join(segments, "."), nil, false)
+		}
+		return validateOutputPath(spec, name+"."+segment, segments[1:], element)
+	case []interface{}:
+		if segment == "*" {
+			var errs []error
+			for index, element := range typed {
+				errs = append(errs, validateOutputPath(spec, fmt.Sprintf("%s.%d", name, index), segments[1:], element)...)
+			}
+			return errs
+		}
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(typed) {
+			return validateOutputValue(spec, name+"."+strings.Join(segments, "."), nil, false)
+		}
+		return validateOutputPath(spec, name+"."+segment, segments[1:], typed[index])
+	default:
+		return validateOutputValue(spec, name+"."+strings.Join(segments, "."), nil, false)
+	}
+}
+
+// getOutputType returns the terraform type of a normalized value
+func getOutputType(value interface{}) OutputType {
+	switch value.(type) {
+	case string:
+		return OutputTypeString
+	case float64:
+		return OutputTypeNumber
+	case bool:
+		return OutputTypeBool
+	case []interface{}:
+		return OutputTypeList
+	case map[string]interface{}:
+		return OutputTypeObject
+	default:
+		return OutputType(fmt.Sprintf("%T", value))
+	}
+}
+
+func isAllowedOutputValue(value interface{}, allowedValues []interface{}) bool {
+	for _, allowed := range allowedValues {
+		normalized, err := getPlanComparableValue(allowed)
+		if err == nil && reflect.DeepEqual(value, normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeOutputs unmarshals terraform outputs, for example LastTestTerraformOutputs, into a value of type T.
+// If T is a struct, each exported field is read from the output named by its `json` tag (or the field name), and the
+// error lists every missing output and every output whose value does not fit the type of its field. Fields tagged with
+// `omitempty` may be missing. Other types are unmarshalled from the outputs as a JSON object.
+func DecodeOutputs[T any](outputs map[string]interface{}) (T, error) {
+	var result T
+	resultValue := reflect.ValueOf(&result).Elem()
+	if resultValue.Kind() != reflect.Struct {
+		outputsBytes, err := json.Marshal(outputs)
+		if err != nil {
+			return result, fmt.Errorf("error reading outputs: %w", err)
+		}
+		if err := json.Unmarshal(outputsBytes, &result); err != nil {
+			return result, fmt.Errorf("error decoding outputs into %T: %w", result, err)
+		}
+		return result, nil
+	}
+
+	var errs []error
+	resultType := resultValue.Type()
+	for index := 0; index < resultType.NumField(); index++ {
+		field := resultType.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		name, optional := getOutputFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		value, found := outputs[name]
+		if !found || value == nil {
+			if !optional {
+				errs = append(errs, fmt.Errorf("output '%s' for field %s was not found", name, field.Name))
+			}
+			continue
+		}
+
+		valueBytes, err := json.Marshal(value)
+		if err == nil {
+			err = json.Unmarshal(valueBytes, resultValue.Field(index).Addr().Interface())
+		}
+		if err != nil {
+			errs = append(errs, getOutputDecodeError(name, field, value, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// getOutputFieldName returns the output name of a struct field and if the output is optional
+func getOutputFieldName(field reflect.StructField) (string, bool) {
+	tag, hasTag := field.Tag.Lookup("json")
+	if !hasTag {
+		return field.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	optional := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			optional = true
+		}
+	}
+	return name, optional
+}
+
+// getOutputDecodeError describes an output that could not be decoded into a struct field, without printing the value
+func getOutputDecodeError(name string, field reflect.StructField, value interface{}, err error) error {
+	normalized, _ := getPlanComparableValue(value)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		location := ""
+		if typeErr.Field != "" {
+			location = " at " + typeErr.Field
+		}
+		// the Value of the error is the JSON kind, followed by the value for numbers
+		kind := strings.Fields(typeErr.Value + " value")[0]
+		return fmt.Errorf("output '%s' for field %s: %s value%s does not fit Go type %s", name, field.Name, kind, location, typeErr.Type)
+	}
+	// other errors, for example of the UnmarshalJSON method of the field type, may contain the value
+	return fmt.Errorf("output '%s' of type %s can not be decoded into field %s of Go type %s", name, getOutputType(normalized), field.Name, field.Type)
+}
@@ -0,0 +1,145 @@
+package testhelper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOutputs = map[string]interface{}{
+	"vpc_crn":      "crn:v1:bluemix:public:is:us-south:a/account::vpc:r006-1",
+	"vpc_name":     "test-vpc",
+	"subnet_count": float64(3),
+	"public":       false,
+	"blank":        " ",
+	"zones":        []interface{}{"us-south-1", "us-south-2"},
+	"empty_list":   []interface{}{},
+	"subnets": []interface{}{
+		map[string]interface{}{"id": "subnet-1", "crn": "crn:v1:subnet-1"},
+		map[string]interface{}{"id": "subnet-2", "crn": "not-a-crn"},
+	},
+	"tags":     map[string]interface{}{"env": "test"},
+	"api_key":  "secret-api-key",
+	"nullable": nil,
+}
+
+func TestValidateTerraformOutputSpecs(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		err := ValidateTerraformOutputSpecs(testOutputs,
+			OutputSpec{Name: "vpc_crn", Type: OutputTypeString, Pattern: "^crn:v1:"},
+			OutputSpec{Name: "subnet_count", Type: OutputTypeNumber, AllowedValues: []interface{}{1, 3}},
+			OutputSpec{Name: "public", Type: OutputTypeBool},
+			OutputSpec{Name: "zones", Type: OutputTypeList, NonEmpty: true, Paths: []OutputSpec{
+				{Name: "0", Type: OutputTypeString, AllowedValues: []interface{}{"us-south-1"}},
+			}},
+			OutputSpec{Name: "subnets", Type: OutputTypeList, Paths: []OutputSpec{
+				{Name: "*.id", Type: OutputTypeString, Pattern: "^subnet-"},
+				{Name: "*.name", Optional: true},
+			}},
+			OutputSpec{Name: "tags", Type: OutputTypeObject, NonEmpty: true, Paths: []OutputSpec{{Name: "env", AllowedValues: []interface{}{"test"}}}},
+			OutputSpec{Name: "nullable", Optional: true, Type: OutputTypeString},
+			OutputSpec{Name: "missing", Optional: true},
+		)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		err := ValidateTerraformOutputSpecs(testOutputs,
+			OutputSpec{Name: "vpc_name", Pattern: "^crn:v1:"},
+			OutputSpec{Name: "subnet_count", Type: OutputTypeString},
+			OutputSpec{Name: "public", AllowedValues: []interface{}{true}},
+			OutputSpec{Name: "blank", NonEmpty: true},
+			OutputSpec{Name: "empty_list", NonEmpty: true},
+			OutputSpec{Name: "subnets", Paths: []OutputSpec{{Name: "*.crn", Pattern: "^crn:v1:"}, {Name: "2.id"}}},
+			OutputSpec{Name: "tags", Paths: []OutputSpec{{Name: "owner"}}},
+			OutputSpec{Name: "api_key", AllowedValues: []interface{}{"other-api-key"}},
+			OutputSpec{Name: "nullable"},
+			OutputSpec{Name: "missing"},
+		)
+		require.Error(t, err)
+		message := err.Error()
+		assert.Contains(t, message, "output 'vpc_name' does not match pattern ^crn:v1:")
+		assert.Contains(t, message, "output 'subnet_count' is of type number, expected string")
+		assert.Contains(t, message, "output 'public' is not one of the 1 allowed values")
+		assert.Contains(t, message, "output 'blank' was not expected to be a blank string")
+		assert.Contains(t, message, "output 'empty_list' was not expected to be an empty list")
+		assert.Contains(t, message, "output 'subnets'.1.crn does not match pattern ^crn:v1:")
+		assert.NotContains(t, message, "output 'subnets'.0.crn")
+		assert.Contains(t, message, "output 'subnets'.2.id was not found")
+		assert.Contains(t, message, "output 'tags'.owner was not found")
+		assert.Contains(t, message, "output 'nullable' was not expected to be nil")
+		assert.Contains(t, message, "output 'missing' was not found")
+		assert.NotContains(t, message, "secret-api-key", "values are not printed")
+		assert.NotContains(t, message, "not-a-crn", "values are not printed")
+	})
+
+	t.Run("TestOptions", func(t *testing.T) {
+		options := &TestOptions{LastTestTerraformOutputs: testOutputs}
+		assert.NoError(t, options.ValidateOutputs(OutputSpec{Name: "vpc_name", Type: OutputTypeString}))
+		assert.Error(t, options.ValidateOutputs(OutputSpec{Name: "vpc_name", Type: OutputTypeList}))
+	})
+}
+
+func TestDecodeOutputs(t *testing.T) {
+	type subnet struct {
+		ID  string `json:"id"`
+		CRN string `json:"crn"`
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		type outputs struct {
+			VpcCrn      string            `json:"vpc_crn"`
+			SubnetCount int               `json:"subnet_count"`
+			Public      bool              `json:"public"`
+			Subnets     []subnet          `json:"subnets"`
+			Tags        map[string]string `json:"tags"`
+			Missing     string            `json:"missing,omitempty"`
+			Ignored     string            `json:"-"`
+			unexported  string
+		}
+		decoded, err := DecodeOutputs[outputs](testOutputs)
+		require.NoError(t, err)
+		assert.Equal(t, "crn:v1:bluemix:public:is:us-south:a/account::vpc:r006-1", decoded.VpcCrn)
+		assert.Equal(t, 3, decoded.SubnetCount)
+		assert.Equal(t, []subnet{{ID: "subnet-1", CRN: "crn:v1:subnet-1"}, {ID: "subnet-2", CRN: "not-a-crn"}}, decoded.Subnets)
+		assert.Equal(t, map[string]string{"env": "test"}, decoded.Tags)
+		assert.Empty(t, decoded.unexported)
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		type outputs struct {
+			VpcCrn      int      `json:"vpc_crn"`
+			SubnetCount uint8    `json:"subnet_count"`
+			Subnets     []string `json:"subnets"`
+			Zones       []string `json:"zones"`
+			Missing     string   `json:"missing"`
+		}
+		decoded, err := DecodeOutputs[outputs](testOutputs)
+		require.Error(t, err)
+		message := err.Error()
+		assert.Contains(t, message, "output 'vpc_crn' for field VpcCrn: string value does not fit Go type int")
+		assert.Contains(t, message, "output 'subnets' for field Subnets: object value at 0 does not fit Go type string")
+		assert.Contains(t, message, "output 'missing' for field Missing was not found")
+		assert.NotContains(t, message, "crn:v1", "values are not printed")
+		assert.Equal(t, uint8(3), decoded.SubnetCount)
+		assert.Equal(t, []string{"us-south-1", "us-south-2"}, decoded.Zones, "fields that fit are decoded")
+	})
+
+	t.Run("UnmarshalerError", func(t *testing.T) {
+		type outputs struct {
+			Created time.Time `json:"vpc_crn"`
+		}
+		_, err := DecodeOutputs[outputs](testOutputs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "output 'vpc_crn' of type string can not be decoded into field Created of Go type time.Time")
+		assert.NotContains(t, err.Error(), "crn:v1", "values are not printed")
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		decoded, err := DecodeOutputs[map[string]interface{}](map[string]interface{}{"a": "b"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"a": "b"}, decoded)
+	})
+}
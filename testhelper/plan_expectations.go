@@ -0,0 +1,230 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// PlanExpectation asserts the resource changes of a terraform plan, see ExpectPlan.
+// Failed expectations are reported to the test and do not stop it, like the assert package.
+type PlanExpectation struct {
+	t    assert.TestingT
+	plan *terraform.PlanStruct
+}
+
+// ResourceExpectation asserts the changes of the resources matched by PlanExpectation.Resource
+type ResourceExpectation struct {
+	t         assert.TestingT
+	address   string
+	resources []*tfjson.ResourceChange
+}
+
+// ExpectPlan returns an expectation of the plan that reports failures to t, for example:
+//
+//	testhelper.ExpectPlan(t, plan).
+//		CountOfType("ibm_is_subnet", 3).
+//		NoResourcesOfType("ibm_is_public_gateway").
+//		Resource("module.vpc.ibm_is_vpc.vpc").WillBeCreated().Attribute("classic_access", false)
+//
+// Sensitive attribute values are never printed in failures.
+func ExpectPlan(t assert.TestingT, plan *terraform.PlanStruct) *PlanExpectation {
+	return &PlanExpectation{t: t, plan: plan}
+}
+
+// ExpectPlan returns an expectation of the plan, for example the plan of RunTestPlan, that reports failures to
+// options.Testing, see the ExpectPlan function
+func (options *TestOptions) ExpectPlan(plan *terraform.PlanStruct) *PlanExpectation {
+	return ExpectPlan(options.Testing, plan)
+}
+
+// Resource returns an expectation of the resources whose address matches the glob style pattern, where `*` matches any
+// sequence of characters and `?` a single character, for example `module.vpc.ibm_is_subnet.subnet[*]` matches all
+// `count` or `for_each` instances. Fails if no resource matches.
+func (expect *PlanExpectation) Resource(address string) *ResourceExpectation {
+	resources := expect.matchResources(func(resource *tfjson.ResourceChange) bool {
+		return matchAddressGlob(address, resource.Address)
+	})
+	if len(resources) == 0 {
+		assert.Fail(expect.t, fmt.Sprintf("plan: no resource matches %s", address))
+	}
+	return &ResourceExpectation{t: expect.t, address: address, resources: resources}
+}
+
+// CountOfType fails if the number of managed resources of the type that exist after apply (created, updated, replaced
+// or unchanged) is not count
+func (expect *PlanExpectation) CountOfType(resourceType string, count int) *PlanExpectation {
+	resources := expect.matchResources(func(resource *tfjson.ResourceChange) bool {
+		return isPlannedResourceOfType(resource, resourceType)
+	})
+	if len(resources) != count {
+		assert.Fail(expect.t, fmt.Sprintf("plan: %d resources of type %s, expected %d", len(resources), resourceType, count),
+			getResourceAddresses(resources))
+	}
+	return expect
+}
+
+// NoResourcesOfType fails if a managed resource of the type exists after apply
+func (expect *PlanExpectation) NoResourcesOfType(resourceType string) *PlanExpectation {
+	resources := expect.matchResources(func(resource *tfjson.ResourceChange) bool {
+		return isPlannedResourceOfType(resource, resourceType)
+	})
+	if len(resources) > 0 {
+		assert.Fail(expect.t, fmt.Sprintf("plan: expected no resources of type %s", resourceType), getResourceAddresses(resources))
+	}
+	return expect
+}
+
+// matchResources returns the resource changes of the plan that match, ordered by address
+func (expect *PlanExpectation) matchResources(match func(resource *tfjson.ResourceChange) bool) []*tfjson.ResourceChange {
+	var resources []*tfjson.ResourceChange
+	if expect.plan == nil {
+		return resources
+	}
+	for _, resource := range expect.plan.ResourceChangesMap {
+		if resource != nil && resource.Change != nil && match(resource) {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Address < resources[j].Address })
+	return resources
+}
+
+// Count fails if the number of resources matched is not count
+func (expect *ResourceExpectation) Count(count int) *ResourceExpectation {
+	if len(expect.resources) != count {
+		assert.Fail(expect.t, fmt.Sprintf("plan: %d resources match %s, expected %d", len(expect.resources), expect.address, count),
+			getResourceAddresses(expect.resources))
+	}
+	return expect
+}
+
+// WillBeCreated fails if a matched resource is not created, replacements are not creations
+func (expect *ResourceExpectation) WillBeCreated() *ResourceExpectation {
+	return expect.expectAction("created", tfjson.Actions.Create)
+}
+
+// WillBeUpdated fails if a matched resource is not updated in place
+func (expect *ResourceExpectation) WillBeUpdated() *ResourceExpectation {
+	return expect.expectAction("updated", tfjson.Actions.Update)
+}
+
+// WillBeReplaced fails if a matched resource is not replaced
+func (expect *ResourceExpectation) WillBeReplaced() *ResourceExpectation {
+	return expect.expectAction("replaced", tfjson.Actions.Replace)
+}
+
+// WillBeDestroyed fails if a matched resource is not destroyed, replacements are not destructions
+func (expect *ResourceExpectation) WillBeDestroyed() *ResourceExpectation {
+	return expect.expectAction("destroyed", tfjson.Actions.Delete)
+}
+
+// WillNotChange fails if a matched resource changes
+func (expect *ResourceExpectation) WillNotChange() *ResourceExpectation {
+	return expect.expectAction("unchanged", tfjson.Actions.NoOp)
+}
+
+func (expect *ResourceExpectation) expectAction(expected string, isAction func(tfjson.Actions) bool) *ResourceExpectation {
+	for _, resource := range expect.resources {
+		if !isAction(resource.Change.Actions) {
+			assert.Fail(expect.t, fmt.Sprintf("plan: %s will be %s, expected to be %s", resource.Address,
+				getPlanActionDescription(resource.Change.Actions), expected))
+		}
+	}
+	return expect
+}
+
+// Attribute fails if the planned value of the attribute of a matched resource is not the expected value.
+// The path is a dot separated list of attribute names and list indexes, for example `boot_volume.0.name`.
+// Values are compared as JSON, so numbers of any type match. Values that are only known after apply never match.
+func (expect *ResourceExpectation) Attribute(path string, expected interface{}) *ResourceExpectation {
+	segments := strings.Split(path, ".")
+	expectedValue, err := getPlanComparableValue(expected)
+	if err != nil {
+		assert.Fail(expect.t, fmt.Sprintf("plan: expected value of attribute %s can not be compared: %s", path, err))
+		return expect
+	}
+
+	for _, resource := range expect.resources {
+		if unknown, ok := lookupAttributePath(resource.Change.AfterUnknown, segments).(bool); ok && unknown {
+			assert.Fail(expect.t, fmt.Sprintf("plan: %s attribute %s is %s", resource.Address, path, replaceCauseUnknownValue))
+			continue
+		}
+
+		actualValue, err := getPlanComparableValue(lookupAttributePath(resource.Change.After, segments))
+		if err == nil && reflect.DeepEqual(actualValue, expectedValue) {
+			continue
+		}
+
+		// only show the sanitized value, and not the expected value of a sensitive attribute either
+		sanitized := lookupAttributePath(sanitizeChangeValue(resource.Change.After, getMergedSensitive(resource.Change)), segments)
+		if isSanitizedValue(sanitized) {
+			assert.Fail(expect.t, fmt.Sprintf("plan: %s attribute %s is sensitive and does not match the expected value", resource.Address, path))
+		} else {
+			assert.Fail(expect.t, fmt.Sprintf("plan: %s attribute %s is %s, expected %s", resource.Address, path,
+				formatReplaceCauseValue(sanitized), formatReplaceCauseValue(expectedValue)))
+		}
+	}
+	return expect
+}
+
+// isPlannedResourceOfType reports if the change is of a managed resource of the type that exists after apply
+func isPlannedResourceOfType(resource *tfjson.ResourceChange, resourceType string) bool {
+	if resource.Mode == tfjson.DataResourceMode || resource.Type != resourceType {
+		return false
+	}
+	return !resource.Change.Actions.Delete() && !resource.Change.Actions.Forget()
+}
+
+// getPlanActionDescription describes the actions of a change as in "will be ..."
+func getPlanActionDescription(actions tfjson.Actions) string {
+	switch {
+	case actions.Create():
+		return "created"
+	case actions.Update():
+		return "updated"
+	case actions.Replace():
+		return "replaced"
+	case actions.Delete():
+		return "destroyed"
+	case actions.NoOp():
+		return "unchanged"
+	case actions.Read():
+		return "read"
+	default:
+		return fmt.Sprint(actions)
+	}
+}
+
+// getPlanComparableValue converts a value into its decoded JSON form, so that plan values, outputs and Go values compare
+// equal
+func getPlanComparableValue(value interface{}) (interface{}, error) {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	err = json.Unmarshal(valueBytes, &decoded)
+	return decoded, err
+}
+
+// isSanitizedValue reports if the value was hidden by common.SanitizeSensitiveData or could not be sanitized
+func isSanitizedValue(value interface{}) bool {
+	text, ok := value.(string)
+	return ok && (strings.HasPrefix(text, common.SANITIZE_STRING) || text == "Error sanitizing sensitive data")
+}
+
+func getResourceAddresses(resources []*tfjson.ResourceChange) string {
+	addresses := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	return strings.Join(addresses, ", ")
+}
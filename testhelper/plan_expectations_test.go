@@ -0,0 +1,102 @@
+package testhelper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planTestingMock records the failures of plan expectations
+type planTestingMock struct {
+	failures []string
+}
+
+func (mock *planTestingMock) Errorf(format string, args ...interface{}) {
+	mock.failures = append(mock.failures, fmt.Sprintf(format, args...))
+}
+
+func newTestPlan(changes ...*tfjson.ResourceChange) *terraform.PlanStruct {
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+	for _, change := range changes {
+		plan.ResourceChangesMap[change.Address] = change
+	}
+	return plan
+}
+
+func TestExpectPlan(t *testing.T) {
+	createAction := tfjson.Actions{tfjson.ActionCreate}
+	vpc := newTestResourceChange("module.vpc.ibm_is_vpc.vpc", "ibm_is_vpc", createAction,
+		nil, map[string]interface{}{"classic_access": false, "name": "test-vpc", "tags": []interface{}{"a", "b"}})
+	vpc.Change.AfterUnknown = map[string]interface{}{"crn": true}
+	key := newTestResourceChange("ibm_iam_api_key.key", "ibm_iam_api_key", createAction,
+		nil, map[string]interface{}{"apikey": "secret-api-key", "name": "key"})
+	key.Change.AfterSensitive = map[string]interface{}{"apikey": true}
+	plan := newTestPlan(
+		vpc,
+		key,
+		newTestResourceChange(`module.vpc.ibm_is_subnet.subnet["zone-1"]`, "ibm_is_subnet", createAction, nil, map[string]interface{}{"ipv4_cidr_block_size": 256}),
+		newTestResourceChange(`module.vpc.ibm_is_subnet.subnet["zone-2"]`, "ibm_is_subnet", createAction, nil, map[string]interface{}{"ipv4_cidr_block_size": 256}),
+		newTestResourceChange(`module.vpc.ibm_is_subnet.subnet["zone-3"]`, "ibm_is_subnet", tfjson.Actions{tfjson.ActionNoop}, nil, map[string]interface{}{"ipv4_cidr_block_size": 256}),
+		newTestResourceChange("ibm_is_subnet.old", "ibm_is_subnet", tfjson.Actions{tfjson.ActionDelete}, map[string]interface{}{}, nil),
+		newTestResourceChange("ibm_is_public_gateway.gateway", "ibm_is_public_gateway", tfjson.Actions{tfjson.ActionDelete}, map[string]interface{}{}, nil),
+	)
+
+	t.Run("Passing", func(t *testing.T) {
+		mock := &planTestingMock{}
+		ExpectPlan(mock, plan).
+			CountOfType("ibm_is_subnet", 3).
+			NoResourcesOfType("ibm_is_public_gateway").
+			NoResourcesOfType("ibm_is_vpn_gateway").
+			Resource("module.vpc.ibm_is_vpc.vpc").WillBeCreated().Count(1).
+			Attribute("classic_access", false).
+			Attribute("tags", []string{"a", "b"}).
+			Attribute("tags.1", "b")
+		ExpectPlan(mock, plan).Resource("module.vpc.ibm_is_subnet.subnet[*]").Count(3).Attribute("ipv4_cidr_block_size", 256)
+		ExpectPlan(mock, plan).Resource("ibm_is_public_gateway.gateway").WillBeDestroyed()
+		ExpectPlan(mock, plan).Resource("ibm_iam_api_key.key").Attribute("apikey", "secret-api-key")
+		assert.Empty(t, mock.failures)
+	})
+
+	t.Run("Failing", func(t *testing.T) {
+		mock := &planTestingMock{}
+		ExpectPlan(mock, plan).
+			CountOfType("ibm_is_subnet", 2).
+			NoResourcesOfType("ibm_is_vpc").
+			Resource("module.vpc.ibm_is_subnet.subnet[*]").WillBeCreated()
+		ExpectPlan(mock, plan).Resource("module.vpc.ibm_is_vpc.vpc").
+			Attribute("classic_access", true).
+			Attribute("crn", "crn:v1").
+			WillBeUpdated()
+		ExpectPlan(mock, plan).Resource("module.missing.*")
+		failures := strings.Join(mock.failures, "\n")
+
+		require.Len(t, mock.failures, 7)
+		assert.Contains(t, failures, "plan: 3 resources of type ibm_is_subnet, expected 2")
+		assert.Contains(t, failures, "plan: expected no resources of type ibm_is_vpc")
+		assert.Contains(t, failures, `plan: module.vpc.ibm_is_subnet.subnet["zone-3"] will be unchanged, expected to be created`)
+		assert.Contains(t, failures, "plan: module.vpc.ibm_is_vpc.vpc attribute classic_access is false, expected true")
+		assert.Contains(t, failures, "plan: module.vpc.ibm_is_vpc.vpc attribute crn is (known after apply)")
+		assert.Contains(t, failures, "plan: module.vpc.ibm_is_vpc.vpc will be created, expected to be updated")
+		assert.Contains(t, failures, "plan: no resource matches module.missing.*")
+	})
+
+	t.Run("SensitiveValuesAreNotPrinted", func(t *testing.T) {
+		mock := &planTestingMock{}
+		ExpectPlan(mock, plan).Resource("ibm_iam_api_key.key").Attribute("apikey", "other-api-key")
+		require.Len(t, mock.failures, 1)
+		assert.Contains(t, mock.failures[0], "attribute apikey is sensitive")
+		assert.NotContains(t, mock.failures[0], "secret-api-key")
+		assert.NotContains(t, mock.failures[0], "other-api-key")
+	})
+
+	t.Run("NoPlan", func(t *testing.T) {
+		mock := &planTestingMock{}
+		ExpectPlan(mock, nil).NoResourcesOfType("ibm_is_vpc").CountOfType("ibm_is_vpc", 0)
+		assert.Empty(t, mock.failures)
+	})
+}
@@ -0,0 +1,249 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// CheckTerraformVariables parses the terraform files of dir and returns an error listing the passed variables that are
+// not declared by the module, and the required variables (declared without a default) that are neither passed nor set.
+// Set variables are variables that may also be supplied to the module in another way, for example by TF_VAR_ environment
+// variables, and are not required to be declared. No terraform commands are run.
+func CheckTerraformVariables(dir string, passed []string, set []string) error {
+	return checkTerraformVariables(dir, passed, set, true)
+}
+
+// CheckUndeclaredTerraformVariables parses the terraform files of dir and returns an error listing the passed variables
+// that are not declared by the module. Required variables that are not passed are not checked, see
+// CheckTerraformVariables. No terraform commands are run.
+func CheckUndeclaredTerraformVariables(dir string, passed []string) error {
+	return checkTerraformVariables(dir, passed, nil, false)
+}
+
+// checkTerraformVariables checks the passed variables of CheckTerraformVariables, and the required variables if checkRequired is set
+func checkTerraformVariables(dir string, passed []string, set []string, checkRequired bool) error {
+	moduleInterface, err := LoadModuleInterface(dir)
+	if err != nil {
+		return err
+	}
+
+	supplied := make(map[string]bool)
+	var undeclared []string
+	for _, name := range passed {
+		supplied[name] = true
+		if _, declared := moduleInterface.Variables[name]; !declared {
+			undeclared = append(undeclared, name)
+		}
+	}
+	for _, name := range set {
+		supplied[name] = true
+	}
+
+	var missing []string
+	for name, variable := range moduleInterface.Variables {
+		if checkRequired && !variable.HasDefault && !supplied[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(undeclared)
+	sort.Strings(missing)
+
+	var errs []error
+	if len(undeclared) > 0 {
+		errs = append(errs, fmt.Errorf("variable [%s] passed in test but not declared in %s", strings.Join(undeclared, ", "), dir))
+	}
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("required variable [%s] of %s not passed in test", strings.Join(missing, ", "), dir))
+	}
+	return errors.Join(errs...)
+}
+
+// RunPreflightChecks validates the module of the test without creating resources: TerraformVars are checked with
+// CheckTerraformVariables, and `terraform validate` and `terraform fmt -check` are run in the terraform directory.
+// Every problem is reported as a test failure, with the file and line of terraform diagnostics, and the returned error
+// lists them all. The checks run in the working directory of the test, so call it after setup (see TestSetup), or set
+// PreflightChecks to run the checks during test setup.
+func (options *TestOptions) RunPreflightChecks() error {
+	logger.Log(options.Testing, "START: Preflight Checks")
+	terraformOptions := options.getPreflightTerraformOptions()
+
+	var errs []error
+	passed, set, err := getPreflightVariableNames(terraformOptions)
+	if err == nil {
+		err = CheckTerraformVariables(terraformOptions.TerraformDir, passed, set)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, options.runPreflightValidate(terraformOptions)...)
+	errs = append(errs, options.runPreflightFmt(terraformOptions)...)
+
+	err = options.reportPreflightErrors(errs)
+	logger.Log(options.Testing, "FINISHED: Preflight Checks")
+	return err
+}
+
+// runModulePreflightChecks parses the terraform files of TerraformDir, and checks that the TerraformVars already set
+// are declared by the module. No terraform commands are run. TestOptionsDefault runs it before the region lookup, so
+// that a module with errors fails without calling the region APIs.
+func (options *TestOptions) runModulePreflightChecks() error {
+	// TerraformDir is relative to the git root, or an absolute path within it, as the test is not set up yet
+	gitRoot, err := common.GitRootPath(".")
+	if err != nil {
+		return options.reportPreflightErrors([]error{fmt.Errorf("error getting git root path: %w", err)})
+	}
+	terraformDir := path.Join(gitRoot, strings.TrimPrefix(options.TerraformDir, gitRoot))
+
+	passed := make([]string, 0, len(options.TerraformVars))
+	for name := range options.TerraformVars {
+		passed = append(passed, name)
+	}
+	if err := CheckUndeclaredTerraformVariables(terraformDir, passed); err != nil {
+		return options.reportPreflightErrors([]error{err})
+	}
+	return nil
+}
+
+// reportPreflightErrors fails the test for every error, and returns the errors joined
+func (options *TestOptions) reportPreflightErrors(errs []error) error {
+	err := errors.Join(errs...)
+	if err != nil {
+		for _, preflightErr := range errs {
+			assert.Fail(options.Testing, fmt.Sprintf("preflight: %s", preflightErr))
+		}
+	}
+	return err
+}
+
+// getPreflightTerraformOptions returns a copy of the terraform options of the test, or options built from the test
+// before setup, that do not retry or log the output of terraform
+func (options *TestOptions) getPreflightTerraformOptions() *terraform.Options {
+	preflightOptions := terraform.Options{
+		TerraformDir:    options.TerraformDir,
+		TerraformBinary: options.TerraformBinary,
+		Vars:            options.TerraformVars,
+	}
+	if options.TerraformOptions != nil {
+		preflightOptions = *options.TerraformOptions
+	}
+	preflightOptions.RetryableTerraformErrors = nil
+	preflightOptions.Logger = logger.Discard
+	return &preflightOptions
+}
+
+// getPreflightVariableNames returns the names of the variables passed with Vars, and the names of the variables set
+// with var files and TF_VAR_ environment variables
+func getPreflightVariableNames(terraformOptions *terraform.Options) ([]string, []string, error) {
+	var passed, set []string
+	for name := range terraformOptions.Vars {
+		passed = append(passed, name)
+	}
+
+	environment := os.Environ()
+	for name, value := range terraformOptions.EnvVars {
+		environment = append(environment, name+"="+value)
+	}
+	for _, entry := range environment {
+		if name, _, found := strings.Cut(entry, "="); found && strings.HasPrefix(name, "TF_VAR_") {
+			set = append(set, strings.TrimPrefix(name, "TF_VAR_"))
+		}
+	}
+
+	parser := hclparse.NewParser()
+	for _, varFile := range terraformOptions.VarFiles {
+		if !filepath.IsAbs(varFile) {
+			varFile = filepath.Join(terraformOptions.TerraformDir, varFile)
+		}
+		parseFile := parser.ParseHCLFile
+		if strings.HasSuffix(varFile, ".json") {
+			parseFile = parser.ParseJSONFile
+		}
+		file, diags := parseFile(varFile)
+		if diags.HasErrors() {
+			return nil, nil, fmt.Errorf("error parsing %s: %s", varFile, diags.Error())
+		}
+		attributes, diags := file.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, nil, fmt.Errorf("error reading variables of %s: %s", varFile, diags.Error())
+		}
+		for name := range attributes {
+			set = append(set, name)
+		}
+	}
+	return passed, set, nil
+}
+
+// runPreflightValidate runs `terraform validate -json` and returns an error for each error diagnostic, warnings are logged
+func (options *TestOptions) runPreflightValidate(terraformOptions *terraform.Options) []error {
+	// validate needs the providers and modules, but no backend
+	if _, err := terraform.RunTerraformCommandAndGetStdoutContextE(options.Testing, options.getRunContext(), terraformOptions,
+		"init", "-backend=false", "-input=false", "-no-color"); err != nil {
+		return []error{fmt.Errorf("terraform init failed: %w", err)}
+	}
+
+	// validate exits with an error if the configuration is not valid, the diagnostics are in the output
+	output, validateErr := terraform.RunTerraformCommandAndGetStdoutContextE(options.Testing, options.getRunContext(), terraformOptions,
+		"validate", "-json", "-no-color")
+	var validation tfjson.ValidateOutput
+	if err := json.Unmarshal([]byte(output), &validation); err != nil {
+		if validateErr != nil {
+			return []error{fmt.Errorf("terraform validate failed: %w", validateErr)}
+		}
+		return []error{fmt.Errorf("error reading terraform validate output: %w", err)}
+	}
+
+	var errs []error
+	for _, diagnostic := range validation.Diagnostics {
+		if diagnostic.Severity == tfjson.DiagnosticSeverityError {
+			errs = append(errs, errors.New(formatValidateDiagnostic(diagnostic)))
+		} else {
+			logger.Log(options.Testing, "preflight: "+formatValidateDiagnostic(diagnostic))
+		}
+	}
+	if len(errs) == 0 && !validation.Valid {
+		errs = append(errs, fmt.Errorf("terraform validate reported %d error(s)", validation.ErrorCount))
+	}
+	return errs
+}
+
+// runPreflightFmt runs `terraform fmt -check` and returns an error for each file that is not formatted
+func (options *TestOptions) runPreflightFmt(terraformOptions *terraform.Options) []error {
+	// fmt exits with an error if files are not formatted, and lists them one per line
+	output, fmtErr := terraform.RunTerraformCommandAndGetStdoutContextE(options.Testing, options.getRunContext(), terraformOptions,
+		"fmt", "-check", "-list=true", "-no-color")
+	var errs []error
+	for _, file := range strings.Split(output, "\n") {
+		if file = strings.TrimSpace(file); file != "" {
+			errs = append(errs, fmt.Errorf("%s is not formatted, run terraform fmt", file))
+		}
+	}
+	if len(errs) == 0 && fmtErr != nil {
+		errs = append(errs, fmt.Errorf("terraform fmt failed: %w", fmtErr))
+	}
+	return errs
+}
+
+// formatValidateDiagnostic formats a diagnostic as `file:line: summary: detail`
+func formatValidateDiagnostic(diagnostic tfjson.Diagnostic) string {
+	text := diagnostic.Summary
+	if diagnostic.Detail != "" {
+		text += ": " + diagnostic.Detail
+	}
+	if diagnostic.Range != nil && diagnostic.Range.Filename != "" {
+		text = fmt.Sprintf("%s:%d: %s", diagnostic.Range.Filename, diagnostic.Range.Start.Line, text)
+	}
+	return text
+}
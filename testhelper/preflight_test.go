@@ -0,0 +1,117 @@
+package testhelper
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTerraformVariables(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"variables.tf": `
+variable "ibmcloud_api_key" {
+  type      = string
+  sensitive = true
+}
+variable "prefix" {
+  type = string
+}
+variable "region" {
+  type    = string
+  default = "us-south"
+}
+`,
+		// a declaration in a comment or string is not a variable
+		"main.tf": `
+# variable "commented" {}
+locals {
+  text = "variable \"in_string\" {}"
+}
+`,
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, CheckTerraformVariables(dir, []string{"prefix", "region"}, []string{"ibmcloud_api_key"}))
+	})
+
+	t.Run("Undeclared", func(t *testing.T) {
+		err := CheckTerraformVariables(dir, []string{"prefix", "commented", "in_string"}, []string{"ibmcloud_api_key", "unused"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "variable [commented, in_string] passed in test but not declared in")
+		assert.NotContains(t, err.Error(), "unused")
+	})
+
+	t.Run("MissingRequired", func(t *testing.T) {
+		err := CheckTerraformVariables(dir, []string{"region"}, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "required variable [ibmcloud_api_key, prefix] of")
+	})
+
+	t.Run("UndeclaredOnly", func(t *testing.T) {
+		assert.NoError(t, CheckUndeclaredTerraformVariables(dir, []string{"region"}), "required variables are not checked")
+		err := CheckUndeclaredTerraformVariables(dir, []string{"region", "commented"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "variable [commented] passed in test but not declared in")
+	})
+
+	t.Run("InvalidModule", func(t *testing.T) {
+		invalidDir := writeModuleFiles(t, map[string]string{"main.tf": `variable "prefix" {`})
+		assert.Error(t, CheckTerraformVariables(invalidDir, nil, nil))
+	})
+}
+
+func TestPreflightVariableNames(t *testing.T) {
+	t.Setenv("TF_VAR_ibmcloud_api_key", "test-key")
+	dir := writeModuleFiles(t, map[string]string{
+		"test.tfvars":      "zone = \"us-south-1\"\ntags = [\"a\"]\n",
+		"test.tfvars.json": `{"resource_group": "default"}`,
+	})
+	terraformOptions := &terraform.Options{
+		TerraformDir: dir,
+		Vars:         map[string]interface{}{"prefix": "test"},
+		EnvVars:      map[string]string{"TF_VAR_region": "eu-de", "TF_LOG": "DEBUG"},
+		VarFiles:     []string{"test.tfvars", filepath.Join(dir, "test.tfvars.json")},
+	}
+
+	passed, set, err := getPreflightVariableNames(terraformOptions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prefix"}, passed)
+	assert.Subset(t, set, []string{"ibmcloud_api_key", "region", "zone", "tags", "resource_group"})
+	assert.NotContains(t, set, "TF_LOG")
+
+	terraformOptions.VarFiles = []string{"missing.tfvars"}
+	_, _, err = getPreflightVariableNames(terraformOptions)
+	assert.Error(t, err)
+}
+
+func TestOptionsDefaultModulePreflightChecks(t *testing.T) {
+	t.Setenv("TF_VAR_ibmcloud_api_key", "test-key")
+	// TerraformDir is relative to the git root, and the test runs in the testhelper directory
+	options := TestOptionsDefault(&TestOptions{
+		Testing:         t,
+		TerraformDir:    "sample/terraform/sample1",
+		Prefix:          "preflight",
+		Region:          "us-south",
+		PreflightChecks: true,
+		TerraformVars:   map[string]interface{}{"prefix": "preflight", "region": "us-south"},
+	})
+	assert.NoError(t, options.preflightErr)
+}
+
+func TestFormatValidateDiagnostic(t *testing.T) {
+	diagnostic := tfjson.Diagnostic{
+		Severity: tfjson.DiagnosticSeverityError,
+		Summary:  "Unsupported argument",
+		Detail:   `An argument named "nme" is not expected here.`,
+		Range:    &tfjson.Range{Filename: "main.tf", Start: tfjson.Pos{Line: 12, Column: 3}},
+	}
+	assert.Equal(t, `main.tf:12: Unsupported argument: An argument named "nme" is not expected here.`, formatValidateDiagnostic(diagnostic))
+
+	diagnostic.Range = nil
+	diagnostic.Detail = ""
+	assert.Equal(t, "Unsupported argument", formatValidateDiagnostic(diagnostic))
+}
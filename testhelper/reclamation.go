@@ -0,0 +1,83 @@
+package testhelper
+
+import (
+	"context"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// collectReclamationCRNs returns the CRNs of the resource instances whose reclamations are purged after the destroy,
+// see PurgeReclamations. It is run before the destroy, while the state and outputs still hold the resources.
+func (options *TestOptions) collectReclamationCRNs(ctx context.Context) []string {
+	if options.PurgeReclamations == nil || len(options.PurgeReclamations.Services) == 0 {
+		return nil
+	}
+
+	var crns []string
+	if options.PurgeReclamations.CrnOutput != "" {
+		value, found := options.LastTestTerraformOutputs[options.PurgeReclamations.CrnOutput]
+		if !found {
+			logger.Log(options.Testing, "WARNING: output ", options.PurgeReclamations.CrnOutput, " not found, no reclamations will be purged")
+			return nil
+		}
+		crns = common.CollectCRNsFromValue(value)
+	} else {
+		// Turn off logging for this step so sensitive data in the state is not logged
+		previousLogger := options.TerraformOptions.Logger
+		options.TerraformOptions.Logger = logger.Discard
+		stateJSON, showErr := terraform.RunTerraformCommandAndGetStdoutContextE(options.Testing, ctx, options.TerraformOptions, "show", "-json", "-no-color")
+		options.TerraformOptions.Logger = previousLogger // restore the logger of the test
+		if showErr != nil {
+			logger.Log(options.Testing, "WARNING: failed to read terraform state, no reclamations will be purged: ", showErr)
+			return nil
+		}
+		var parseErr error
+		crns, parseErr = common.CollectCRNsFromState(stateJSON)
+		if parseErr != nil {
+			logger.Log(options.Testing, "WARNING: no reclamations will be purged: ", parseErr)
+			return nil
+		}
+	}
+	return common.FilterInstanceCRNsByService(crns, options.PurgeReclamations.Services)
+}
+
+// purgeReclamations purges the reclamations of the destroyed resource instances and logs a summary.
+// Failed purges are logged as warnings, they do not fail the test.
+func (options *TestOptions) purgeReclamations(crns []string) {
+	if len(crns) == 0 {
+		return
+	}
+
+	purger := options.CloudInfoService
+	if purger == nil {
+		cacheEnabled := true
+		if options.CacheEnabled != nil {
+			cacheEnabled = *options.CacheEnabled
+		}
+		cloudInfoSvc, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{
+			CacheEnabled: cacheEnabled,
+			CacheTTL:     options.CacheTTL,
+		})
+		if err != nil {
+			logger.Log(options.Testing, "WARNING: Error creating CloudInfoService for testhelper, skipping reclamation purge: ", err)
+			return
+		}
+		purger = cloudInfoSvc
+	}
+
+	config := common.ReclamationPurgeRetryConfig()
+	if options.PurgeReclamations.RetryConfig != nil {
+		config = *options.PurgeReclamations.RetryConfig
+	}
+
+	logger.Log(options.Testing, "START: Reclamation purge")
+	summary := common.PurgeReclamations(purger, crns, config)
+	logger.Log(options.Testing, summary.String())
+	if len(summary.Failed) > 0 {
+		logger.Log(options.Testing, "WARNING: the reclamations of some destroyed instances could not be purged, remove them manually if required")
+	}
+	logger.Log(options.Testing, "END: Reclamation purge")
+}
@@ -0,0 +1,143 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// replaceCauseUnknownValue is shown as the new value of an attribute that will only be known after apply
+const replaceCauseUnknownValue = "(known after apply)"
+
+// ReplaceCause describes an attribute that forced the replacement of a resource, as identified by the
+// `replace_paths` of the terraform plan
+type ReplaceCause struct {
+	Path   string `json:"path"`   // attribute path that forced the replacement, for example `boot_volume.0.name`
+	Before string `json:"before"` // sanitized JSON of the old value
+	After  string `json:"after"`  // sanitized JSON of the new value
+}
+
+// String returns a short description of the cause for use in logs
+func (cause ReplaceCause) String() string {
+	return fmt.Sprintf("%s: %s => %s", cause.Path, cause.Before, cause.After)
+}
+
+// GetReplaceCauses returns the attributes that forced the replacement of a resource, along with their old and new values.
+// Sensitive values are sanitized using common.SanitizeSensitiveData.
+// Returns nil if the change is not a replacement or terraform did not supply any replace paths.
+func GetReplaceCauses(change *tfjson.Change) []ReplaceCause {
+	if change == nil || !change.Actions.Replace() || len(change.ReplacePaths) == 0 {
+		return nil
+	}
+
+	mergedSensitive := getMergedSensitive(change)
+	before := sanitizeChangeValue(change.Before, mergedSensitive)
+	after := sanitizeChangeValue(change.After, mergedSensitive)
+
+	var causes []ReplaceCause
+	for _, replacePath := range change.ReplacePaths {
+		segments := getReplacePathSegments(replacePath)
+		if len(segments) == 0 {
+			continue
+		}
+
+		cause := ReplaceCause{
+			Path:   strings.Join(segments, "."),
+			Before: formatReplaceCauseValue(lookupAttributePath(before, segments)),
+		}
+		if unknown, ok := lookupAttributePath(change.AfterUnknown, segments).(bool); ok && unknown {
+			cause.After = replaceCauseUnknownValue
+		} else {
+			cause.After = formatReplaceCauseValue(lookupAttributePath(after, segments))
+		}
+		causes = append(causes, cause)
+	}
+
+	return causes
+}
+
+// getReplaceCausePaths returns only the attribute paths of the replace causes
+func getReplaceCausePaths(causes []ReplaceCause) []string {
+	var paths []string
+	for _, cause := range causes {
+		paths = append(paths, fmt.Sprintf("`%s`", cause.Path))
+	}
+	return paths
+}
+
+// getReplacePathSegments converts a single entry of the plan `replace_paths`, which is a list of attribute names
+// and list indexes, into string path segments
+func getReplacePathSegments(replacePath interface{}) []string {
+	var segments []string
+	switch path := replacePath.(type) {
+	case []interface{}:
+		for _, segment := range path {
+			switch value := segment.(type) {
+			case float64:
+				segments = append(segments, fmt.Sprintf("%d", int(value)))
+			default:
+				segments = append(segments, fmt.Sprint(value))
+			}
+		}
+	case string:
+		segments = append(segments, path)
+	}
+	return segments
+}
+
+// lookupAttributePath walks a decoded JSON value following the path segments.
+// If a sanitized (string) value is found before the end of the path it is returned, so that sensitive parents are not
+// traversed, otherwise nil is returned for paths that do not exist.
+func lookupAttributePath(value interface{}, segments []string) interface{} {
+	current := value
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[segment]
+		case []interface{}:
+			var index int
+			if _, err := fmt.Sscanf(segment, "%d", &index); err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			current = node[index]
+		case string:
+			return node
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// sanitizeChangeValue returns a copy of a before or after value of a change with sensitive data sanitized.
+// If the value can not be sanitized a placeholder is returned, so that sensitive data is never printed.
+func sanitizeChangeValue(value interface{}, mergedSensitive map[string]interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return "Error sanitizing sensitive data"
+	}
+	sanitized, err := common.SanitizeSensitiveData(string(valueBytes), mergedSensitive)
+	if err != nil {
+		return "Error sanitizing sensitive data"
+	}
+	var sanitizedValue interface{}
+	if err := json.Unmarshal([]byte(sanitized), &sanitizedValue); err != nil {
+		return "Error sanitizing sensitive data"
+	}
+	return sanitizedValue
+}
+
+// formatReplaceCauseValue converts a value into compact JSON for display
+func formatReplaceCauseValue(value interface{}) string {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return "--UNAVAILABLE--"
+	}
+	return string(valueBytes)
+}
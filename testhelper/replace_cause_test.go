@@ -0,0 +1,60 @@
+package testhelper
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReplaceCauses(t *testing.T) {
+	change := &tfjson.Change{
+		Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate},
+		Before: map[string]interface{}{
+			"zone":        "us-south-1",
+			"image":       "r006-old",
+			"boot_volume": []interface{}{map[string]interface{}{"name": "old-boot"}},
+			"user_data":   "secret-old",
+		},
+		After: map[string]interface{}{
+			"zone":        "us-south-2",
+			"boot_volume": []interface{}{map[string]interface{}{"name": "new-boot"}},
+			"user_data":   "secret-new",
+		},
+		AfterUnknown:   map[string]interface{}{"image": true},
+		AfterSensitive: map[string]interface{}{"user_data": true},
+		ReplacePaths: []interface{}{
+			[]interface{}{"zone"},
+			[]interface{}{"image"},
+			[]interface{}{"boot_volume", float64(0), "name"},
+			[]interface{}{"user_data"},
+		},
+	}
+
+	causes := GetReplaceCauses(change)
+	require.Len(t, causes, 4)
+
+	assert.Equal(t, ReplaceCause{Path: "zone", Before: `"us-south-1"`, After: `"us-south-2"`}, causes[0])
+	assert.Equal(t, ReplaceCause{Path: "image", Before: `"r006-old"`, After: replaceCauseUnknownValue}, causes[1])
+	assert.Equal(t, ReplaceCause{Path: "boot_volume.0.name", Before: `"old-boot"`, After: `"new-boot"`}, causes[2])
+
+	// sensitive values must not be shown
+	assert.Equal(t, "user_data", causes[3].Path)
+	assert.NotContains(t, causes[3].Before, "secret-old")
+	assert.NotContains(t, causes[3].After, "secret-new")
+
+	assert.Contains(t, getReplaceCausesMessage(causes), "Replaced because of: `zone`, `image`, `boot_volume.0.name`, `user_data`")
+}
+
+func TestGetReplaceCausesNotReplace(t *testing.T) {
+	update := &tfjson.Change{
+		Actions:      tfjson.Actions{tfjson.ActionUpdate},
+		ReplacePaths: []interface{}{[]interface{}{"zone"}},
+	}
+	assert.Nil(t, GetReplaceCauses(update))
+
+	noPaths := &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate, tfjson.ActionDelete}}
+	assert.Nil(t, GetReplaceCauses(noPaths))
+	assert.Empty(t, getReplaceCausesMessage(nil))
+}
@@ -0,0 +1,56 @@
+package testhelper
+
+import (
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// recordTeardownJournal records the terraform directory and variables of the test in the teardown journal before
+// resources are created or changed, so that the test can still be torn down with the resume-cleanup command if the
+// test process dies. Nothing is recorded if SkipTestTearDown is set, as the resources are meant to be kept.
+// Journal errors are logged and do not fail the test.
+func (options *TestOptions) recordTeardownJournal() {
+	if options.SkipTestTearDown || options.TerraformOptions == nil {
+		return
+	}
+
+	journal := common.GetDefaultTeardownJournal()
+	if options.teardownJournalID == "" {
+		options.teardownJournalID = common.NewTeardownJournalID(options.Prefix)
+	}
+
+	entry := common.TeardownJournalEntry{
+		ID:              options.teardownJournalID,
+		Type:            common.TeardownJournalTerraform,
+		Prefix:          options.Prefix,
+		TerraformDir:    options.TerraformOptions.TerraformDir,
+		TerraformBinary: options.TerraformOptions.TerraformBinary,
+		WorkspaceName:   options.WorkspaceName,
+		WorkspacePath:   options.WorkspacePath,
+		ResourceGroup:   options.ResourceGroup,
+	}
+	if options.Testing != nil {
+		entry.TestName = options.Testing.Name()
+	}
+
+	varFile, varErr := journal.WriteVarFile(options.teardownJournalID, options.TerraformOptions.Vars)
+	if varErr != nil {
+		logger.Log(options.Testing, "WARNING: failed to store terraform variables in the teardown journal: ", varErr)
+	} else {
+		entry.VarFile = varFile
+	}
+
+	if err := journal.Record(entry); err != nil {
+		logger.Log(options.Testing, "WARNING: failed to record test in the teardown journal: ", err)
+	}
+}
+
+// completeTeardownJournal marks the journal entry of the test as complete after a successful destroy
+func (options *TestOptions) completeTeardownJournal() {
+	if options.teardownJournalID == "" {
+		return
+	}
+	if err := common.GetDefaultTeardownJournal().Complete(options.teardownJournalID); err != nil {
+		logger.Log(options.Testing, "WARNING: failed to complete test in the teardown journal: ", err)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/logger"
@@ -128,127 +130,222 @@ func CleanTerraformDir(directory string) {
 	}
 }
 
-// checkConsistency Fails the test if any destroys are detected and the resource is not exempt.
-// If any addresses are provided in IgnoreUpdates.List then fail on updates too unless the resource is exempt
-// Returns TRUE if there were consistency changes that were identified
-func CheckConsistency(plan *terraform.PlanStruct, testOptions CheckConsistencyOptionsI) bool {
-	validChange := false
-
+// CheckConsistency Fails the test if any destroys are detected and the resource is not exempt.
+// Updates also fail the test unless the resource is exempt in IgnoreUpdates, either by address, by pattern, or because
+// every changed attribute is covered by an attribute exemption (IgnoreUpdates.Attributes).
+// Returns a ConsistencyReport listing every resource that was identified with a create, update, delete or replace
+// action, and if the resource was exempted. Use ConsistencyReport.HasFailures() to determine if the check failed.
+// If an ExemptionFile is supplied its entries are merged with the exemption lists, expired entries fail the test and
+// entries that did not match any resource are reported as warnings.
+func CheckConsistency(plan *terraform.PlanStruct, testOptions CheckConsistencyOptionsI) *ConsistencyReport {
 	// extract consistency options from base set of options (schematic or terratest)
 	options := testOptions.GetCheckConsistencyOptions()
 
+	report := &ConsistencyReport{
+		IsUpgradeTest: options.IsUpgradeTest,
+	}
+
+	// merge in exemptions from the exemption file, if one was supplied
+	now := time.Now()
+	exemptionFile := applyExemptionFile(options, now)
+
+	// in upgrade tests, resources that were destroyed and created at a new address can usually be fixed with a moved block
+	movedBlocks := make(map[string]*MovedBlockSuggestion)
+	if options.IsUpgradeTest {
+		for _, suggestion := range SuggestMovedBlocks(plan) {
+			movedBlocks[suggestion.From] = &suggestion
+		}
+	}
+
 	for _, resource := range plan.ResourceChangesMap {
-		// get JSON string of full changes for the logs
-		changesBytes, changesErr := json.MarshalIndent(resource.Change, "", "  ")
-		// if it errors in the marshall step, just put a placeholder and move on, not important
-		changesJson := "--UNAVAILABLE--"
-		if changesErr == nil {
-			changesJson = string(changesBytes)
+		actionType := getConsistencyActionType(resource.Change.Actions)
+		if actionType == "" {
+			continue
 		}
 
-		var resourceDetails string
+		// We only want to check pure Adds (creates without destroy) if the consistency test is
+		// NOT the result of an Upgrade, as some adds are expected when doing the Upgrade test
+		// (such as new resources were added as part of the pull request)
+		if actionType == ConsistencyActionCreate && options.IsUpgradeTest {
+			continue
+		}
 
-		// Treat all keys in the BeforeSensitive and AfterSensitive maps as sensitive
-		// Assuming BeforeSensitive and AfterSensitive are of type interface{}
-		beforeSensitive, beforeSensitiveOK := resource.Change.BeforeSensitive.(map[string]interface{})
-		afterSensitive, afterSensitiveOK := resource.Change.AfterSensitive.(map[string]interface{})
+		resourceDetails, before, after := getResourceChangeDetails(resource, options)
+
+		var exemptions Exemptions
+		var errorMessage string
+		var replaceCauses []ReplaceCause
+		switch actionType {
+		case ConsistencyActionDelete, ConsistencyActionReplace:
+			exemptions = options.IgnoreDestroys
+			replaceCauses = GetReplaceCauses(resource.Change)
+			errorMessage = fmt.Sprintf("Resource(s) identified to be destroyed %s%s%s", getReplaceCausesMessage(replaceCauses), getMovedBlocksMessage(movedBlocks[resource.Address]), resourceDetails)
+		case ConsistencyActionUpdate:
+			exemptions = options.IgnoreUpdates
+			errorMessage = fmt.Sprintf("Resource(s) identified to be updated %s", resourceDetails)
+		case ConsistencyActionCreate:
+			exemptions = options.IgnoreAdds
+			errorMessage = fmt.Sprintf("Resource(s) identified to be created %s", resourceDetails)
+		}
 
-		// Create the mergedSensitive map
-		mergedSensitive := make(map[string]interface{})
+		violation := ConsistencyViolation{
+			Address:    resource.Address,
+			Name:       resource.Name,
+			Type:       resource.Type,
+			ActionType: actionType,
+			Before:     before,
+			After:      after,
 
-		// Check if BeforeSensitive is of the expected type
-		if beforeSensitiveOK {
-			// Copy the keys and values from BeforeSensitive to the mergedSensitive map.
-			for key, value := range beforeSensitive {
-				if isSanitizationSensitiveValue(value) {
-					mergedSensitive[key] = value
-				}
-			}
+			ReplaceCauses: replaceCauses,
+		}
+		if actionType == ConsistencyActionUpdate {
+			// attribute level exemptions only apply to updates
+			violation.ChangedAttributes = GetChangedAttributePaths(resource.Change)
+			violation.Exempted = exemptions.IsExemptedUpdate(resource)
+		} else {
+			violation.Exempted = exemptions.IsExemptedResource(resource.Address)
+		}
+		for _, action := range resource.Change.Actions {
+			violation.Actions = append(violation.Actions, string(action))
 		}
 
-		// Check if AfterSensitive is of the expected type
-		if afterSensitiveOK {
-			// Copy the keys and values from AfterSensitive to the mergedSensitive map.
-			for key, value := range afterSensitive {
-				if isSanitizationSensitiveValue(value) {
-					mergedSensitive[key] = value
-				}
+		if !violation.Exempted {
+			assert.Fail(options.Testing, errorMessage)
+			if suggestion, ok := movedBlocks[resource.Address]; ok && actionType == ConsistencyActionDelete {
+				report.MovedBlockSuggestions = append(report.MovedBlockSuggestions, *suggestion)
 			}
 		}
 
-		// Perform sanitization
-		sanitizedChangesJson, err := sanitizeResourceChanges(resource.Change, mergedSensitive)
-		if err != nil {
-			sanitizedChangesJson = "Error sanitizing sensitive data"
-			logger.Log(options.Testing, sanitizedChangesJson)
+		report.Violations = append(report.Violations, violation)
+	}
+
+	// map iteration is random, keep report output stable
+	sort.Slice(report.Violations, func(i, j int) bool {
+		return report.Violations[i].Address < report.Violations[j].Address
+	})
+
+	sort.Slice(report.MovedBlockSuggestions, func(i, j int) bool {
+		return report.MovedBlockSuggestions[i].From < report.MovedBlockSuggestions[j].From
+	})
+	if len(report.MovedBlockSuggestions) > 0 {
+		logger.Log(options.Testing, "Suggested moved blocks for destroyed resources:\n"+report.MovedBlocksHCL())
+	}
+
+	report.Warnings = getUnusedExemptionWarnings(exemptionFile, report, now)
+	for _, warning := range report.Warnings {
+		logger.Log(options.Testing, "WARNING: "+warning)
+	}
+
+	return report
+}
+
+// getReplaceCausesMessage builds the part of a consistency failure message that explains which attributes forced a
+// replacement, returns an empty string if there are no causes
+func getReplaceCausesMessage(causes []ReplaceCause) string {
+	if len(causes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\nReplaced because of: %s", strings.Join(getReplaceCausePaths(causes), ", ")))
+	for _, cause := range causes {
+		sb.WriteString(fmt.Sprintf("\n  %s", cause))
+	}
+	return sb.String()
+}
+
+// getResourceChangeDetails builds the sanitized details of a resource change that are used in consistency failure messages.
+// Returns the full details string, along with the sanitized before and after parts of the diff.
+func getResourceChangeDetails(resource *tfjson.ResourceChange, options *CheckConsistencyOptions) (string, string, string) {
+	// get JSON string of full changes for the logs
+	changesBytes, changesErr := json.MarshalIndent(resource.Change, "", "  ")
+	// if it errors in the marshall step, just put a placeholder and move on, not important
+	changesJson := "--UNAVAILABLE--"
+	if changesErr == nil {
+		changesJson = string(changesBytes)
+	}
+
+	mergedSensitive := getMergedSensitive(resource.Change)
+
+	// Perform sanitization
+	sanitizedChangesJson, err := sanitizeResourceChanges(resource.Change, mergedSensitive)
+	if err != nil {
+		sanitizedChangesJson = "Error sanitizing sensitive data"
+		logger.Log(options.Testing, sanitizedChangesJson)
+	}
+	formatChangesJson, err := common.FormatJsonStringPretty(sanitizedChangesJson)
+
+	var formatChangesJsonString string
+	if err != nil {
+		logger.Log(options.Testing, "Error formatting JSON, use unformatted")
+		formatChangesJsonString = sanitizedChangesJson
+	} else {
+		formatChangesJsonString = string(formatChangesJson)
+	}
+
+	var before, after string
+	diff, diffErr := common.GetBeforeAfterDiff(changesJson)
+
+	if diffErr != nil {
+		diff = fmt.Sprintf("Error getting diff: %s", diffErr)
+	} else {
+		// Split the changesJson into "Before" and "After" parts
+		beforeAfter := strings.Split(diff, "After: ")
+
+		// Perform sanitization on "After" part
+		if len(beforeAfter) > 1 {
+			after, err = common.SanitizeSensitiveData(beforeAfter[1], mergedSensitive)
+			handleSanitizationError(err, "after diff", options)
+		} else {
+			after = "Could not parse after from diff" // dont print incase diff contains sensitive values
 		}
-		formatChangesJson, err := common.FormatJsonStringPretty(sanitizedChangesJson)
 
-		var formatChangesJsonString string
-		if err != nil {
-			logger.Log(options.Testing, "Error formatting JSON, use unformatted")
-			formatChangesJsonString = sanitizedChangesJson
+		// Perform sanitization on "Before" part
+		if len(beforeAfter) > 0 {
+			before, err = common.SanitizeSensitiveData(strings.TrimPrefix(beforeAfter[0], "Before: "), mergedSensitive)
+			handleSanitizationError(err, "before diff", options)
 		} else {
-			formatChangesJsonString = string(formatChangesJson)
+			before = "Could not parse before from diff" // dont print incase diff contains sensitive values
 		}
 
-		diff, diffErr := common.GetBeforeAfterDiff(changesJson)
+		// Reassemble the sanitized diff string
+		diff = "  Before: \n\t" + before + "\n  After: \n\t" + after
+	}
+	resourceDetails := fmt.Sprintf("\nName: %s\nAddress: %s\nActions: %s\nDIFF:\n%s\n\nChange Detail:\n%s", resource.Name, resource.Address, resource.Change.Actions, diff, formatChangesJsonString)
 
-		if diffErr != nil {
-			diff = fmt.Sprintf("Error getting diff: %s", diffErr)
-		} else {
-			// Split the changesJson into "Before" and "After" parts
-			beforeAfter := strings.Split(diff, "After: ")
-
-			// Perform sanitization on "After" part
-			var after string
-			if len(beforeAfter) > 1 {
-				after, err = common.SanitizeSensitiveData(beforeAfter[1], mergedSensitive)
-				handleSanitizationError(err, "after diff", options)
-			} else {
-				after = "Could not parse after from diff" // dont print incase diff contains sensitive values
-			}
+	return resourceDetails, before, after
+}
 
-			// Perform sanitization on "Before" part
-			var before string
-			if len(beforeAfter) > 0 {
-				before, err = common.SanitizeSensitiveData(strings.TrimPrefix(beforeAfter[0], "Before: "), mergedSensitive)
-				handleSanitizationError(err, "before diff", options)
-			} else {
-				before = "Could not parse before from diff" // dont print incase diff contains sensitive values
+// getMergedSensitive returns a map of all attribute keys that are marked sensitive either before or after the change.
+func getMergedSensitive(change *tfjson.Change) map[string]interface{} {
+	// Treat all keys in the BeforeSensitive and AfterSensitive maps as sensitive
+	// Assuming BeforeSensitive and AfterSensitive are of type interface{}
+	beforeSensitive, beforeSensitiveOK := change.BeforeSensitive.(map[string]interface{})
+	afterSensitive, afterSensitiveOK := change.AfterSensitive.(map[string]interface{})
+
+	// Create the mergedSensitive map
+	mergedSensitive := make(map[string]interface{})
+
+	// Check if BeforeSensitive is of the expected type
+	if beforeSensitiveOK {
+		// Copy the keys and values from BeforeSensitive to the mergedSensitive map.
+		for key, value := range beforeSensitive {
+			if isSanitizationSensitiveValue(value) {
+				mergedSensitive[key] = value
 			}
-
-			// Reassemble the sanitized diff string
-			diff = "  Before: \n\t" + before + "\n  After: \n\t" + after
 		}
-		resourceDetails = fmt.Sprintf("\nName: %s\nAddress: %s\nActions: %s\nDIFF:\n%s\n\nChange Detail:\n%s", resource.Name, resource.Address, resource.Change.Actions, diff, formatChangesJsonString)
+	}
 
-		var errorMessage string
-		if !options.IgnoreDestroys.IsExemptedResource(resource.Address) {
-			errorMessage = fmt.Sprintf("Resource(s) identified to be destroyed %s", resourceDetails)
-			assert.False(options.Testing, resource.Change.Actions.Delete(), errorMessage)
-			assert.False(options.Testing, resource.Change.Actions.DestroyBeforeCreate(), errorMessage)
-			assert.False(options.Testing, resource.Change.Actions.CreateBeforeDestroy(), errorMessage)
-			validChange = true
-		}
-		if !options.IgnoreUpdates.IsExemptedResource(resource.Address) {
-			errorMessage = fmt.Sprintf("Resource(s) identified to be updated %s", resourceDetails)
-			assert.False(options.Testing, resource.Change.Actions.Update(), errorMessage)
-			validChange = true
-		}
-		// We only want to check pure Adds (creates without destroy) if the consistency test is
-		// NOT the result of an Upgrade, as some adds are expected when doing the Upgrade test
-		// (such as new resources were added as part of the pull request)
-		if !options.IsUpgradeTest {
-			if !options.IgnoreAdds.IsExemptedResource(resource.Address) {
-				errorMessage = fmt.Sprintf("Resource(s) identified to be created %s", resourceDetails)
-				assert.False(options.Testing, resource.Change.Actions.Create(), errorMessage)
-				validChange = true
+	// Check if AfterSensitive is of the expected type
+	if afterSensitiveOK {
+		// Copy the keys and values from AfterSensitive to the mergedSensitive map.
+		for key, value := range afterSensitive {
+			if isSanitizationSensitiveValue(value) {
+				mergedSensitive[key] = value
 			}
 		}
 	}
 
-	return validChange
+	return mergedSensitive
 }
 
 // sanitizeResourceChanges sanitizes the sensitive data in a Terraform JSON Change and returns the sanitized JSON.
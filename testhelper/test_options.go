@@ -1,7 +1,9 @@
 package testhelper
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"testing"
 	"time"
@@ -39,6 +41,15 @@ type TestOptions struct {
 	// If left empty, this will be populated by dynamic region selection by default constructor and can be referenced later.
 	Region string
 
+	// Set to true if you wish the default constructor to select the VPC zone of the Region with the least instances, subnets
+	// and bare metal servers, and populate Zone with it. Set OS environment variable FORCE_TEST_ZONE to force a specific zone.
+	SelectBestZone bool
+
+	// If set during creation, this zone will be used for test and dynamic zone selection will be skipped.
+	// If left empty and SelectBestZone is true, this will be populated by the default constructor, falling back to the
+	// first zone of the Region. The WithVars constructor uses this value to populate the `zone` input variable.
+	Zone string
+
 	// Only required if using the WithVars constructor, as this value will then populate the `resource_group` input variable.
 	ResourceGroup string
 
@@ -57,14 +68,30 @@ type TestOptions struct {
 	TerraformVars map[string]interface{}
 
 	// This map contains key-value pairs that will be used as variables for the test terraform run.
-	// If set, the test will execute a second modified terraform apply with the given variables.
+	// If set, the test will execute a second modified terraform apply with the given variables, followed by a plan
+	// that checks that the modified configuration is idempotent.
 	ModifiedTerraformVars map[string]interface{}
 
+	// OPTIONAL: the resource changes allowed in the modified apply of ModifiedTerraformVars. If set, the modified apply is
+	// planned first and every resource change that is not listed fails the test, any other resource must not change.
+	// The consistency exemptions IgnoreAdds, IgnoreUpdates and IgnoreDestroys also apply to this plan, the ExemptionFile
+	// only to the consistency checks.
+	ExpectedModifiedChanges *ExpectedChanges
+
 	// When set during teardown this Terraform output will be used to disable CBR Rules that were created during the
 	// test to allow to destroy to complete.
 	// The last latest state of the terraform output will be used, and expects a list of CBR Rule IDs in string format.
 	CBRRuleListOutputVariable string
 
+	// Terraform outputs whose values are passed to an Unlocker during teardown, before the destroy, to remove
+	// protections that would make the destroy fail. The unlocker of each output is taken from Unlockers, or else from
+	// the unlockers registered with RegisterUnlocker (the built-in ones are cbr_rule_ids, iam_policy_ids,
+	// service_authorization_ids, kms_key_crns and cos_bucket_urls). Unlocker failures are logged and do not fail the test.
+	UnlockOutputs []string
+
+	// Unlockers for outputs of UnlockOutputs, keyed by output name, used instead of the registered unlockers.
+	Unlockers map[string]Unlocker
+
 	// This is the subdirectory of the project that contains the terraform to run for the test.
 	// This value is relative to the root directory of the project.
 	// Defaults to root directory of project if not supplied.
@@ -123,10 +150,17 @@ type TestOptions struct {
 	// Normally this would fail a consistency check but can be ignored by adding to one of these lists.
 	//
 	// Name format is terraform style, for example: `module.some_module.null_resource.foo`
+	// Glob (`Patterns`) and regular expression (`RegexPatterns`) address matching is also supported, and `IgnoreUpdates` can
+	// ignore individual attributes of a resource using `Attributes`, for example: `parameters.last_updated`
 	IgnoreAdds     Exemptions
 	IgnoreDestroys Exemptions
 	IgnoreUpdates  Exemptions
 
+	// OPTIONAL: path to a YAML or JSON exemption file (see ExemptionFile). Entries in the file are merged with the
+	// IgnoreAdds, IgnoreUpdates and IgnoreDestroys lists. Each entry documents the reason for the exemption, and can have
+	// an issue link and expiry date. Expired exemptions fail the test, and unused exemptions are logged as warnings.
+	ExemptionFile string
+
 	// Implicit Destroy can be used to speed up the `terraform destroy` action of the test, by removing resources from the state file
 	// before the destroy process is executed.
 	//
@@ -153,6 +187,18 @@ type TestOptions struct {
 	// Set to true if you wish for an Upgrade test to do a final `terraform apply` after the consistency check on the new (not base) branch.
 	CheckApplyResultForUpgrade bool
 
+	// OPTIONAL: for Upgrade tests, upgrade from previous releases of the base repo instead of the base branch.
+	// Each entry is either a release tag (e.g. `v3.1.0`) or a version constraint (e.g. `^2.0.0` or `>=3.0.0`), which is
+	// resolved to the latest matching tag of the base repo. The oldest release is applied first, then each later release
+	// is planned, checked for consistency and applied in turn, with the final hop being a plan of the PR branch.
+	// The results of every hop are stored in LastUpgradeHops.
+	UpgradeFromVersions []string
+
+	// OPTIONAL: for Upgrade tests, also compare the variables and outputs of the root module in the base branch (or newest
+	// release when using UpgradeFromVersions) with the PR branch, and fail the test for breaking changes such as removed
+	// variables or outputs, new required variables and changed variable types. See RunTestModuleInterface to run this standalone.
+	CheckModuleInterface bool
+
 	// If you want to skip test setup and teardown use these
 	SkipTestSetup    bool
 	SkipTestTearDown bool
@@ -166,6 +212,21 @@ type TestOptions struct {
 	// Unless the upgrade test is run with the `CheckApplyResultForUpgrade` set to true.
 	LastTestTerraformOutputs map[string]interface{}
 
+	// LastConsistencyReport is the structured result of the last consistency check performed by RunTestConsistency or RunTestUpgrade.
+	// It lists every resource identified with a create, update, delete or replace action and can be rendered as text, JSON or Markdown
+	// for publishing, or inspected for further assertions after the test.
+	LastConsistencyReport *ConsistencyReport
+
+	// LastModifiedChangesReport is the result of checking the plan of the modified apply against ExpectedModifiedChanges
+	LastModifiedChangesReport *ConsistencyReport
+
+	// LastUpgradeHops holds the result of each hop of the last upgrade test run with UpgradeFromVersions.
+	// Use FirstDestroyHop to find the release that first introduced a destroy.
+	LastUpgradeHops []UpgradeHop
+
+	// LastModuleInterfaceReport holds the result of the last module interface check (see CheckModuleInterface)
+	LastModuleInterfaceReport *ModuleInterfaceReport
+
 	// These properties are considered READ ONLY and are used internally in the service to keep track of certain data elements.
 	// Some of these properties are public, and can be used after the test is run to determine specific outcomes.
 	IsUpgradeTest      bool // Identifies if current test is an UPGRADE test, used for special processing
@@ -192,6 +253,45 @@ type TestOptions struct {
 	// Default: 10 minutes if not specified when cache is enabled
 	// Recommended: 5-15 minutes for test scenarios, 10 minutes for CI/CD pipelines
 	CacheTTL time.Duration
+
+	// OPTIONAL: parent context for all terraform operations of the test. Cancelling this context will cancel the running
+	// apply or plan, after which the test resources are still destroyed.
+	Context context.Context
+
+	// OPTIONAL: time reserved for teardown before the `go test -timeout` deadline. Terraform operations of the test are
+	// cancelled once only this amount of time remains, so that destroy can still run before the test binary is killed.
+	// Default: 15 minutes (common.DefaultTeardownReserve), at most half of the time left before the deadline
+	TeardownReserve time.Duration
+
+	// OPTIONAL: validate the module during test setup, before terraform init and apply, see RunPreflightChecks.
+	// TerraformVars not declared by the module, required variables that are not set, `terraform validate` errors and
+	// files that are not formatted fail the test, and the test is not run. Files that can not be parsed, and TerraformVars
+	// set before TestOptionsDefault that are not declared, fail before the region of the test is selected.
+	PreflightChecks bool
+
+	// OPTIONAL: detect resources left behind by the test. A snapshot of the resource instances (and VPCs) is
+	// taken before setup and after teardown, and the test fails listing every new resource whose name or tags contain Prefix.
+	CheckForLeaks bool
+
+	// OPTIONAL: scope of the snapshots taken for CheckForLeaks.
+	// Default: the resource instances of ResourceGroup and of the resource groups whose name starts with Prefix, and the
+	// VPCs of the test Region
+	LeakCheckOptions *cloudinfo.ResourceSnapshotOptions
+
+	// OPTIONAL: purge the reclamations of the destroyed resource instances of the listed services after a successful
+	// destroy, so that their names and quotas are released right away. The CRNs are collected from the terraform state,
+	// or from the CrnOutput output, before the destroy.
+	PurgeReclamations *common.ReclamationPurgeOptions
+
+	runContext        context.Context           // internal: context for terraform operations, see getRunContext
+	cancelRunContext  context.CancelFunc        // internal: releases runContext
+	interruptTeardown *common.InterruptTeardown // internal: teardown run on SIGINT/SIGTERM while runContext is in use
+	teardownJournalID string                    // internal: ID of the teardown journal entry of the current test run
+	leakDetector      *cloudinfo.LeakDetector   // internal: snapshot of the resources before setup, see CheckForLeaks
+	modifiedApplyPlan *terraform.PlanStruct     // internal: idempotency plan after the modified apply, see ModifiedTerraformVars
+	regionLeased      bool                      // internal: Region was selected during setup and is leased until teardown
+	preflightErr      error                     // internal: the preflight checks failed and the test is not run, see PreflightChecks
+	tempWorkingDir    string                    // internal: temporary copy of the repository created by the setup
 }
 
 type CheckConsistencyOptions struct {
@@ -207,10 +307,14 @@ type CheckConsistencyOptions struct {
 	// Normally this would fail a consistency check but can be ignored by adding to one of these lists.
 	//
 	// Name format is terraform style, for example: `module.some_module.null_resource.foo`
+	// Glob (`Patterns`) and regular expression (`RegexPatterns`) address matching is also supported, and `IgnoreUpdates` can
+	// ignore individual attributes of a resource using `Attributes`, for example: `parameters.last_updated`
 	IgnoreAdds     Exemptions
 	IgnoreDestroys Exemptions
 	IgnoreUpdates  Exemptions
 
+	ExemptionFile string // OPTIONAL: path to a YAML or JSON exemption file, merged with the lists above
+
 	IsUpgradeTest bool // Identifies if current test is an UPGRADE test, used for special processing
 }
 
@@ -227,10 +331,16 @@ func (options *TestOptions) GetCheckConsistencyOptions() *CheckConsistencyOption
 		IgnoreAdds:     options.IgnoreAdds,
 		IgnoreDestroys: options.IgnoreDestroys,
 		IgnoreUpdates:  options.IgnoreUpdates,
+		ExemptionFile:  options.ExemptionFile,
 		IsUpgradeTest:  options.IsUpgradeTest,
 	}
 }
 
+// To support consistency check options interface, so that options built for a single check can be passed directly
+func (options *CheckConsistencyOptions) GetCheckConsistencyOptions() *CheckConsistencyOptions {
+	return options
+}
+
 // Default constructor for TestOptions struct. This constructor takes in an existing TestOptions object with minimal values set, and returns
 // a new object that has amended or new values set.
 //
@@ -240,6 +350,7 @@ func (options *TestOptions) GetCheckConsistencyOptions() *CheckConsistencyOption
 // Common TerraformVars added:
 // * prefix
 // * region
+// * zone, if set or selected with SelectBestZone
 // * resource_group
 // * resource_tags
 //
@@ -257,6 +368,7 @@ func TestOptionsDefaultWithVars(originalOptions *TestOptions) *TestOptions {
 
 	common.ConditionalAdd(varsMap, "prefix", newOptions.Prefix, "")
 	common.ConditionalAdd(varsMap, "region", newOptions.Region, "")
+	common.ConditionalAdd(varsMap, "zone", newOptions.Zone, "")
 	common.ConditionalAdd(varsMap, "resource_group", newOptions.ResourceGroup, "")
 
 	varsMap["resource_tags"] = common.GetTagsFromTravis()
@@ -275,6 +387,7 @@ func TestOptionsDefaultWithVars(originalOptions *TestOptions) *TestOptions {
 // * appends unique 6-char string to end of original prefix
 // * checks that certain required environment variables are set
 // * computes best dynamic region for test, if Region is not supplied
+// * computes best dynamic zone of the region, if SelectBestZone is set and Zone is not supplied
 // * sets various other properties to sensible defaults
 func TestOptionsDefault(originalOptions *TestOptions) *TestOptions {
 
@@ -305,6 +418,14 @@ func TestOptionsDefault(originalOptions *TestOptions) *TestOptions {
 		}
 	}
 
+	// a module with errors fails before the region lookup, and the test is not set up
+	if newOptions.PreflightChecks {
+		newOptions.preflightErr = newOptions.runModulePreflightChecks()
+		if newOptions.preflightErr != nil && newOptions.Region == "" {
+			newOptions.Region = newOptions.DefaultRegion
+		}
+	}
+
 	if newOptions.Region == "" {
 		// Get the best region
 		// Programmatically determine region to use based on availability
@@ -318,6 +439,13 @@ func TestOptionsDefault(originalOptions *TestOptions) *TestOptions {
 		} else {
 			newOptions.Region, _ = GetBestVpcRegionO(newOptions.RequiredEnvironmentVars[ibmcloudApiKeyVar], defaultRegionYaml, newOptions.DefaultRegion, *regionOptions)
 		}
+		_, forced := os.LookupEnv(ForceTestRegionEnvName)
+		newOptions.regionLeased = !forced
+	}
+	if newOptions.SelectBestZone && newOptions.Zone == "" && newOptions.preflightErr == nil {
+		// Programmatically determine zone of the region to use based on consumption
+		zoneOptions := TesthelperTerraformOptions{CloudInfoService: newOptions.CloudInfoService}
+		newOptions.Zone, _ = GetBestVpcZoneO(newOptions.RequiredEnvironmentVars[ibmcloudApiKeyVar], newOptions.Region, newOptions.Region+"-1", zoneOptions)
 	}
 	newOptions.SkipTestSetup = false
 	newOptions.SkipTestTearDown = false
@@ -350,6 +478,7 @@ func (options *TestOptions) Clone() (*TestOptions, error) {
 	// pointers to other complex structs
 	newOptions.Testing = options.Testing
 	newOptions.TerraformOptions = options.TerraformOptions
+	newOptions.Context = options.Context
 
 	return newOptions, nil
 }
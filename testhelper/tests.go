@@ -1,7 +1,6 @@
 package testhelper
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"path"
@@ -29,15 +28,18 @@ import (
 // * API_DATA_IS_SENSITIVE environment variable is set to true
 // * If calling test had not provided its own TerraformOptions, then default settings are used
 // * Temp directory is created
+//
+// Failed preflight checks, see PreflightChecks, are reported to the test.
 func (options *TestOptions) TestSetup() {
 	oldSetupValue := options.SkipTestSetup
 	options.SkipTestSetup = false
-	options.testSetup()
+	// the preflight errors already failed the test
+	_ = options.testSetup()
 	options.SkipTestSetup = oldSetupValue
 }
 
-// testSetup Setup test
-func (options *TestOptions) testSetup() {
+// testSetup Setup test, the preflight checks run before the leak detection snapshot and any terraform command
+func (options *TestOptions) testSetup() error {
 	if !options.SkipTestSetup {
 
 		if options.ApiDataIsSensitive == nil {
@@ -60,6 +62,11 @@ func (options *TestOptions) testSetup() {
 			})
 		}
 
+		if options.preflightErr != nil {
+			logger.Log(options.Testing, "Preflight checks failed, the test is not run")
+			return options.preflightErr
+		}
+
 		if !options.DisableTempWorkingDir {
 			// Ensure always running from git root
 			gitRoot, err := common.GitRootPath(".")
@@ -74,6 +81,7 @@ func (options *TestOptions) testSetup() {
 				logger.Log(options.Testing, err)
 			} else {
 				logger.Log(options.Testing, "TEMP CREATED: ", tempDir)
+				options.tempWorkingDir = tempDir
 
 				// To avoid workspace collisions when running in parallel, ignoring any temp terraform files
 				// NOTE: if it is an upgrade test, we need hidden .git files
@@ -103,15 +111,25 @@ func (options *TestOptions) testSetup() {
 			}
 		}
 
+		if options.PreflightChecks {
+			if err := options.RunPreflightChecks(); err != nil {
+				logger.Log(options.Testing, "Preflight checks failed, the test is not run")
+				options.preflightErr = err
+				return err
+			}
+		}
+
 		options.WorkspacePath = options.TerraformOptions.TerraformDir
 		if options.UseTerraformWorkspace {
 			// Always run in a new clean workspace to avoid reusing existing state files
-			options.WorkspaceName = terraform.WorkspaceSelectOrNewContext(options.Testing, context.Background(), options.TerraformOptions, options.Prefix)
+			options.WorkspaceName = terraform.WorkspaceSelectOrNewContext(options.Testing, options.getRunContext(), options.TerraformOptions, options.Prefix)
 			options.WorkspacePath = fmt.Sprintf("%s/terraform.tfstate.d/%s", options.WorkspacePath, options.Prefix)
 		}
 	} else {
 		logger.Log(options.Testing, "Skipping automatic Test Setup")
 	}
+	options.startLeakDetection()
+	return nil
 }
 
 // Function to destroy all resources. Resources are not destroyed if tests failed and "DO_NOT_DESTROY_ON_FAILURE" environment variable is true.
@@ -125,13 +143,38 @@ func (options *TestOptions) TestTearDown() {
 
 // testTearDown Tear down test
 func (options *TestOptions) testTearDown() {
+	defer options.releaseRunContext()
+	// the next test run records a new journal entry, an entry that was not completed stays pending in the journal
+	defer func() { options.teardownJournalID = "" }()
+	// the region stays leased while the resources of a skipped teardown remain
+	if !options.SkipTestTearDown {
+		defer options.releaseRegionLease()
+	}
+	// nothing was applied if the preflight checks failed, there is nothing to destroy
+	if options.preflightErr != nil {
+		return
+	}
+	// if the test was interrupted the teardown is already running, and is not run a second time
+	if options.interruptTeardown != nil {
+		options.interruptTeardown.Run()
+		return
+	}
+	options.runTestTearDown()
+}
+
+// runTestTearDown destroys the test resources, see TestTearDown
+func (options *TestOptions) runTestTearDown() {
+	// teardown must still run after the test operations were cancelled or timed out
+	teardownCtx, cancelTeardown := options.getTeardownContext()
+	defer cancelTeardown()
+
 	// Get the output of the last terraform apply
 	// NOTE: this is done before the destroy so that the output is available for debugging
 	var outputErr error
 
 	// Turn off logging for this step so sensitive data is not logged
 	options.TerraformOptions.Logger = logger.Discard
-	options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, context.Background(), options.TerraformOptions)
+	options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, teardownCtx, options.TerraformOptions)
 	options.TerraformOptions.Logger = logger.Default // turn log back on
 
 	if outputErr != nil {
@@ -158,37 +201,8 @@ func (options *TestOptions) testTearDown() {
 				}
 			}
 
-			if options.CBRRuleListOutputVariable != "" {
-				// Disable any CBR Rules before proceeding with destroy
-				expected_outputs := []string{options.CBRRuleListOutputVariable}
-				_, err := ValidateTerraformOutputs(options.LastTestTerraformOutputs, expected_outputs...)
-				if err == nil {
-					cbr_rule_ids := options.LastTestTerraformOutputs[options.CBRRuleListOutputVariable].([]interface{})
-					cacheEnabled := true
-					if options.CacheEnabled != nil {
-						cacheEnabled = *options.CacheEnabled
-					}
-					infosvc, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{
-						CacheEnabled: cacheEnabled,
-						CacheTTL:     options.CacheTTL,
-					})
-					if err != nil {
-						logger.Log(options.Testing, "Error creating CloudInfoService for testhelper, skipping CBR Rule disable")
-					} else {
-						for _, cbr_rule_id := range cbr_rule_ids {
-							// Disable CBR Rule
-							disable_error := infosvc.SetCBREnforcementMode(cbr_rule_id.(string), "disabled")
-							if disable_error != nil {
-								logger.Log(options.Testing, fmt.Sprintf("Error Disabling CBR Rule %s, %s", cbr_rule_id.(string), disable_error))
-							} else {
-								logger.Log(options.Testing, fmt.Sprintf("Disabled CBR Rule %s", cbr_rule_id.(string)))
-							}
-						}
-					}
-				} else {
-					logger.Log(options.Testing, fmt.Sprintf("Error output containing CBRRuleList %s not found in Statefile, skipping CBR Rule disable", options.CBRRuleListOutputVariable))
-				}
-			}
+			// Disable CBR rules and remove other protections before proceeding with destroy
+			options.runUnlockers()
 			if options.PreDestroyHook != nil {
 				logger.Log(options.Testing, "START: PreDestroyHook")
 				hookErr := options.PreDestroyHook(options)
@@ -200,10 +214,11 @@ func (options *TestOptions) testTearDown() {
 					logger.Log(options.Testing, "END: PreDestroyHook")
 				}
 			}
+			reclamationCRNs := options.collectReclamationCRNs(teardownCtx)
 			logger.Log(options.Testing, "Destroying test resources")
 			logger.Log(options.Testing, fmt.Sprintf("Test Passed: %t", !options.Testing.Failed()))
 			logger.Log(options.Testing, "START: Destroy")
-			destroyOutput, destroyError := terraform.DestroyContextE(options.Testing, context.Background(), options.TerraformOptions)
+			destroyOutput, destroyError := terraform.DestroyContextE(options.Testing, teardownCtx, options.TerraformOptions)
 			if !assert.NoError(options.Testing, destroyError) {
 				logger.Log(options.Testing, destroyError)
 				// On destroy resource group failure, list remaining resources
@@ -275,9 +290,11 @@ func (options *TestOptions) testTearDown() {
 				}
 			} else {
 				logger.Log(options.Testing, destroyOutput)
+				options.completeTeardownJournal()
+				options.purgeReclamations(reclamationCRNs)
 			}
 			if options.UseTerraformWorkspace {
-				terraform.WorkspaceDeleteContext(options.Testing, context.Background(), options.TerraformOptions, options.Prefix)
+				terraform.WorkspaceDeleteContext(options.Testing, teardownCtx, options.TerraformOptions, options.Prefix)
 			}
 			logger.Log(options.Testing, "END: Destroy")
 			if options.PostDestroyHook != nil {
@@ -291,6 +308,7 @@ func (options *TestOptions) testTearDown() {
 					logger.Log(options.Testing, "END: PostDestroyHook")
 				}
 			}
+			options.checkForLeaks()
 			//Clean up terraform files
 			CleanTerraformDir(options.TerraformDir)
 		}
@@ -382,9 +400,13 @@ func (options *TestOptions) RunTestUpgrade() (*terraform.PlanStruct, error) {
 		}()
 
 		// Setup the test
-		options.testSetup()
+		setupErr := options.testSetup()
 		// restore the original value
 		options.DisableTempWorkingDir = tempDirCreationBackup
+		if setupErr != nil {
+			options.testTearDown()
+			return nil, setupErr
+		}
 
 		prTempDir := gitRoot
 		baseTempDir := ""
@@ -461,11 +483,25 @@ func (options *TestOptions) RunTestUpgrade() (*terraform.PlanStruct, error) {
 			logger.Log(options.Testing, "Base Repo:", baseRepo)
 			logger.Log(options.Testing, "Base Branch:", baseBranch)
 		}
+
+		// Upgrade through previous releases of the base repo instead of the base branch
+		if len(options.UpgradeFromVersions) > 0 {
+			result, resultErr = options.runUpgradeFromVersions(baseRepo, relativeTestSampleDir, prTempDir, prBranch)
+			options.UpgradeTestSkipped = skipped
+			return result, resultErr
+		}
+
 		cloneBaseErr := common.CloneAndCheckoutBranch(options.Testing, baseRepo, baseBranch, baseTempDir)
 		if cloneBaseErr != nil {
 			return nil, cloneBaseErr
 		}
 
+		if options.CheckModuleInterface {
+			if _, interfaceErr := options.checkModuleInterface(path.Join(baseTempDir, relativeTestSampleDir), path.Join(prTempDir, relativeTestSampleDir)); interfaceErr != nil {
+				return nil, interfaceErr
+			}
+		}
+
 		// Set TerraformDir to the appropriate directory within baseTempDir
 		options.setTerraformDir(path.Join(baseTempDir, relativeTestSampleDir))
 
@@ -483,7 +519,8 @@ func (options *TestOptions) RunTestUpgrade() (*terraform.PlanStruct, error) {
 		logger.Log(options.Testing, "Init / Apply on Base branch:", baseBranch)
 		logger.Log(options.Testing, "Init / Apply on Base branch dir:", options.TerraformOptions.TerraformDir)
 
-		_, resultErr = terraform.InitAndApplyContextE(options.Testing, context.Background(), options.TerraformOptions)
+		options.recordTeardownJournal()
+		_, resultErr = terraform.InitAndApplyContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
 		if resultErr != nil {
 			assert.Nilf(options.Testing, resultErr, "Terraform Apply on Base branch has failed")
 			options.testTearDown()
@@ -494,7 +531,7 @@ func (options *TestOptions) RunTestUpgrade() (*terraform.PlanStruct, error) {
 		var outputErr error
 		// Turn off logging for this step so sensitive data is not logged
 		options.TerraformOptions.Logger = logger.Discard
-		options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, context.Background(), options.TerraformOptions)
+		options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
 		options.TerraformOptions.Logger = logger.Default // turn log back on
 
 		if outputErr != nil {
@@ -547,16 +584,17 @@ func (options *TestOptions) RunTestUpgrade() (*terraform.PlanStruct, error) {
 		}
 
 		logger.Log(options.Testing, "Parsing plan output to determine if any resources identified for destroy (PR branch)...")
-		hasConsistencyChanges := CheckConsistency(result, options)
+		options.LastConsistencyReport = CheckConsistency(result, options)
 
-		if hasConsistencyChanges {
-			terraform.PlanContext(options.Testing, context.Background(), options.TerraformOptions)
+		if options.LastConsistencyReport.HasFailures() {
+			terraform.PlanContext(options.Testing, options.getRunContext(), options.TerraformOptions)
 		}
 
 		// Check if optional upgrade support on PR Branch is needed
 		if options.CheckApplyResultForUpgrade && !options.Testing.Failed() {
 			logger.Log(options.Testing, "Validating Optional upgrade on Current Branch (PR):", prBranch)
-			_, applyErr := terraform.ApplyContextE(options.Testing, context.Background(), options.TerraformOptions)
+			options.recordTeardownJournal()
+			_, applyErr := terraform.ApplyContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
 			if applyErr != nil {
 				logger.Log(options.Testing, "Error during Terraform Apply on PR branch:", applyErr)
 				assert.Nilf(options.Testing, applyErr, "Terraform Apply on PR branch has failed")
@@ -586,7 +624,10 @@ func (options *TestOptions) RunTestConsistency() (*terraform.PlanStruct, error)
 			options.TerraformOptions.PlanFilePath = ""
 		}
 	}()
-	options.testSetup()
+	if err := options.testSetup(); err != nil {
+		options.testTearDown()
+		return nil, err
+	}
 
 	logger.Log(options.Testing, "START: Init / Apply / Consistency Check")
 	_, err := options.runTest()
@@ -594,15 +635,19 @@ func (options *TestOptions) RunTestConsistency() (*terraform.PlanStruct, error)
 		options.testTearDown()
 		return nil, err
 	}
-	result, err := options.runTestPlan()
-	if err != nil {
-		options.testTearDown()
-		return result, err
+	// the modified apply of ExpectedModifiedChanges already planned and checked the consistency of the last apply
+	result := options.modifiedApplyPlan
+	if result == nil {
+		result, err = options.runTestPlan()
+		if err != nil {
+			options.testTearDown()
+			return result, err
+		}
+		options.LastConsistencyReport = CheckConsistency(result, options)
 	}
-	hasConsistencyChanges := CheckConsistency(result, options)
 
-	if hasConsistencyChanges {
-		terraform.PlanContext(options.Testing, context.Background(), options.TerraformOptions)
+	if options.LastConsistencyReport.HasFailures() {
+		terraform.PlanContext(options.Testing, options.getRunContext(), options.TerraformOptions)
 	}
 
 	logger.Log(options.Testing, "FINISHED: Init / Apply / Consistency Check")
@@ -614,7 +659,10 @@ func (options *TestOptions) RunTestConsistency() (*terraform.PlanStruct, error)
 
 // RunTestPlan Runs Test plan and returns the plan as a struct for assertions
 func (options *TestOptions) RunTestPlan() (*terraform.PlanStruct, error) {
-	options.testSetup()
+	if err := options.testSetup(); err != nil {
+		options.testTearDown()
+		return nil, err
+	}
 	outputStruct, err := options.runTestPlan()
 	options.testTearDown()
 
@@ -637,7 +685,7 @@ func (options *TestOptions) runTestPlan() (*terraform.PlanStruct, error) {
 	// The "show" command will produce a very large JSON to stdout which is printed by the logger.
 	// We are temporarily turning the terratest logger OFF (discard) while running "show" to prevent large JSON stdout.
 	options.TerraformOptions.Logger = logger.Discard
-	outputStruct, err := terraform.InitAndPlanAndShowWithStructContextE(options.Testing, context.Background(), options.TerraformOptions)
+	outputStruct, err := terraform.InitAndPlanAndShowWithStructContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
 
 	options.TerraformOptions.Logger = logger.Default // turn log back on
 
@@ -649,7 +697,10 @@ func (options *TestOptions) runTestPlan() (*terraform.PlanStruct, error) {
 
 // RunTest Runs Test and returns the output as a string for assertions
 func (options *TestOptions) RunTest() (string, error) {
-	options.testSetup()
+	if err := options.testSetup(); err != nil {
+		options.testTearDown()
+		return "", err
+	}
 	output, err := options.runTest()
 	options.testTearDown()
 
@@ -659,6 +710,7 @@ func (options *TestOptions) RunTest() (string, error) {
 // runTest Runs Test and returns the output as a string for assertions for internal use no setup or teardown
 func (options *TestOptions) runTest() (string, error) {
 
+	options.modifiedApplyPlan = nil
 	if options.PreApplyHook != nil {
 		logger.Log(options.Testing, "Running PreApplyHook")
 		hook_err := options.PreApplyHook(options)
@@ -668,7 +720,8 @@ func (options *TestOptions) runTest() (string, error) {
 		logger.Log(options.Testing, "Finished PreApplyHook")
 	}
 	logger.Log(options.Testing, "START: Init / Apply")
-	output, err := terraform.InitAndApplyContextE(options.Testing, context.Background(), options.TerraformOptions)
+	options.recordTeardownJournal()
+	output, err := terraform.InitAndApplyContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
 	assert.Nil(options.Testing, err, "Failed", err)
 	logger.Log(options.Testing, "FINISHED: Init / Apply")
 
@@ -678,7 +731,7 @@ func (options *TestOptions) runTest() (string, error) {
 
 		// Turn off logging for this step so sensitive data is not logged
 		options.TerraformOptions.Logger = logger.Discard
-		options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, context.Background(), options.TerraformOptions)
+		options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
 		options.TerraformOptions.Logger = logger.Default // turn log back on
 
 		if outputErr != nil {
@@ -686,18 +739,13 @@ func (options *TestOptions) runTest() (string, error) {
 		}
 	}
 
-	// run another terraform apply if ModifiedTerraformVars have been set
+	// run another terraform apply if ModifiedTerraformVars have been set. Its errors fail the test, they are not
+	// returned so that the PostApplyHook still runs.
 	if err == nil && options.ModifiedTerraformVars != nil {
-		logger.Log(options.Testing, "Running modified apply with terraform vars")
-		logger.Log(options.Testing, "START: Modify Apply")
-		options.TerraformOptions = terraform.WithDefaultRetryableErrors(options.Testing, &terraform.Options{
-			TerraformDir:    options.TerraformDir,
-			TerraformBinary: options.TerraformBinary,
-			Vars:            options.ModifiedTerraformVars,
-		})
-		_, err := terraform.ApplyContextE(options.Testing, context.Background(), options.TerraformOptions)
-		assert.Nil(options.Testing, err, "Failed", err)
-		logger.Log(options.Testing, "FINISHED: Modify Apply")
+		if modifiedErr := options.runModifiedApply(); modifiedErr != nil {
+			logger.Log(options.Testing, "Modified apply failed: ", modifiedErr)
+			options.Testing.Fail()
+		}
 	}
 
 	if err == nil && options.PostApplyHook != nil {
@@ -707,6 +755,8 @@ func (options *TestOptions) runTest() (string, error) {
 			return "", hook_err
 		}
 		logger.Log(options.Testing, "Finished PostApplyHook")
+		// the hook can change resources, so the plan after the modified apply can not be reused
+		options.modifiedApplyPlan = nil
 	}
 	return output, err
 }
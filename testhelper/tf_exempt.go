@@ -1,11 +1,51 @@
 package testhelper
 
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
 // Exemptions Struct to hold the list of exemptions
 type Exemptions struct {
+	// List of exact terraform resource addresses, for example: `module.some_module.null_resource.foo`
 	List []string
+
+	// Glob style address patterns, where `*` matches any sequence of characters and `?` matches a single character.
+	// Example: `module.*.null_resource.*`
+	Patterns []string
+
+	// Regular expressions that are matched against the full resource address.
+	// Example: `^module\.cos\[.+\]\.ibm_iam_authorization_policy\..*$`
+	RegexPatterns []string
+
+	// Attribute level exemptions. These only apply to updates: an update is exempt if every attribute that differs
+	// between the before and after values of the change is covered by an attribute exemption for the resource.
+	Attributes []AttributeExemption
 }
 
-// IsExemptedResource Checks if resource string is in the list of exemptions
+// AttributeExemption identifies attribute paths of a resource that should be ignored when checking for updates
+type AttributeExemption struct {
+	// Resource address, either exact or a glob style pattern (see Exemptions.Patterns)
+	Address string
+
+	// Attribute paths to ignore, using dots to separate nested attributes and list indexes.
+	// A path also ignores all of its children, and `*` can be used to match any single segment.
+	// Examples: `parameters.last_updated`, `tags`, `rules.*.name`
+	Paths []string
+}
+
+// exemptionRegexCache holds the compiled RegexPatterns of all exemptions, as exemptions are checked for every resource
+// of every plan. Invalid patterns are cached as nil so that they are only logged once.
+var exemptionRegexCache sync.Map
+
+// IsExemptedResource Checks if resource string is in the list of exemptions or matches one of the exemption patterns
 func (exemptions Exemptions) IsExemptedResource(resource string) bool {
 
 	for _, exemption := range exemptions.List {
@@ -14,5 +54,182 @@ func (exemptions Exemptions) IsExemptedResource(resource string) bool {
 		}
 	}
 
+	for _, pattern := range exemptions.Patterns {
+		if matchAddressGlob(pattern, resource) {
+			return true
+		}
+	}
+
+	for _, pattern := range exemptions.RegexPatterns {
+		if re := getExemptionRegex(pattern); re != nil && re.MatchString(resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getExemptionRegex returns the compiled regular expression of an exemption pattern, or nil if the pattern is not valid
+func getExemptionRegex(pattern string) *regexp.Regexp {
+	if cached, found := exemptionRegexCache.Load(pattern); found {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Printf("Invalid exemption regex pattern %q: %s", pattern, err)
+		re = nil
+	}
+	exemptionRegexCache.Store(pattern, re)
+	return re
+}
+
+// IsExemptedUpdate Checks if an update to a resource is exempt, either because the whole resource is exempt or because
+// every changed attribute is covered by an attribute exemption for the resource.
+func (exemptions Exemptions) IsExemptedUpdate(resource *tfjson.ResourceChange) bool {
+	if exemptions.IsExemptedResource(resource.Address) {
+		return true
+	}
+
+	ignoredPaths := exemptions.attributePathsForAddress(resource.Address)
+	if len(ignoredPaths) == 0 || resource.Change == nil {
+		return false
+	}
+
+	changedPaths := GetChangedAttributePaths(resource.Change)
+	if len(changedPaths) == 0 {
+		return false
+	}
+
+	for _, changedPath := range changedPaths {
+		if !isIgnoredAttributePath(changedPath, ignoredPaths) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attributePathsForAddress returns all ignored attribute paths that apply to the given resource address
+func (exemptions Exemptions) attributePathsForAddress(address string) []string {
+	var paths []string
+	for _, attribute := range exemptions.Attributes {
+		if attribute.Address == address || matchAddressGlob(attribute.Address, address) {
+			paths = append(paths, attribute.Paths...)
+		}
+	}
+	return paths
+}
+
+// GetChangedAttributePaths returns a sorted list of attribute paths (dot separated) whose values differ between the
+// before and after values of a terraform change.
+func GetChangedAttributePaths(change *tfjson.Change) []string {
+	paths := diffAttributePaths(change.Before, change.After, "")
+	sort.Strings(paths)
+	return paths
+}
+
+// diffAttributePaths recursively compares two values and returns the paths of all leaf values that differ
+func diffAttributePaths(before interface{}, after interface{}, prefix string) []string {
+	var paths []string
+
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]bool)
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		for key := range keys {
+			paths = append(paths, diffAttributePaths(beforeMap[key], afterMap[key], joinAttributePath(prefix, key))...)
+		}
+		return paths
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		length := len(beforeList)
+		if len(afterList) > length {
+			length = len(afterList)
+		}
+		for i := 0; i < length; i++ {
+			var beforeItem, afterItem interface{}
+			if i < len(beforeList) {
+				beforeItem = beforeList[i]
+			}
+			if i < len(afterList) {
+				afterItem = afterList[i]
+			}
+			paths = append(paths, diffAttributePaths(beforeItem, afterItem, joinAttributePath(prefix, fmt.Sprint(i)))...)
+		}
+		return paths
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		paths = append(paths, prefix)
+	}
+
+	return paths
+}
+
+// joinAttributePath appends a segment to an attribute path
+func joinAttributePath(prefix string, segment string) string {
+	if prefix == "" {
+		return segment
+	}
+	return prefix + "." + segment
+}
+
+// isIgnoredAttributePath returns true if the path is equal to, or a child of, one of the ignored paths
+func isIgnoredAttributePath(path string, ignoredPaths []string) bool {
+	pathSegments := strings.Split(path, ".")
+	for _, ignoredPath := range ignoredPaths {
+		ignoredSegments := strings.Split(ignoredPath, ".")
+		if len(ignoredSegments) > len(pathSegments) {
+			continue
+		}
+		matched := true
+		for i, ignoredSegment := range ignoredSegments {
+			if ignoredSegment != "*" && ignoredSegment != pathSegments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
 	return false
 }
+
+// matchAddressGlob matches a resource address against a glob style pattern, where `*` matches any sequence of
+// characters and `?` matches a single character. All other characters are matched literally.
+func matchAddressGlob(globPattern string, resourceAddress string) bool {
+	pattern, address := []rune(globPattern), []rune(resourceAddress)
+	// position in the pattern and address after the last `*`, to backtrack to when the rest does not match
+	starPattern, starAddress := -1, 0
+	p, a := 0, 0
+	for a < len(address) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == address[a]):
+			p++
+			a++
+		case p < len(pattern) && pattern[p] == '*':
+			starPattern, starAddress = p, a
+			p++
+		case starPattern >= 0:
+			// let the last `*` match one more character
+			starAddress++
+			p, a = starPattern+1, starAddress
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
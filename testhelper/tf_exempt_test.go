@@ -3,6 +3,7 @@ package testhelper
 import (
 	"testing"
 
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,3 +26,84 @@ func TestEmptyExemptionList(t *testing.T) {
 
 	assert.False(t, empty.IsExemptedResource("i_am_not_exempt"), "This should not have been exempt")
 }
+
+func TestIsExemptByPattern(t *testing.T) {
+	patterns := Exemptions{
+		Patterns:      []string{"module.*.null_resource.foo", "time_sleep.wait_?"},
+		RegexPatterns: []string{`^module\.cos\[".+"\]\.ibm_iam_authorization_policy\..*$`, "[invalid"},
+	}
+
+	assert.True(t, patterns.IsExemptedResource("module.a.null_resource.foo"))
+	assert.True(t, patterns.IsExemptedResource("module.a.module.b.null_resource.foo"))
+	assert.True(t, patterns.IsExemptedResource("time_sleep.wait_1"))
+	assert.True(t, patterns.IsExemptedResource(`module.cos["one"].ibm_iam_authorization_policy.policy`))
+	assert.False(t, patterns.IsExemptedResource("module.a.null_resource.bar"))
+	assert.False(t, patterns.IsExemptedResource("time_sleep.wait_10"))
+	assert.False(t, patterns.IsExemptedResource("module.cos.ibm_iam_authorization_policy.policy"))
+}
+
+func TestMatchAddressGlob(t *testing.T) {
+	assert.True(t, matchAddressGlob("module.*", "module.a.null_resource.foo"))
+	assert.True(t, matchAddressGlob("*.foo", "module.a.null_resource.foo"))
+	assert.True(t, matchAddressGlob("module.*.null_resource.*", "module.a.null_resource.foo"))
+	assert.True(t, matchAddressGlob(`module.cos["?"].*`, `module.cos["é"].ibm_cos_bucket.bucket`))
+	assert.True(t, matchAddressGlob("*", ""))
+	assert.True(t, matchAddressGlob("null_resource.foo", "null_resource.foo"))
+	assert.False(t, matchAddressGlob("null_resource.foo", "null_resource.foo2"))
+	assert.False(t, matchAddressGlob("module.*.null_resource.foo", "module.a.null_resource.bar"))
+	assert.False(t, matchAddressGlob("null_resource.?", "null_resource."))
+}
+
+func TestIsExemptedUpdate(t *testing.T) {
+	attributes := Exemptions{
+		Attributes: []AttributeExemption{
+			{Address: "module.*.ibm_resource_instance.instance", Paths: []string{"parameters.last_updated", "tags"}},
+			{Address: "ibm_is_security_group.sg", Paths: []string{"rules.*.name"}},
+		},
+	}
+
+	before := map[string]interface{}{
+		"name":       "instance",
+		"tags":       []interface{}{"a", "b"},
+		"parameters": map[string]interface{}{"last_updated": "1", "plan": "lite"},
+	}
+
+	t.Run("OnlyIgnoredAttributesChanged", func(t *testing.T) {
+		after := map[string]interface{}{
+			"name":       "instance",
+			"tags":       []interface{}{"b", "a", "c"},
+			"parameters": map[string]interface{}{"last_updated": "2", "plan": "lite"},
+		}
+		change := newTestResourceChange("module.a.ibm_resource_instance.instance", "ibm_resource_instance", tfjson.Actions{tfjson.ActionUpdate}, before, after)
+		assert.True(t, attributes.IsExemptedUpdate(change))
+		assert.Equal(t, []string{"parameters.last_updated", "tags.0", "tags.1", "tags.2"}, GetChangedAttributePaths(change.Change))
+	})
+
+	t.Run("OtherAttributeChanged", func(t *testing.T) {
+		after := map[string]interface{}{
+			"name":       "instance",
+			"tags":       []interface{}{"a", "b"},
+			"parameters": map[string]interface{}{"last_updated": "2", "plan": "standard"},
+		}
+		change := newTestResourceChange("module.a.ibm_resource_instance.instance", "ibm_resource_instance", tfjson.Actions{tfjson.ActionUpdate}, before, after)
+		assert.False(t, attributes.IsExemptedUpdate(change))
+	})
+
+	t.Run("WildcardSegment", func(t *testing.T) {
+		sgBefore := map[string]interface{}{"rules": []interface{}{map[string]interface{}{"name": "a", "port": 22}}}
+		renamed := map[string]interface{}{"rules": []interface{}{map[string]interface{}{"name": "b", "port": 22}}}
+		newPort := map[string]interface{}{"rules": []interface{}{map[string]interface{}{"name": "a", "port": 443}}}
+		assert.True(t, attributes.IsExemptedUpdate(newTestResourceChange("ibm_is_security_group.sg", "ibm_is_security_group", tfjson.Actions{tfjson.ActionUpdate}, sgBefore, renamed)))
+		assert.False(t, attributes.IsExemptedUpdate(newTestResourceChange("ibm_is_security_group.sg", "ibm_is_security_group", tfjson.Actions{tfjson.ActionUpdate}, sgBefore, newPort)))
+	})
+
+	t.Run("AddressNotMatched", func(t *testing.T) {
+		after := map[string]interface{}{
+			"name":       "instance",
+			"tags":       []interface{}{"a", "b"},
+			"parameters": map[string]interface{}{"last_updated": "2", "plan": "lite"},
+		}
+		change := newTestResourceChange("ibm_resource_instance.instance", "ibm_resource_instance", tfjson.Actions{tfjson.ActionUpdate}, before, after)
+		assert.False(t, attributes.IsExemptedUpdate(change))
+	})
+}
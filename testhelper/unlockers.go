@@ -0,0 +1,338 @@
+package testhelper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	project "github.com/IBM/project-go-sdk/projectv1"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// Output names of the built-in unlockers
+const (
+	UnlockOutputCBRRuleIDs              = "cbr_rule_ids"
+	UnlockOutputIamPolicyIDs            = "iam_policy_ids"
+	UnlockOutputServiceAuthorizationIDs = "service_authorization_ids"
+	UnlockOutputKmsKeyCRNs              = "kms_key_crns"
+	UnlockOutputCosBucketURLs           = "cos_bucket_urls"
+)
+
+// Unlocker removes a protection that would make the destroy of test resources fail, for example a CBR rule that
+// blocks the API calls of the destroy, or an IAM policy created outside of terraform that keeps a resource in use.
+// Unlockers are run before the destroy for every value of a terraform output, see RunUnlockers.
+type Unlocker interface {
+	// Name describes the unlocker in log messages
+	Name() string
+	// Unlock removes the protection identified by one value of the output
+	Unlock(cloudInfoService cloudinfo.CloudInfoServiceI, value string) error
+}
+
+// funcUnlocker is the Unlocker returned by NewUnlocker
+type funcUnlocker struct {
+	name   string
+	unlock func(cloudInfoService cloudinfo.CloudInfoServiceI, value string) error
+}
+
+func (unlocker *funcUnlocker) Name() string {
+	return unlocker.name
+}
+
+func (unlocker *funcUnlocker) Unlock(cloudInfoService cloudinfo.CloudInfoServiceI, value string) error {
+	return unlocker.unlock(cloudInfoService, value)
+}
+
+// NewUnlocker returns an Unlocker that calls the unlock function for every value
+func NewUnlocker(name string, unlock func(cloudInfoService cloudinfo.CloudInfoServiceI, value string) error) Unlocker {
+	return &funcUnlocker{name: name, unlock: unlock}
+}
+
+// CBRRuleUnlocker disables the CBR rules with the IDs of the output
+var CBRRuleUnlocker = NewUnlocker("disable CBR rule", func(cloudInfoService cloudinfo.CloudInfoServiceI, ruleID string) error {
+	service, err := getUnlockService[interface {
+		SetCBREnforcementMode(ruleID string, mode string) error
+	}](cloudInfoService)
+	if err != nil {
+		return err
+	}
+	return service.SetCBREnforcementMode(ruleID, "disabled")
+})
+
+// IamPolicyUnlocker deletes the IAM policies with the IDs of the output. Access policies created outside of terraform
+// and service to service authorizations, for example authorizations scoped to the test resource group that block its
+// deletion, are deleted with the same API. Policies that were already deleted are skipped.
+var IamPolicyUnlocker = NewUnlocker("delete IAM policy", func(cloudInfoService cloudinfo.CloudInfoServiceI, policyID string) error {
+	service, err := getUnlockService[interface {
+		DeleteIamPolicyByID(policyID string) error
+	}](cloudInfoService)
+	if err != nil {
+		return err
+	}
+	err = service.DeleteIamPolicyByID(policyID)
+	if err != nil && err.Error() == cloudinfo.ErrPolicyNotFound {
+		return nil
+	}
+	return err
+})
+
+// KmsKeyUnlocker turns off the dual authorization delete policy of the Key Protect keys with the CRNs of the output,
+// which otherwise requires a second user to authorize the deletion of each key
+var KmsKeyUnlocker = NewUnlocker("disable KMS key dual authorization delete", func(cloudInfoService cloudinfo.CloudInfoServiceI, keyCRN string) error {
+	service, err := getUnlockService[interface {
+		DisableKmsKeyDualAuthDelete(keyCRN string) error
+	}](cloudInfoService)
+	if err != nil {
+		return err
+	}
+	return service.DisableKmsKeyDualAuthDelete(keyCRN)
+})
+
+// CosBucketRetentionUnlocker removes the Object Lock default retention of the Object Storage buckets with the URLs of
+// the output, the S3 endpoint of each bucket followed by its name. Objects that were already locked keep their
+// retention, so only use it for buckets whose objects are written during the test with a short retention.
+var CosBucketRetentionUnlocker = NewUnlocker("remove COS bucket default retention", func(cloudInfoService cloudinfo.CloudInfoServiceI, bucketURL string) error {
+	service, err := getUnlockService[interface {
+		RemoveCosBucketDefaultRetention(bucketURL string) error
+	}](cloudInfoService)
+	if err != nil {
+		return err
+	}
+	return service.RemoveCosBucketDefaultRetention(bucketURL)
+})
+
+// getUnlockService returns the cloud info service of the test if it implements the calls of an unlocker, otherwise a
+// new CloudInfoService created from the environment, for tests that set their own implementation of CloudInfoServiceI
+func getUnlockService[T any](cloudInfoService cloudinfo.CloudInfoServiceI) (T, error) {
+	if service, ok := cloudInfoService.(T); ok {
+		return service, nil
+	}
+	var service T
+	cloudInfoSvc, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{})
+	if err != nil {
+		return service, fmt.Errorf("error creating CloudInfoService for unlocker: %w", err)
+	}
+	service, ok := any(cloudInfoSvc).(T)
+	if !ok {
+		return service, fmt.Errorf("cloud info service does not support the unlocker")
+	}
+	return service, nil
+}
+
+// unlockerRegistry holds the unlockers used for an output name when a test does not set its own
+var unlockerRegistry = map[string]Unlocker{
+	UnlockOutputCBRRuleIDs:              CBRRuleUnlocker,
+	UnlockOutputIamPolicyIDs:            IamPolicyUnlocker,
+	UnlockOutputServiceAuthorizationIDs: IamPolicyUnlocker,
+	UnlockOutputKmsKeyCRNs:              KmsKeyUnlocker,
+	UnlockOutputCosBucketURLs:           CosBucketRetentionUnlocker,
+}
+var unlockerRegistryLock sync.RWMutex
+
+// RegisterUnlocker registers the unlocker used for the output name by every test that lists the output in
+// UnlockOutputs, replacing a registered unlocker of the same output name.
+func RegisterUnlocker(outputName string, unlocker Unlocker) {
+	unlockerRegistryLock.Lock()
+	defer unlockerRegistryLock.Unlock()
+	unlockerRegistry[outputName] = unlocker
+}
+
+// GetRegisteredUnlocker returns the unlocker registered for the output name
+func GetRegisteredUnlocker(outputName string) (Unlocker, bool) {
+	unlockerRegistryLock.RLock()
+	defer unlockerRegistryLock.RUnlock()
+	unlocker, found := unlockerRegistry[outputName]
+	return unlocker, found
+}
+
+// UnlockResult is the outcome of unlocking one value of an output
+type UnlockResult struct {
+	OutputName string
+	Unlocker   string // name of the unlocker, empty if no unlocker was found for the output
+	Value      string // empty if the output was not found or had no values
+	Err        error
+}
+
+// RunUnlockers runs the unlocker of every output name on each value of the output. The unlocker of an output is taken
+// from unlockers, or else from the registry. String, list and map outputs are supported. Errors are returned in the
+// results, an unlocker that fails does not stop the others.
+func RunUnlockers(cloudInfoService cloudinfo.CloudInfoServiceI, outputs map[string]interface{}, outputNames []string, unlockers map[string]Unlocker) []UnlockResult {
+	var results []UnlockResult
+	for _, outputName := range outputNames {
+		unlocker, found := unlockers[outputName]
+		if !found {
+			unlocker, found = GetRegisteredUnlocker(outputName)
+		}
+		if !found {
+			results = append(results, UnlockResult{OutputName: outputName, Err: fmt.Errorf("no unlocker registered for output %s", outputName)})
+			continue
+		}
+
+		value, found := outputs[outputName]
+		if !found {
+			results = append(results, UnlockResult{OutputName: outputName, Unlocker: unlocker.Name(), Err: fmt.Errorf("output %s not found", outputName)})
+			continue
+		}
+		for _, item := range unlockValues(value) {
+			results = append(results, UnlockResult{
+				OutputName: outputName,
+				Unlocker:   unlocker.Name(),
+				Value:      item,
+				Err:        unlocker.Unlock(cloudInfoService, item),
+			})
+		}
+	}
+	return results
+}
+
+// unlockValues returns the string values of an output, which may be a string, list or map
+func unlockValues(value interface{}) []string {
+	var values []string
+	switch typed := value.(type) {
+	case string:
+		if typed != "" {
+			values = append(values, typed)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			values = append(values, unlockValues(item)...)
+		}
+	case []string:
+		for _, item := range typed {
+			values = append(values, unlockValues(item)...)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values = append(values, unlockValues(typed[key])...)
+		}
+	}
+	return values
+}
+
+// FormatUnlockResults returns one line per result for the test log
+func FormatUnlockResults(results []UnlockResult) string {
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		status := "OK"
+		if result.Err != nil {
+			status = fmt.Sprintf("FAILED: %s", result.Err)
+		}
+		if result.Value == "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", result.OutputName, status))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s %s: %s", result.OutputName, result.Unlocker, result.Value, status))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ProjectOutputs returns the outputs of all configurations of a project, keyed by output name, for use with
+// RunUnlockers in project and addon tests. If two configurations have an output of the same name, their values are
+// combined in a list.
+func ProjectOutputs(cloudInfoService cloudinfo.CloudInfoServiceI, projectID string) (map[string]interface{}, error) {
+	configs, err := cloudInfoService.GetProjectConfigs(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing configurations of project %s: %w", projectID, err)
+	}
+
+	outputs := make(map[string]interface{})
+	for _, configSummary := range configs {
+		if configSummary.ID == nil {
+			continue
+		}
+		config, _, err := cloudInfoService.GetConfig(&cloudinfo.ConfigDetails{ProjectID: projectID, ConfigID: *configSummary.ID})
+		if err != nil {
+			return nil, fmt.Errorf("error getting configuration %s of project %s: %w", *configSummary.ID, projectID, err)
+		}
+		addProjectConfigOutputs(outputs, config)
+	}
+	return outputs, nil
+}
+
+// RunProjectUnlockers runs the unlockers of the output names on the outputs of the configurations of a project, see
+// ProjectOutputs, and logs the results. Used by project and addon tests before the undeploy, failures are logged as
+// warnings and do not fail the test.
+func RunProjectUnlockers(cloudInfoService cloudinfo.CloudInfoServiceI, projectID string, outputNames []string, unlockers map[string]Unlocker, testLogger common.Logger) {
+	outputs, err := ProjectOutputs(cloudInfoService, projectID)
+	if err != nil {
+		testLogger.ShortWarn(fmt.Sprintf("Skipping unlockers: %s", err))
+		return
+	}
+
+	testLogger.ShortInfo("Running unlockers before undeploy")
+	for _, result := range RunUnlockers(cloudInfoService, outputs, outputNames, unlockers) {
+		if result.Err != nil {
+			testLogger.ShortWarn(FormatUnlockResults([]UnlockResult{result}))
+		} else {
+			testLogger.ShortInfo(FormatUnlockResults([]UnlockResult{result}))
+		}
+	}
+}
+
+// addProjectConfigOutputs adds the outputs of the configuration, combining values of outputs with the same name
+func addProjectConfigOutputs(outputs map[string]interface{}, config *project.ProjectConfig) {
+	if config == nil {
+		return
+	}
+	for _, output := range config.Outputs {
+		if output.Name == nil {
+			continue
+		}
+		existing, found := outputs[*output.Name]
+		if !found {
+			outputs[*output.Name] = output.Value
+			continue
+		}
+		combined, isList := existing.([]interface{})
+		if !isList {
+			combined = []interface{}{existing}
+		}
+		outputs[*output.Name] = append(combined, output.Value)
+	}
+}
+
+// runUnlockers runs the unlockers of UnlockOutputs and CBRRuleListOutputVariable on the last outputs, before destroy.
+// Results are logged, failures do not fail the test.
+func (options *TestOptions) runUnlockers() {
+	outputNames := append([]string{}, options.UnlockOutputs...)
+	unlockers := make(map[string]Unlocker, len(options.Unlockers)+1)
+	for outputName, unlocker := range options.Unlockers {
+		unlockers[outputName] = unlocker
+	}
+	if options.CBRRuleListOutputVariable != "" {
+		outputNames = append(outputNames, options.CBRRuleListOutputVariable)
+		unlockers[options.CBRRuleListOutputVariable] = CBRRuleUnlocker
+	}
+	if len(outputNames) == 0 {
+		return
+	}
+
+	cloudInfoService := options.CloudInfoService
+	if cloudInfoService == nil {
+		cacheEnabled := true
+		if options.CacheEnabled != nil {
+			cacheEnabled = *options.CacheEnabled
+		}
+		cloudInfoSvc, err := cloudinfo.NewCloudInfoServiceFromEnv(ibmcloudApiKeyVar, cloudinfo.CloudInfoServiceOptions{
+			CacheEnabled: cacheEnabled,
+			CacheTTL:     options.CacheTTL,
+		})
+		if err != nil {
+			logger.Log(options.Testing, "Error creating CloudInfoService for testhelper, skipping unlockers: ", err)
+			return
+		}
+		cloudInfoService = cloudInfoSvc
+	}
+
+	logger.Log(options.Testing, "START: Unlockers")
+	results := RunUnlockers(cloudInfoService, options.LastTestTerraformOutputs, outputNames, unlockers)
+	logger.Log(options.Testing, FormatUnlockResults(results))
+	logger.Log(options.Testing, "END: Unlockers, continuing with destroy")
+}
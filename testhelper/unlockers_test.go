@@ -0,0 +1,154 @@
+package testhelper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	project "github.com/IBM/project-go-sdk/projectv1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
+)
+
+// fakeUnlockService implements the cloud info calls used by the built-in unlockers and ProjectOutputs
+type fakeUnlockService struct {
+	cloudinfo.CloudInfoServiceI
+	disabledRules   []string
+	deletedPolicies []string
+	unlockedKeys    []string
+	unlockedBuckets []string
+	configs         map[string]*project.ProjectConfig
+}
+
+func (f *fakeUnlockService) SetCBREnforcementMode(ruleID string, mode string) error {
+	if mode != "disabled" {
+		return errors.New("unexpected mode")
+	}
+	f.disabledRules = append(f.disabledRules, ruleID)
+	return nil
+}
+
+func (f *fakeUnlockService) DeleteIamPolicyByID(policyID string) error {
+	if policyID == "gone" {
+		return errors.New(cloudinfo.ErrPolicyNotFound)
+	}
+	f.deletedPolicies = append(f.deletedPolicies, policyID)
+	return nil
+}
+
+func (f *fakeUnlockService) DisableKmsKeyDualAuthDelete(keyCRN string) error {
+	f.unlockedKeys = append(f.unlockedKeys, keyCRN)
+	return nil
+}
+
+func (f *fakeUnlockService) RemoveCosBucketDefaultRetention(bucketURL string) error {
+	f.unlockedBuckets = append(f.unlockedBuckets, bucketURL)
+	return nil
+}
+
+func (f *fakeUnlockService) GetProjectConfigs(projectID string) ([]project.ProjectConfigSummary, error) {
+	var summaries []project.ProjectConfigSummary
+	for _, id := range []string{"config-1", "config-2"} {
+		summaries = append(summaries, project.ProjectConfigSummary{ID: core.StringPtr(id)})
+	}
+	return summaries, nil
+}
+
+func (f *fakeUnlockService) GetConfig(configDetails *cloudinfo.ConfigDetails) (*project.ProjectConfig, *core.DetailedResponse, error) {
+	return f.configs[configDetails.ConfigID], nil, nil
+}
+
+func TestRunUnlockers(t *testing.T) {
+	service := &fakeUnlockService{}
+	outputs := map[string]interface{}{
+		UnlockOutputCBRRuleIDs:   []interface{}{"rule-1", "rule-2"},
+		UnlockOutputIamPolicyIDs: map[string]interface{}{"b": "policy-2", "a": []interface{}{"policy-1", "gone"}},
+		"bucket_names":           "bucket-1",
+	}
+	failing := NewUnlocker("remove bucket retention", func(cloudInfoService cloudinfo.CloudInfoServiceI, value string) error {
+		return errors.New("retention can not be removed")
+	})
+
+	results := RunUnlockers(service, outputs, []string{UnlockOutputCBRRuleIDs, UnlockOutputIamPolicyIDs, "bucket_names", "missing", "unknown"}, map[string]Unlocker{
+		"bucket_names": failing,
+		"missing":      failing,
+	})
+
+	assert.Equal(t, []string{"rule-1", "rule-2"}, service.disabledRules)
+	assert.Equal(t, []string{"policy-1", "policy-2"}, service.deletedPolicies)
+	require.Len(t, results, 8)
+	for _, result := range results[:5] {
+		assert.NoError(t, result.Err, result.Value)
+	}
+	assert.Equal(t, UnlockResult{OutputName: "bucket_names", Unlocker: "remove bucket retention", Value: "bucket-1", Err: errors.New("retention can not be removed")}, results[5])
+	assert.EqualError(t, results[6].Err, "output missing not found")
+	assert.EqualError(t, results[7].Err, "no unlocker registered for output unknown")
+
+	formatted := FormatUnlockResults(results)
+	assert.Contains(t, formatted, "cbr_rule_ids: disable CBR rule rule-1: OK")
+	assert.Contains(t, formatted, "bucket_names: remove bucket retention bucket-1: FAILED: retention can not be removed")
+	assert.Contains(t, formatted, "unknown: FAILED: no unlocker registered for output unknown")
+}
+
+func TestKmsAndCosUnlockers(t *testing.T) {
+	service := &fakeUnlockService{}
+	outputs := map[string]interface{}{
+		UnlockOutputKmsKeyCRNs:    []interface{}{"crn:v1:bluemix:public:kms:us-south:a/account:instance-1:key:key-1"},
+		UnlockOutputCosBucketURLs: "https://s3.us-south.cloud-object-storage.appdomain.cloud/bucket-1",
+	}
+
+	results := RunUnlockers(service, outputs, []string{UnlockOutputKmsKeyCRNs, UnlockOutputCosBucketURLs}, nil)
+	require.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, []string{"crn:v1:bluemix:public:kms:us-south:a/account:instance-1:key:key-1"}, service.unlockedKeys)
+	assert.Equal(t, []string{"https://s3.us-south.cloud-object-storage.appdomain.cloud/bucket-1"}, service.unlockedBuckets)
+}
+
+func TestUnlockersWithOtherCloudInfoService(t *testing.T) {
+	// a service without the unlock calls falls back to a CloudInfoService created from the environment
+	t.Setenv(ibmcloudApiKeyVar, "")
+	results := RunUnlockers(&struct{ cloudinfo.CloudInfoServiceI }{}, map[string]interface{}{
+		UnlockOutputCBRRuleIDs:              "rule-1",
+		UnlockOutputServiceAuthorizationIDs: "policy-1",
+	}, []string{UnlockOutputCBRRuleIDs, UnlockOutputServiceAuthorizationIDs}, nil)
+	require.Len(t, results, 2)
+	assert.ErrorContains(t, results[0].Err, "error creating CloudInfoService for unlocker")
+	assert.Equal(t, "delete IAM policy", results[1].Unlocker, "service authorizations are deleted as IAM policies")
+	assert.ErrorContains(t, results[1].Err, "error creating CloudInfoService for unlocker")
+}
+
+func TestRegisterUnlocker(t *testing.T) {
+	var unlocked []string
+	RegisterUnlocker("test_kms_key_ids", NewUnlocker("disable key protection", func(cloudInfoService cloudinfo.CloudInfoServiceI, value string) error {
+		unlocked = append(unlocked, value)
+		return nil
+	}))
+
+	unlocker, found := GetRegisteredUnlocker("test_kms_key_ids")
+	require.True(t, found)
+	assert.Equal(t, "disable key protection", unlocker.Name())
+
+	results := RunUnlockers(&fakeUnlockService{}, map[string]interface{}{"test_kms_key_ids": []string{"key-1", ""}}, []string{"test_kms_key_ids"}, nil)
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, []string{"key-1"}, unlocked)
+}
+
+func TestProjectOutputs(t *testing.T) {
+	service := &fakeUnlockService{configs: map[string]*project.ProjectConfig{
+		"config-1": {Outputs: []project.OutputValue{
+			{Name: core.StringPtr(UnlockOutputCBRRuleIDs), Value: []interface{}{"rule-1"}},
+			{Name: core.StringPtr(UnlockOutputIamPolicyIDs), Value: "policy-1"},
+		}},
+		"config-2": {Outputs: []project.OutputValue{
+			{Name: core.StringPtr(UnlockOutputIamPolicyIDs), Value: "policy-2"},
+		}},
+	}}
+
+	outputs, err := ProjectOutputs(service, "project-1")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"rule-1"}, outputs[UnlockOutputCBRRuleIDs])
+	assert.Equal(t, []interface{}{"policy-1", "policy-2"}, outputs[UnlockOutputIamPolicyIDs])
+}
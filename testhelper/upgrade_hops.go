@@ -0,0 +1,191 @@
+package testhelper
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// UpgradeHop is the result of a single step of a multi-release upgrade test (see TestOptions.UpgradeFromVersions),
+// where the state of the From release was planned with the code of the To release.
+type UpgradeHop struct {
+	From   string             `json:"from"`   // release tag the state was created with
+	To     string             `json:"to"`     // release tag, or PR branch for the final hop, that was planned
+	Report *ConsistencyReport `json:"report"` // consistency report of the plan for this hop
+}
+
+// IntroducedDestroy returns true if the plan of this hop had a delete or replace that was not exempted
+func (hop UpgradeHop) IntroducedDestroy() bool {
+	for _, violation := range hop.Report.Failures() {
+		if violation.ActionType == ConsistencyActionDelete || violation.ActionType == ConsistencyActionReplace {
+			return true
+		}
+	}
+	return false
+}
+
+// FirstDestroyHop returns the first hop of an upgrade path that introduced a destroy, or nil if there was none
+func FirstDestroyHop(hops []UpgradeHop) *UpgradeHop {
+	for i := range hops {
+		if hops[i].IntroducedDestroy() {
+			return &hops[i]
+		}
+	}
+	return nil
+}
+
+// runUpgradeFromVersions runs an upgrade test through every release in UpgradeFromVersions, starting with an apply of the
+// oldest release, then planning, checking consistency and applying each later release in turn, with the final hop
+// planning the PR branch. Test setup must already be done, teardown is done by this function.
+func (options *TestOptions) runUpgradeFromVersions(baseRepo string, relativeTestSampleDir string, prTempDir string, prBranch string) (*terraform.PlanStruct, error) {
+	options.LastUpgradeHops = nil
+
+	tags, err := common.GetRemoteTags(baseRepo)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := common.ResolveVersions(tags, options.UpgradeFromVersions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve UpgradeFromVersions %v: %w", options.UpgradeFromVersions, err)
+	}
+	labels := append(append([]string{}, versions...), prBranch)
+	logger.Log(options.Testing, "Upgrade path:", strings.Join(labels, " -> "))
+
+	// Clone each release into its own temporary directory, the PR branch is already in prTempDir
+	var terraformDirs []string
+	var newestReleaseDir string
+	for _, version := range versions {
+		releaseTempDir, err := os.MkdirTemp("", fmt.Sprintf("terraform-%s-%s", version, options.Prefix))
+		if err != nil {
+			// No need to tearDown as nothing was created
+			return nil, fmt.Errorf("failed to create temp dir for release %s: %v", version, err)
+		}
+		logger.Log(options.Testing, "TEMP UPGRADE RELEASE DIR CREATED: ", releaseTempDir)
+		if !options.SkipTestTearDown {
+			defer os.RemoveAll(releaseTempDir) // clean up
+		}
+
+		if cloneErr := common.CloneAndCheckoutTag(options.Testing, baseRepo, version, releaseTempDir); cloneErr != nil {
+			return nil, cloneErr
+		}
+		newestReleaseDir = path.Join(releaseTempDir, relativeTestSampleDir)
+		terraformDirs = append(terraformDirs, newestReleaseDir)
+	}
+	terraformDirs = append(terraformDirs, path.Join(prTempDir, relativeTestSampleDir))
+
+	// Compare the module interface of the newest release with the PR branch
+	if options.CheckModuleInterface {
+		if _, interfaceErr := options.checkModuleInterface(newestReleaseDir, terraformDirs[len(terraformDirs)-1]); interfaceErr != nil {
+			return nil, interfaceErr
+		}
+	}
+
+	// Apply the oldest release
+	options.setTerraformDir(terraformDirs[0])
+
+	if options.PreApplyHook != nil {
+		logger.Log(options.Testing, "Running PreApplyHook")
+		hookErr := options.PreApplyHook(options)
+		if hookErr != nil {
+			assert.Nilf(options.Testing, hookErr, "PreApplyHook failed")
+			options.testTearDown()
+			return nil, hookErr
+		}
+		logger.Log(options.Testing, "PreApplyHook completed successfully")
+	}
+
+	logger.Log(options.Testing, "Init / Apply on release:", labels[0])
+	options.recordTeardownJournal()
+	_, applyErr := terraform.InitAndApplyContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
+	if applyErr != nil {
+		assert.Nilf(options.Testing, applyErr, "Terraform Apply on release %s has failed", labels[0])
+		options.testTearDown()
+		return nil, applyErr
+	}
+
+	// set outputs after this apply so they are available for hooks
+	var outputErr error
+	// Turn off logging for this step so sensitive data is not logged
+	options.TerraformOptions.Logger = logger.Discard
+	options.LastTestTerraformOutputs, outputErr = terraform.OutputAllContextE(options.Testing, options.getRunContext(), options.TerraformOptions)
+	options.TerraformOptions.Logger = logger.Default // turn log back on
+	if outputErr != nil {
+		logger.Log(options.Testing, "failed to get terraform output: ", outputErr)
+	}
+
+	if options.PostApplyHook != nil {
+		logger.Log(options.Testing, "Running PostApplyHook")
+		hookErr := options.PostApplyHook(options)
+		if hookErr != nil {
+			assert.Nilf(options.Testing, hookErr, "PostApplyHook failed")
+			options.testTearDown()
+			return nil, hookErr
+		}
+		logger.Log(options.Testing, "PostApplyHook completed successfully")
+	}
+
+	// Plan, check and apply every following release using the state of the previous one
+	var result *terraform.PlanStruct
+	for i := 1; i < len(terraformDirs); i++ {
+		from, to := labels[i-1], labels[i]
+		finalHop := i == len(terraformDirs)-1
+		previousStatePath := path.Join(options.TerraformOptions.TerraformDir, "terraform.tfstate")
+
+		options.setTerraformDir(terraformDirs[i])
+
+		// ensure terraform working files/folders are removed before copying state file
+		CleanTerraformDir(options.TerraformOptions.TerraformDir)
+		if errCopyState := common.CopyFile(previousStatePath, path.Join(options.TerraformOptions.TerraformDir, "terraform.tfstate")); errCopyState != nil {
+			options.testTearDown()
+			return nil, fmt.Errorf("failed to copy state file for upgrade %s -> %s: %v", from, to, errCopyState)
+		}
+
+		logger.Log(options.Testing, fmt.Sprintf("Init / Plan upgrade hop %s -> %s", from, to))
+		var planErr error
+		result, planErr = options.runTestPlan()
+		if planErr != nil {
+			assert.Nilf(options.Testing, planErr, "Terraform Plan for upgrade hop %s -> %s has failed", from, to)
+			options.testTearDown()
+			return nil, planErr
+		}
+
+		logger.Log(options.Testing, fmt.Sprintf("Parsing plan output to determine if any resources identified for destroy (upgrade hop %s -> %s)...", from, to))
+		hop := UpgradeHop{From: from, To: to, Report: CheckConsistency(result, options)}
+		options.LastUpgradeHops = append(options.LastUpgradeHops, hop)
+		options.LastConsistencyReport = hop.Report
+		if hop.IntroducedDestroy() {
+			logger.Log(options.Testing, fmt.Sprintf("Upgrade hop %s -> %s introduced resource destroys", from, to))
+		}
+		if hop.Report.HasFailures() {
+			terraform.PlanContext(options.Testing, options.getRunContext(), options.TerraformOptions)
+		}
+
+		if finalHop && (!options.CheckApplyResultForUpgrade || options.Testing.Failed()) {
+			break
+		}
+
+		// apply this hop so the next hop starts from this release
+		logger.Log(options.Testing, "Apply on:", to)
+		options.recordTeardownJournal()
+		if _, applyErr := terraform.ApplyContextE(options.Testing, options.getRunContext(), options.TerraformOptions); applyErr != nil {
+			assert.Nilf(options.Testing, applyErr, "Terraform Apply for upgrade hop %s -> %s has failed", from, to)
+			options.testTearDown()
+			return nil, applyErr
+		}
+	}
+
+	if destroyHop := FirstDestroyHop(options.LastUpgradeHops); destroyHop != nil {
+		logger.Log(options.Testing, fmt.Sprintf("First upgrade hop to introduce a destroy: %s -> %s", destroyHop.From, destroyHop.To))
+	}
+
+	// Tear down the test
+	options.testTearDown()
+
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package testhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirstDestroyHop(t *testing.T) {
+	hops := []UpgradeHop{
+		{From: "v1.0.0", To: "v2.0.0", Report: &ConsistencyReport{IsUpgradeTest: true}},
+		{From: "v2.0.0", To: "v3.0.0", Report: &ConsistencyReport{IsUpgradeTest: true, Violations: []ConsistencyViolation{
+			{Address: "null_resource.exempt", ActionType: ConsistencyActionDelete, Exempted: true},
+			{Address: "null_resource.updated", ActionType: ConsistencyActionUpdate},
+		}}},
+		{From: "v3.0.0", To: "my-pr-branch", Report: &ConsistencyReport{IsUpgradeTest: true, Violations: []ConsistencyViolation{
+			{Address: "null_resource.replaced", ActionType: ConsistencyActionReplace},
+		}}},
+	}
+
+	assert.False(t, hops[0].IntroducedDestroy())
+	assert.False(t, hops[1].IntroducedDestroy(), "exempted destroys and updates are not destroys")
+
+	destroyHop := FirstDestroyHop(hops)
+	require.NotNil(t, destroyHop)
+	assert.Equal(t, "v3.0.0", destroyHop.From)
+	assert.Equal(t, "my-pr-branch", destroyHop.To)
+
+	assert.Nil(t, FirstDestroyHop(hops[:2]))
+	assert.Nil(t, FirstDestroyHop(nil))
+}
@@ -0,0 +1,22 @@
+package testprojects
+
+import (
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+)
+
+// acquireProjectLease waits for one of the MaxConcurrentProjects slots shared by the test processes, before the
+// project is created. The wait ends when the test run is cancelled.
+func (options *TestProjectsOptions) acquireProjectLease() error {
+	lease, err := testhelper.AcquireProjectLease(options.runContext, options.MaxConcurrentProjects, options.Logger)
+	if err != nil {
+		return err
+	}
+	options.projectLease = lease
+	return nil
+}
+
+// releaseProjectLease frees the project slot once the project is deleted, or was not created
+func (options *TestProjectsOptions) releaseProjectLease() {
+	testhelper.ReleaseProjectLease(options.projectLease, options.Logger)
+	options.projectLease = nil
+}
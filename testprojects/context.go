@@ -0,0 +1,40 @@
+package testprojects
+
+import (
+	"context"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// getDeployEndTime returns the time until which deploy operations are polled: DeployTimeoutMinutes from now, but no later
+// than the deadline of Context or the go test deadline minus the TeardownReserve.
+func (options *TestProjectsOptions) getDeployEndTime() time.Time {
+	ctx, cancel := common.NewTestContext(options.Testing, options.Context, options.TeardownReserve)
+	defer cancel()
+	return time.Now().Add(common.BoundTimeout(ctx, time.Duration(options.DeployTimeoutMinutes)*time.Minute))
+}
+
+// getTeardownEndTime returns the time until which undeploy and teardown operations are polled: DeployTimeoutMinutes from
+// now, but no later than the go test deadline. Cancelling Context does not stop the teardown.
+func (options *TestProjectsOptions) getTeardownEndTime() time.Time {
+	ctx, cancel := common.NewTeardownContext(options.Testing, options.Context)
+	defer cancel()
+	return time.Now().Add(common.BoundTimeout(ctx, time.Duration(options.DeployTimeoutMinutes)*time.Minute))
+}
+
+// startRunContext creates the context of a test run from Context, which is cancelled by the returned function, or once
+// only the TeardownReserve is left before the go test deadline
+func (options *TestProjectsOptions) startRunContext() context.CancelFunc {
+	var cancel context.CancelFunc
+	options.runContext, cancel = common.NewTestContext(options.Testing, options.Context, options.TeardownReserve)
+	return cancel
+}
+
+// isRunCancelled returns true if the test run or Context has been cancelled, in which case deploy operations stop waiting
+func (options *TestProjectsOptions) isRunCancelled() bool {
+	if options.runContext != nil {
+		return options.runContext.Err() != nil
+	}
+	return options.Context != nil && options.Context.Err() != nil
+}
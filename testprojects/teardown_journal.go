@@ -0,0 +1,42 @@
+package testprojects
+
+import (
+	"fmt"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// recordTeardownJournal records the test project in the teardown journal once it is created, so that the project can
+// still be undeployed and deleted with the resume-cleanup command if the test process dies.
+// Nothing is recorded if SkipTestTearDown is set, journal errors are logged and do not fail the test.
+func (options *TestProjectsOptions) recordTeardownJournal() {
+	if options.SkipTestTearDown || options.currentProject == nil || options.currentProject.ID == nil {
+		return
+	}
+
+	options.teardownJournalID = common.NewTeardownJournalID(options.Prefix)
+	entry := common.TeardownJournalEntry{
+		ID:            options.teardownJournalID,
+		Type:          common.TeardownJournalProject,
+		Prefix:        options.Prefix,
+		ProjectID:     *options.currentProject.ID,
+		ResourceGroup: options.ResourceGroup,
+	}
+	if options.Testing != nil {
+		entry.TestName = options.Testing.Name()
+	}
+	if err := common.GetDefaultTeardownJournal().Record(entry); err != nil {
+		options.Logger.ShortWarn(fmt.Sprintf("Failed to record project in the teardown journal: %s", err))
+	}
+}
+
+// completeTeardownJournal marks the journal entry of the test project as complete
+func (options *TestProjectsOptions) completeTeardownJournal() {
+	if options.teardownJournalID == "" {
+		return
+	}
+	if err := common.GetDefaultTeardownJournal().Complete(options.teardownJournalID); err != nil {
+		options.Logger.ShortWarn(fmt.Sprintf("Failed to complete project in the teardown journal: %s", err))
+	}
+	options.teardownJournalID = ""
+}
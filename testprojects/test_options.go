@@ -1,6 +1,7 @@
 package testprojects
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -14,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
 )
 
 const defaultRegion = "us-south"
@@ -115,6 +117,42 @@ type TestProjectsOptions struct {
 	// DeployTimeoutMinutes The number of minutes to wait for the stack to deploy. Also used for undeploy. Default is 6 hours.
 	DeployTimeoutMinutes int
 
+	// Context is an optional parent context for the test. Once it is cancelled, or the `go test -timeout` deadline minus the
+	// TeardownReserve is reached, the test stops waiting for the deploy and continues with teardown.
+	Context context.Context
+	// TeardownReserve is the time reserved for undeploy and teardown before the `go test -timeout` deadline.
+	// Default is 15 minutes (common.DefaultTeardownReserve), at most half of the time left before the deadline.
+	TeardownReserve time.Duration
+	// runContext is the context of the current test run, cancelled on an interrupt
+	runContext context.Context
+	// teardownJournalID is the ID of the teardown journal entry of the test project
+	teardownJournalID string
+
+	// CheckForLeaks enables the detection of resources left behind by the test. A snapshot of the resources is taken
+	// before the test and after teardown, and the test fails listing every new resource whose name or tags contain Prefix.
+	CheckForLeaks bool
+	// LeakCheckOptions is the scope of the snapshots taken for CheckForLeaks. Default is the resource instances of
+	// ResourceGroup and of the resource groups whose name starts with Prefix, and the VPCs of the us-south region.
+	LeakCheckOptions *cloudinfo.ResourceSnapshotOptions
+	// leakDetector holds the snapshot of the resources before the test
+	leakDetector *cloudinfo.LeakDetector
+
+	// MaxConcurrentProjects is the maximum number of projects created at the same time by the tests of all processes
+	// sharing the lease directory (the TEST_LEASE_DIR environment variable, default is the temp directory). A test waits
+	// for a free slot before creating its project, and frees it once the project is deleted or the process exits.
+	// Default is 0, no limit.
+	MaxConcurrentProjects int
+	// projectLease is the slot of the project, see MaxConcurrentProjects
+	projectLease *common.Lease
+
+	// UnlockOutputs are the outputs of the project configurations whose values are passed to an unlocker before the
+	// undeploy, to remove protections that would make the undeploy fail. The unlocker of each output is taken from
+	// Unlockers, or else from the unlockers registered with testhelper.RegisterUnlocker. Unlocker failures are logged
+	// and do not fail the test.
+	UnlockOutputs []string
+	// Unlockers for outputs of UnlockOutputs, keyed by output name, used instead of the registered unlockers.
+	Unlockers map[string]testhelper.Unlocker
+
 	// If you want to skip teardown use this flag
 	SkipTestTearDown  bool
 	SkipUndeploy      bool
@@ -293,6 +331,7 @@ func (options *TestProjectsOptions) Clone() (*TestProjectsOptions, error) {
 	// the Copy library does not handle pointer of struct very well so we want to manually take care of our
 	// pointers to other complex structs
 	newOptions.Testing = options.Testing
+	newOptions.Context = options.Context
 
 	return newOptions, nil
 }
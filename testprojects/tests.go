@@ -155,7 +155,7 @@ func (options *TestProjectsOptions) TriggerDeployAndWait() (errorList []error) {
 	var mu sync.Mutex
 
 	// setup timeout
-	deployEndTime := time.Now().Add(time.Duration(options.DeployTimeoutMinutes) * time.Minute)
+	deployEndTime := options.getDeployEndTime()
 	deployComplete := false
 	failed := false
 
@@ -190,7 +190,7 @@ func (options *TestProjectsOptions) TriggerDeployAndWait() (errorList []error) {
 	// Add counter for consecutive polls with missing members
 	consecutiveMissingMemberPolls := 0
 
-	for !deployComplete && time.Now().Before(deployEndTime) && !failed {
+	for !deployComplete && time.Now().Before(deployEndTime) && !failed && !options.isRunCancelled() {
 		options.Logger.ShortInfo("Checking Stack Deploy Status")
 		stackDetails, _, err := options.CloudInfoService.GetConfig(options.currentStackConfig)
 		if err != nil {
@@ -329,7 +329,7 @@ func (options *TestProjectsOptions) TriggerDeployAndWait() (errorList []error) {
 					} else {
 						workspaceID := matches[1]
 						location := strings.SplitN(workspaceID, "-", 2)[0]
-						options.CloudInfoService.WaitForSchematicsJobCompletion(workspaceID, *member.LastValidated.Job.ID, location, 10)
+						options.CloudInfoService.WaitForSchematicsJobCompletion(workspaceID, *member.LastValidated.Job.ID, location, common.BoundTimeoutMinutes(options.runContext, 10))
 						rawJobLogs, err := options.CloudInfoService.GetSchematicsJobLogsText(*member.LastValidated.Job.ID, location)
 						if err != nil {
 							options.Logger.ShortWarn(fmt.Sprintf("Could not get job logs for job: %s", *member.LastValidated.Job.ID))
@@ -527,7 +527,7 @@ func (options *TestProjectsOptions) TriggerUnDeploy() (bool, []error) {
 		var mu sync.Mutex
 
 		readyForUndeploy := false
-		timeoutEndTime := time.Now().Add(time.Duration(options.DeployTimeoutMinutes) * time.Minute)
+		timeoutEndTime := options.getTeardownEndTime()
 
 		// while not ready for undeploy and timeout not reached, keep checking
 		for !readyForUndeploy && time.Now().Before(timeoutEndTime) {
@@ -601,7 +601,7 @@ func (options *TestProjectsOptions) TriggerUnDeployAndWait() (errorList []error)
 		}
 
 		if triggered {
-			undeployEndTime := time.Now().Add(time.Duration(options.DeployTimeoutMinutes) * time.Minute)
+			undeployEndTime := options.getTeardownEndTime()
 			undeployComplete := false
 			failed := false
 
@@ -829,6 +829,12 @@ func (options *TestProjectsOptions) TriggerUnDeployAndWait() (errorList []error)
 // Deploys the configuration
 // Deletes the project
 func (options *TestProjectsOptions) RunProjectsTest() error {
+	// on SIGINT/SIGTERM stop waiting for the deploy and undeploy/delete the project, the teardown is only run once
+	cancelRun := options.startRunContext()
+	defer cancelRun()
+	interruptTeardown := common.RegisterInterruptTeardown(options.Testing, fmt.Sprintf("projects test %s", options.Prefix), cancelRun, options.TestTearDown)
+	defer interruptTeardown.Unregister()
+
 	if !options.SkipTestTearDown {
 		// ensure we always run the test tear down, even if a panic occurs
 		defer func() {
@@ -843,7 +849,7 @@ func (options *TestProjectsOptions) RunProjectsTest() error {
 					options.Logger.ShortError(fmt.Sprintf("Recovered from panic: %v", r))
 				}
 			}
-			options.TestTearDown()
+			interruptTeardown.Run()
 		}()
 	}
 
@@ -852,6 +858,7 @@ func (options *TestProjectsOptions) RunProjectsTest() error {
 		options.Testing.Fail()
 		return fmt.Errorf("test setup has failed:%w", setupErr)
 	}
+	options.startLeakDetection()
 
 	// First, validate that the branch exists in the remote repository BEFORE creating any resources
 	// Use the new cloudinfo helper for offering import preparation
@@ -887,6 +894,9 @@ func (options *TestProjectsOptions) RunProjectsTest() error {
 		return err
 	}
 
+	if err := options.acquireProjectLease(); err != nil {
+		return err
+	}
 	project, projectConfig, err := cloudinfo.SetupProject(cloudinfo.SetupProjectOptions{
 		CurrentProject:           options.currentProject,
 		CurrentProjectConfig:     options.currentProjectConfig,
@@ -906,10 +916,12 @@ func (options *TestProjectsOptions) RunProjectsTest() error {
 		Testing:                  options.Testing,
 	})
 	if err != nil {
+		options.releaseProjectLease()
 		return err
 	}
 	options.currentProject = project
 	options.currentProjectConfig = projectConfig
+	options.recordTeardownJournal()
 
 	if assert.NoError(options.Testing, options.ConfigureTestStack()) {
 		options.Logger.ShortInfo(fmt.Sprintf("Configured Test Stack - %s \n- %s %s \n- %s %s", *options.currentProject.Definition.Name, common.ColorizeString(common.Colors.Blue, "Project ID:"), *options.currentProject.ID, common.ColorizeString(common.Colors.Blue, "Config ID:"), *options.currentStack.Configuration.ID))
@@ -967,6 +979,9 @@ func (options *TestProjectsOptions) TestTearDown() {
 	}
 	if !options.SkipTestTearDown {
 		if options.executeResourceTearDown() {
+			// the leak check runs once the project is deleted, which is also a resource instance
+			defer options.checkForLeaks()
+			options.runUnlockers()
 			// Trigger undeploy and wait for completion
 			options.Logger.ShortInfo("Triggering Undeploy and waiting for completion")
 			undeployErrors := options.TriggerUnDeployAndWait()
@@ -981,7 +996,7 @@ func (options *TestProjectsOptions) TestTearDown() {
 		if options.executeProjectTearDown() {
 			// Wait until no pipeline actions are running or timeout is reached
 			options.Logger.ShortInfo("Checking all pipeline actions are complete")
-			timeout := time.Now().Add(time.Duration(options.DeployTimeoutMinutes) * time.Minute)
+			timeout := options.getTeardownEndTime()
 			for {
 				running, err := options.CloudInfoService.ArePipelineActionsRunning(options.currentStackConfig)
 				if err != nil {
@@ -1046,6 +1061,8 @@ func (options *TestProjectsOptions) TestTearDown() {
 
 				if assert.NoError(options.Testing, err) {
 					options.Logger.ShortInfo("Deleted Test Project")
+					options.completeTeardownJournal()
+					options.releaseProjectLease()
 				} else {
 					projectURL := fmt.Sprintf("https://cloud.ibm.com/projects/%s", *options.currentProject.ID)
 					options.Logger.ShortError(fmt.Sprintf("Error deleting Test Project: %s\nProject Console: %s", err, projectURL))
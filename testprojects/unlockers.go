@@ -0,0 +1,14 @@
+package testprojects
+
+import (
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+)
+
+// runUnlockers runs the unlockers of UnlockOutputs on the outputs of the project configurations, before the undeploy.
+// Results are logged, failures do not fail the test.
+func (options *TestProjectsOptions) runUnlockers() {
+	if len(options.UnlockOutputs) == 0 || options.currentProject == nil || options.currentProject.ID == nil {
+		return
+	}
+	testhelper.RunProjectUnlockers(options.CloudInfoService, *options.currentProject.ID, options.UnlockOutputs, options.Unlockers, options.Logger)
+}
@@ -0,0 +1,55 @@
+package testschematic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+)
+
+const (
+	// workspaceLeaseName is the lease that caps the schematics workspaces of concurrent tests, see MaxConcurrentWorkspaces
+	workspaceLeaseName         = "schematics-workspaces"
+	workspaceLeasePollInterval = 30 * time.Second
+)
+
+// acquireWorkspaceLease waits for one of the MaxConcurrentWorkspaces slots shared by the test processes, before the
+// workspace is created. The wait is bounded by the job context.
+func (svc *SchematicsTestService) acquireWorkspaceLease() error {
+	options := svc.TestOptions
+	if options == nil || options.MaxConcurrentWorkspaces <= 0 {
+		return nil
+	}
+
+	options.Testing.Logf("[SCHEMATICS] Waiting for one of %d workspace slots", options.MaxConcurrentWorkspaces)
+	lease, err := common.GetDefaultLeaseManager().Acquire(svc.JobContext, workspaceLeaseName, options.MaxConcurrentWorkspaces, workspaceLeasePollInterval)
+	if err != nil {
+		return fmt.Errorf("error waiting for a schematics workspace slot: %w", err)
+	}
+	svc.workspaceLease = lease
+	return nil
+}
+
+// releaseWorkspaceLease frees the workspace slot once the workspace is deleted
+func (svc *SchematicsTestService) releaseWorkspaceLease() {
+	if svc.workspaceLease == nil {
+		return
+	}
+	if err := svc.workspaceLease.Release(); err != nil {
+		svc.TestOptions.Testing.Logf("[SCHEMATICS] WARNING: %s", err)
+	}
+	svc.workspaceLease = nil
+}
+
+// releaseRegionLease frees the region selected for the test during setup, so that other tests can select it
+func (svc *SchematicsTestService) releaseRegionLease() {
+	options := svc.TestOptions
+	if options == nil || !options.regionLeased {
+		return
+	}
+	if err := testhelper.ReleaseRegionLease(options.CloudInfoService, options.Region); err != nil {
+		options.Testing.Logf("[SCHEMATICS] WARNING: %s", err)
+	}
+	options.regionLeased = false
+}
@@ -0,0 +1,43 @@
+package testschematic
+
+import (
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+)
+
+// startLeakDetection takes the snapshot of the account resources before the workspace is created, if CheckForLeaks
+// is set. Errors are logged and disable the leak check, they do not fail the test.
+func (svc *SchematicsTestService) startLeakDetection() {
+	options := svc.TestOptions
+	if options == nil || !options.CheckForLeaks || options.SkipTestTearDown {
+		return
+	}
+
+	snapshotOptions := testhelper.GetLeakCheckOptions(options.LeakCheckOptions, options.ResourceGroup, options.Region, options.Prefix)
+	detector, err := testhelper.StartLeakCheck(svc.CloudInfoService, options.Prefix, snapshotOptions)
+	if err != nil {
+		options.Testing.Logf("[SCHEMATICS] WARNING: skipping leak check: %s", err)
+		return
+	}
+	svc.leakDetector = detector
+}
+
+// checkForLeaks fails the test for every resource created since startLeakDetection whose name or tags contain the
+// test prefix. It is run after the destroy, and not if the resources were kept for debugging.
+func (svc *SchematicsTestService) checkForLeaks() {
+	detector := svc.leakDetector
+	svc.leakDetector = nil
+	if detector == nil {
+		return
+	}
+	options := svc.TestOptions
+	if options.Testing.Failed() && common.DoNotDestroyOnFailure() {
+		options.Testing.Log("[SCHEMATICS] Skipping leak check, resources of the failed test were not destroyed")
+		return
+	}
+
+	options.Testing.Log("[SCHEMATICS] Checking for leaked resources")
+	if _, err := testhelper.FinishLeakCheck(options.Testing, detector); err != nil {
+		options.Testing.Logf("[SCHEMATICS] WARNING: leak check failed: %s", err)
+	}
+}
@@ -0,0 +1,8 @@
+package testschematic
+
+import "github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+
+// ValidateOutputs checks LastTestTerraformOutputs against the specs, see testhelper.ValidateTerraformOutputSpecs
+func (options *TestSchematicOptions) ValidateOutputs(specs ...testhelper.OutputSpec) error {
+	return testhelper.ValidateTerraformOutputSpecs(options.LastTestTerraformOutputs, specs...)
+}
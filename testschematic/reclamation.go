@@ -0,0 +1,79 @@
+package testschematic
+
+import (
+	"fmt"
+
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// collectReclamationCRNs returns the CRNs of the resource instances whose reclamations are purged after the destroy,
+// see PurgeReclamations. It is run before the destroy, using the state file of the last APPLY job or the outputs.
+func (svc *SchematicsTestService) collectReclamationCRNs() []string {
+	options := svc.TestOptions
+	if options == nil || options.PurgeReclamations == nil || len(options.PurgeReclamations.Services) == 0 {
+		return nil
+	}
+
+	var crns []string
+	if options.PurgeReclamations.CrnOutput != "" {
+		value, found := options.LastTestTerraformOutputs[options.PurgeReclamations.CrnOutput]
+		if !found {
+			options.Testing.Logf("[SCHEMATICS] WARNING: output %s not found, no reclamations will be purged", options.PurgeReclamations.CrnOutput)
+			return nil
+		}
+		crns = common.CollectCRNsFromValue(value)
+	} else {
+		stateJSON, stateErr := svc.getLatestApplyStateFile()
+		if stateErr != nil {
+			options.Testing.Logf("[SCHEMATICS] WARNING: failed to read terraform state, no reclamations will be purged: %s", stateErr)
+			return nil
+		}
+		var parseErr error
+		crns, parseErr = common.CollectCRNsFromState(stateJSON)
+		if parseErr != nil {
+			options.Testing.Logf("[SCHEMATICS] WARNING: no reclamations will be purged: %s", parseErr)
+			return nil
+		}
+	}
+	return common.FilterInstanceCRNsByService(crns, options.PurgeReclamations.Services)
+}
+
+// getLatestApplyStateFile returns the state file of the latest APPLY job of the workspace
+func (svc *SchematicsTestService) getLatestApplyStateFile() (string, error) {
+	applyJob, applyJobErr := svc.FindLatestWorkspaceJobByName(SchematicsJobTypeApply)
+	if applyJobErr != nil {
+		return "", fmt.Errorf("error finding the APPLY job: %w", applyJobErr)
+	}
+	if applyJob == nil || applyJob.ActionID == nil {
+		return "", fmt.Errorf("APPLY job has no ID")
+	}
+	data, dataErr := svc.CloudInfoService.GetSchematicsJobFileData(*applyJob.ActionID, "state_file", svc.WorkspaceLocation)
+	if dataErr != nil {
+		return "", dataErr
+	}
+	if data == nil || data.FileContent == nil {
+		return "", fmt.Errorf("state file of APPLY job %s is empty", *applyJob.ActionID)
+	}
+	return *data.FileContent, nil
+}
+
+// purgeReclamations purges the reclamations of the destroyed resource instances and logs a summary.
+// Failed purges are logged as warnings, they do not fail the test.
+func (svc *SchematicsTestService) purgeReclamations(crns []string) {
+	if len(crns) == 0 || svc.CloudInfoService == nil {
+		return
+	}
+	options := svc.TestOptions
+
+	config := common.ReclamationPurgeRetryConfig()
+	if options.PurgeReclamations.RetryConfig != nil {
+		config = *options.PurgeReclamations.RetryConfig
+	}
+
+	options.Testing.Log("[SCHEMATICS] Purging reclamations of destroyed instances")
+	summary := common.PurgeReclamations(svc.CloudInfoService, crns, config)
+	options.Testing.Logf("[SCHEMATICS] %s", summary.String())
+	if len(summary.Failed) > 0 {
+		options.Testing.Log("[SCHEMATICS] WARNING: the reclamations of some destroyed instances could not be purged, remove them manually if required")
+	}
+}
@@ -3,18 +3,17 @@
 package testschematic
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strings"
 
 	"github.com/IBM/go-sdk-core/v5/core"
 	schematics "github.com/IBM/schematics-go-sdk/schematicsv1"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/cloudinfo"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
 )
 
 // Re-export constants from cloudinfo for backward compatibility
@@ -72,6 +71,10 @@ type SchematicsTestService struct {
 	TestTerraformRepo         string                      // the URL of the repo for the pull request, will be either origin or a fork
 	TestTerraformRepoBranch   string                      // the branch of the test, usually the current checked out branch of the test run
 	BaseTerraformTempDir      string                      // if upgrade test, will contain the temp directory containing clone of base repo
+	JobContext                context.Context             // if set, the wait for schematics jobs is bounded by the deadline of this context
+	teardownJournalID         string                      // ID of the teardown journal entry of the test workspace
+	leakDetector              *cloudinfo.LeakDetector     // snapshot of the resources before the test, see CheckForLeaks
+	workspaceLease            *common.Lease               // slot of the workspace, see MaxConcurrentWorkspaces
 }
 
 // CreateAuthenticator will accept a valid IBM cloud API key, and
@@ -364,17 +367,26 @@ func (svc *SchematicsTestService) GetWorkspaceJobDetail(jobID string) (*schemati
 // been reached.
 // Returns the final status value of the activity when it has finished.
 // Returns an error if the activity does not finish before the configured time threshold.
+// If a JobContext is set, the wait is also limited to the time remaining before its deadline.
 func (svc *SchematicsTestService) WaitForFinalJobStatus(jobID string) (string, error) {
 	waitMinutes := DefaultWaitJobCompleteMinutes
 	if svc.TestOptions != nil && svc.TestOptions.WaitJobCompleteMinutes > 0 {
 		waitMinutes = svc.TestOptions.WaitJobCompleteMinutes
 	}
 
+	boundedMinutes := int(waitMinutes)
+	if svc.JobContext != nil {
+		if ctxErr := svc.JobContext.Err(); ctxErr != nil {
+			return "", fmt.Errorf("not waiting for schematics job %s: %w", jobID, ctxErr)
+		}
+		boundedMinutes = common.BoundTimeoutMinutes(svc.JobContext, boundedMinutes)
+	}
+
 	return svc.CloudInfoService.WaitForSchematicsJobCompletion(
 		svc.WorkspaceID,
 		jobID,
 		svc.WorkspaceLocation,
-		int(waitMinutes),
+		boundedMinutes,
 	)
 }
 
@@ -396,73 +408,13 @@ func (svc *SchematicsTestService) DeleteWorkspace() (string, error) {
 }
 
 // variable validation function for validating if some variable is passed to test which is not
-// declared in variables.tf. Currently schematics does not fail the test in such a case where
-// normal terraform run would give an error saying passed variable does not exist in variables.tf file
+// declared in the terraform files. Currently schematics does not fail the test in such a case where
+// normal terraform run would give an error saying passed variable does not exist
 func (svc *SchematicsTestService) validateVariables(terraformDir string) error {
-
-	entries, err := os.ReadDir(terraformDir)
-	if err != nil {
-		return fmt.Errorf("error reading directory: %v", err)
-	}
-
-	re := regexp.MustCompile(`variable\s+"([^"]+)"`)
-	declaredVars := []string{}
-
-	for _, entry := range entries { // loop all .tf files in terraform directory
-		if entry.IsDir() {
-			continue // Skip directories
-		}
-
-		if strings.HasSuffix(entry.Name(), ".tf") {
-			filePath := filepath.Join(terraformDir, entry.Name())
-
-			content, err := os.ReadFile(filePath)
-			if err != nil {
-				return fmt.Errorf("error reading file %s: %v", filePath, err)
-			}
-
-			matches := re.FindAllStringSubmatch(string(content), -1)
-			for _, match := range matches {
-				if len(match) > 1 {
-					declaredVars = append(declaredVars, match[1])
-				}
-			}
-		}
-	}
-
-	optionVars := svc.TestOptions.TerraformVars
-	passedVars := make([]string, 0)
-
-	for _, varInfo := range optionVars {
-
+	passedVars := make([]string, 0, len(svc.TestOptions.TerraformVars))
+	for _, varInfo := range svc.TestOptions.TerraformVars {
 		passedVars = append(passedVars, varInfo.Name)
 	}
 
-	extraVariables := make([]string, 0)
-	// check if there is some variable passed to the test but is not declared in variables.tf
-
-	for _, passedVar := range passedVars {
-
-		found := false
-
-		for _, declaredVar := range declaredVars {
-			if passedVar == declaredVar {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-
-			extraVariables = append(extraVariables, passedVar)
-		}
-	}
-	if len(extraVariables) > 0 {
-
-		vars := strings.Join(extraVariables, ", ")
-		return fmt.Errorf("variable [%s] passed in test but not declared in variables.tf", vars)
-
-	}
-	return nil
-
+	return testhelper.CheckUndeclaredTerraformVariables(terraformDir, passedVars)
 }
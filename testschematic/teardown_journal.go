@@ -0,0 +1,42 @@
+package testschematic
+
+import (
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/common"
+)
+
+// recordTeardownJournal records the test workspace in the teardown journal once it is created, so that the workspace
+// and its resources can still be removed with the resume-cleanup command if the test process dies.
+// Nothing is recorded if SkipTestTearDown is set, journal errors are logged and do not fail the test.
+func (svc *SchematicsTestService) recordTeardownJournal() {
+	options := svc.TestOptions
+	if options == nil || options.SkipTestTearDown {
+		return
+	}
+
+	svc.teardownJournalID = common.NewTeardownJournalID(options.Prefix)
+	entry := common.TeardownJournalEntry{
+		ID:                          svc.teardownJournalID,
+		Type:                        common.TeardownJournalSchematics,
+		Prefix:                      options.Prefix,
+		SchematicsWorkspaceID:       svc.WorkspaceID,
+		SchematicsWorkspaceLocation: svc.WorkspaceLocation,
+		ResourceGroup:               options.ResourceGroup,
+	}
+	if options.Testing != nil {
+		entry.TestName = options.Testing.Name()
+	}
+	if err := common.GetDefaultTeardownJournal().Record(entry); err != nil {
+		options.Testing.Logf("[SCHEMATICS] WARNING: failed to record workspace in the teardown journal: %s", err)
+	}
+}
+
+// completeTeardownJournal marks the journal entry of the test workspace as complete
+func (svc *SchematicsTestService) completeTeardownJournal() {
+	if svc.teardownJournalID == "" {
+		return
+	}
+	if err := common.GetDefaultTeardownJournal().Complete(svc.teardownJournalID); err != nil {
+		svc.TestOptions.Testing.Logf("[SCHEMATICS] WARNING: failed to complete workspace in the teardown journal: %s", err)
+	}
+	svc.teardownJournalID = ""
+}
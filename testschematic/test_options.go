@@ -1,9 +1,11 @@
 package testschematic
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/jinzhu/copier"
 	"github.com/stretchr/testify/require"
@@ -85,6 +87,43 @@ type TestSchematicOptions struct {
 	// Default: 120 (two hours)
 	WaitJobCompleteMinutes int16
 
+	// OPTIONAL: parent context for the test. Once it is cancelled, or the `go test -timeout` deadline minus the TeardownReserve
+	// is reached, the test stops waiting for schematics jobs and continues with teardown.
+	Context context.Context
+
+	// OPTIONAL: time reserved for teardown before the `go test -timeout` deadline.
+	// Default: 15 minutes (common.DefaultTeardownReserve), at most half of the time left before the deadline
+	TeardownReserve time.Duration
+
+	// OPTIONAL: detect resources left behind by the test. A snapshot of the resource instances (and VPCs) is
+	// taken before the workspace is created and after teardown, and the test fails listing every new resource whose name
+	// or tags contain Prefix.
+	CheckForLeaks bool
+
+	// OPTIONAL: scope of the snapshots taken for CheckForLeaks.
+	// Default: the resource instances of ResourceGroup and of the resource groups whose name starts with Prefix, and the
+	// VPCs of the test Region
+	LeakCheckOptions *cloudinfo.ResourceSnapshotOptions
+
+	// OPTIONAL: maximum number of schematics workspaces created at the same time by the tests of all processes sharing the
+	// lease directory (the TEST_LEASE_DIR environment variable, default is the temp directory). A test waits for a free
+	// slot before creating its workspace, and frees it once the workspace is deleted or the process exits.
+	// Default: 0, no limit
+	MaxConcurrentWorkspaces int
+
+	// OPTIONAL: purge the reclamations of the destroyed resource instances of the listed services after a successful
+	// DESTROY job, so that their names and quotas are released right away. The CRNs are collected from the state file of
+	// the last APPLY job, or from the CrnOutput output, before the destroy.
+	PurgeReclamations *common.ReclamationPurgeOptions
+
+	// OPTIONAL: terraform outputs whose values are passed to an unlocker before the DESTROY job, to remove protections
+	// that would make the destroy fail. The unlocker of each output is taken from Unlockers, or else from the unlockers
+	// registered with testhelper.RegisterUnlocker. Unlocker failures are logged and do not fail the test.
+	UnlockOutputs []string
+
+	// OPTIONAL: unlockers for outputs of UnlockOutputs, keyed by output name, used instead of the registered unlockers.
+	Unlockers map[string]testhelper.Unlocker
+
 	// Base URL of the schematics REST API. Set to override default.
 	// Default will be based on the appropriate endpoint for the chosen `WorkspaceRegion`
 	SchematicsApiURL string
@@ -115,6 +154,7 @@ type TestSchematicOptions struct {
 	CloudInfoService  cloudinfo.CloudInfoServiceI // OPTIONAL: Supply if you need multiple tests to share info service and data
 	SchematicsApiSvc  SchematicsApiSvcI           // OPTIONAL: service pointer for interacting with external schematics api
 	schematicsTestSvc *SchematicsTestService      // internal property to specify pointer to test service, used for test mocking
+	regionLeased      bool                        // internal property, Region was selected during setup and is leased until teardown
 
 	// For Consistency Checks: Specify terraform resource names to ignore for consistency checks.
 	// You can ignore specific resources in both idempotent and upgrade consistency checks by adding their names to these
@@ -124,10 +164,17 @@ type TestSchematicOptions struct {
 	// Normally this would fail a consistency check but can be ignored by adding to one of these lists.
 	//
 	// Name format is terraform style, for example: `module.some_module.null_resource.foo`
+	// Glob (`Patterns`) and regular expression (`RegexPatterns`) address matching is also supported, and `IgnoreUpdates` can
+	// ignore individual attributes of a resource using `Attributes`, for example: `parameters.last_updated`
 	IgnoreAdds     testhelper.Exemptions
 	IgnoreDestroys testhelper.Exemptions
 	IgnoreUpdates  testhelper.Exemptions
 
+	// OPTIONAL: path to a YAML or JSON exemption file (see testhelper.ExemptionFile), where every exemption has a reason and
+	// optionally an issue link and expiry date. Entries are merged with the IgnoreAdds, IgnoreUpdates and IgnoreDestroys lists.
+	// An expired exemption will fail the test, while exemptions that were not used are logged as warnings.
+	ExemptionFile string
+
 	// Use these options to specify a base terraform repo and branch to use for upgrade tests.
 	// If not supplied, the default logic will be used to determine the base repo and branch.
 	// Will be overridden by environment variables BASE_TERRAFORM_REPO and BASE_TERRAFORM_BRANCH if set.
@@ -171,6 +218,11 @@ type TestSchematicOptions struct {
 	// Unless the upgrade test is run with the `CheckApplyResultForUpgrade` set to true.
 	LastTestTerraformOutputs map[string]interface{}
 
+	// LastConsistencyReport is the structured result of the consistency (or upgrade) plan check performed during the test.
+	// It lists every resource identified with a create, update, delete or replace action and can be rendered as text, JSON or Markdown
+	// for publishing, or inspected for further assertions after the test.
+	LastConsistencyReport *testhelper.ConsistencyReport
+
 	// Hooks These allow us to inject custom code into the test process
 	// example to set a hook:
 	// options.PreApplyHook = func(options *TestSchematicOptions) error {
@@ -210,6 +262,7 @@ func (options *TestSchematicOptions) GetCheckConsistencyOptions() *testhelper.Ch
 		IgnoreAdds:     options.IgnoreAdds,
 		IgnoreDestroys: options.IgnoreDestroys,
 		IgnoreUpdates:  options.IgnoreUpdates,
+		ExemptionFile:  options.ExemptionFile,
 		IsUpgradeTest:  options.IsUpgradeTest,
 	}
 }
@@ -250,6 +303,8 @@ func TestSchematicOptionsDefault(originalOptions *TestSchematicOptions) *TestSch
 		} else {
 			newOptions.Region, _ = testhelper.GetBestVpcRegionO(newOptions.RequiredEnvironmentVars[ibmcloudApiKeyVar], defaultRegionYaml, newOptions.DefaultRegion, *regionOptions)
 		}
+		_, forced := os.LookupEnv(testhelper.ForceTestRegionEnvName)
+		newOptions.regionLeased = !forced
 	}
 
 	if newOptions.WaitJobCompleteMinutes <= 0 {
@@ -273,6 +328,7 @@ func (options *TestSchematicOptions) Clone() (*TestSchematicOptions, error) {
 	// the Copy library does not handle pointer of struct very well so we want to manually take care of our
 	// pointers to other complex structs
 	newOptions.Testing = options.Testing
+	newOptions.Context = options.Context
 
 	return newOptions, nil
 }
@@ -1,6 +1,7 @@
 package testschematic
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -67,6 +68,20 @@ func executeSchematicTest(options *TestSchematicOptions, performUpgradeTest bool
 	svc.TerraformTestStarted = false
 	svc.TerraformResourcesCreated = false
 
+	// bound the schematics jobs of the test by the go test deadline, leaving time for teardown
+	var cancelJobs context.CancelFunc
+	svc.JobContext, cancelJobs = common.NewTestContext(options.Testing, options.Context, options.TeardownReserve)
+	defer cancelJobs()
+	if deadline, ok := svc.JobContext.Deadline(); ok {
+		options.Testing.Logf("[SCHEMATICS] Waiting for jobs will stop at %s to reserve time for teardown", deadline.Format("15:04:05"))
+	}
+
+	// on SIGINT/SIGTERM stop waiting for jobs and tear down the workspace, the teardown is only run once
+	interruptTeardown := common.RegisterInterruptTeardown(options.Testing, fmt.Sprintf("schematics test %s", options.Prefix), cancelJobs, func() {
+		testTearDown(svc, options)
+	})
+	defer interruptTeardown.Unregister()
+
 	// PANIC CATCH and TEAR DOWN
 	// This defer will set up two things:
 	// A catch of a panic and recover, to continue all tests in case of panic
@@ -78,7 +93,7 @@ func executeSchematicTest(options *TestSchematicOptions, performUpgradeTest bool
 			fmt.Println("=== RECOVER FROM PANIC (stacktrace end) ===")
 			options.Testing.Errorf("Recovered from panic: %v", r)
 		}
-		testTearDown(svc, options)
+		interruptTeardown.Run()
 	}()
 
 	// get the root path of this project
@@ -97,6 +112,12 @@ func executeSchematicTest(options *TestSchematicOptions, performUpgradeTest bool
 		}
 	}
 
+	svc.startLeakDetection()
+
+	if leaseErr := svc.acquireWorkspaceLease(); leaseErr != nil {
+		return leaseErr
+	}
+
 	// create a new empty workspace, resulting in "draft" status
 	options.Testing.Log("[SCHEMATICS] Creating Test Workspace")
 	_, wsErr := svc.CreateTestWorkspace(options.Prefix, options.ResourceGroup, svc.WorkspaceLocation, options.TemplateFolder, options.TerraformVersion, options.Tags)
@@ -105,6 +126,7 @@ func executeSchematicTest(options *TestSchematicOptions, performUpgradeTest bool
 	}
 
 	options.Testing.Logf("[SCHEMATICS] Workspace Created: %s (%s)", svc.WorkspaceName, svc.WorkspaceID)
+	svc.recordTeardownJournal()
 	// can be used in error messages to repeat workspace name
 	svc.WorkspaceNameForLog = fmt.Sprintf("[ %s (%s) ]", svc.WorkspaceName, svc.WorkspaceID)
 
@@ -269,8 +291,10 @@ func executeSchematicTest(options *TestSchematicOptions, performUpgradeTest bool
 						// convert the json string into a terratest plan struct
 						planStruct, planStructErr := terraform.ParsePlanJSON(consistencyPlanJson)
 						if assert.NoErrorf(options.Testing, planStructErr, "error converting %s plan string into struct: %s -%s", consistencyTypeForLog, planStructErr, svc.WorkspaceNameForLog) {
-							// not consuming the boolean return from CheckConsistency on purpose, as it does not let us know what we need to know here
-							testhelper.CheckConsistency(planStruct, options)
+							options.LastConsistencyReport = testhelper.CheckConsistency(planStruct, options)
+							if options.LastConsistencyReport.HasFailures() {
+								options.Testing.Logf("[SCHEMATICS] %s", options.LastConsistencyReport.String())
+							}
 						}
 					}
 				}
@@ -412,6 +436,11 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 		}
 	}()
 
+	// teardown jobs are not bound by the test context, which may already be cancelled
+	var cancelTeardown context.CancelFunc
+	svc.JobContext, cancelTeardown = common.NewTeardownContext(options.Testing, options.Context)
+	defer cancelTeardown()
+
 	// retrieve and store the last set of outputs right before destroy
 	if svc.TerraformResourcesCreated {
 		outputs, outputsErr := svc.GetLatestWorkspaceOutputs()
@@ -439,10 +468,12 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 
 		// ------ DESTROY RESOURCES ------
 		// only run destroy if we had potentially created resources
+		resourcesRemain := false
 		if svc.TerraformResourcesCreated {
 			// Once we enter this block, turn the Created to false
 			// This is to prevent this part from running again in case of panic and tear down is executed a 2nd time
 			svc.TerraformResourcesCreated = false
+			resourcesRemain = true
 
 			// Check if "DO_NOT_DESTROY_ON_FAILURE" is set
 			if options.Testing.Failed() && common.DoNotDestroyOnFailure() {
@@ -451,6 +482,8 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 				options.Testing.Log("Performing Teardown")
 				options.Testing.Log(fmt.Sprintf("Test Passed: %t", !options.Testing.Failed()))
 
+				svc.runUnlockers()
+				reclamationCRNs := svc.collectReclamationCRNs()
 				destroySuccess := false // will only flip to true if job completes
 				destroyResponse, destroyErr := svc.CreateDestroyJob()
 				if assert.NoErrorf(options.Testing, destroyErr, "error creating DESTROY - %s", svc.WorkspaceName) {
@@ -459,6 +492,10 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 					if assert.NoErrorf(options.Testing, destroyStatusErr, "error waiting for DESTROY to finish - %s", svc.WorkspaceName) {
 						destroySuccess = assert.Equalf(options.Testing, SchematicsJobStatusCompleted, destroyJobStatus, "DESTROY has failed with status %s - %s", destroyJobStatus, svc.WorkspaceName)
 					}
+					resourcesRemain = !destroySuccess
+					if destroySuccess {
+						svc.purgeReclamations(reclamationCRNs)
+					}
 
 					if !destroySuccess || options.PrintAllSchematicsLogs {
 						printDestroyLogErr := svc.printWorkspaceJobLogToTestLog(*destroyResponse.Activityid, "DESTROY")
@@ -471,6 +508,7 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 		}
 
 		// only attempt to delete workspace if it was created (valid workspace id)
+		workspaceDeleted := len(svc.WorkspaceID) == 0
 		if len(svc.WorkspaceID) > 0 {
 			// ------ DELETE WORKSPACE ------
 			// only delete workspace if one of these is true:
@@ -485,9 +523,22 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 				_, deleteWsErr := svc.DeleteWorkspace()
 				if deleteWsErr != nil {
 					options.Testing.Logf("[SCHEMATICS] WARNING: Schematics WORKSPACE DELETE failed! Remove manually if required. Name: %s (%s)", svc.WorkspaceName, svc.WorkspaceID)
+				} else {
+					workspaceDeleted = true
 				}
 			}
 		}
+		// a workspace kept for analysis stays pending in the teardown journal
+		if workspaceDeleted && !resourcesRemain {
+			svc.completeTeardownJournal()
+		}
+		if workspaceDeleted {
+			svc.releaseWorkspaceLease()
+		}
+		// the region stays leased while resources remain
+		if !resourcesRemain {
+			svc.releaseRegionLease()
+		}
 
 		// POST-DESTROY HOOK
 		if options.PostDestroyHook != nil {
@@ -502,6 +553,8 @@ func testTearDown(svc *SchematicsTestService, options *TestSchematicOptions) {
 			}
 		}
 
+		svc.checkForLeaks()
+
 		// clean up any temp directories that were created
 		if len(svc.BaseTerraformTempDir) > 0 {
 			options.Testing.Logf("[SCHEMATICS] Removing temp directory for upgrade test: %s", svc.BaseTerraformTempDir)
@@ -0,0 +1,18 @@
+package testschematic
+
+import (
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+)
+
+// runUnlockers runs the unlockers of UnlockOutputs on the last outputs of the workspace, before the DESTROY job.
+// Results are logged, failures do not fail the test.
+func (svc *SchematicsTestService) runUnlockers() {
+	options := svc.TestOptions
+	if options == nil || len(options.UnlockOutputs) == 0 || svc.CloudInfoService == nil {
+		return
+	}
+
+	options.Testing.Log("[SCHEMATICS] Running unlockers before destroy")
+	results := testhelper.RunUnlockers(svc.CloudInfoService, options.LastTestTerraformOutputs, options.UnlockOutputs, options.Unlockers)
+	options.Testing.Logf("[SCHEMATICS] %s", testhelper.FormatUnlockResults(results))
+}